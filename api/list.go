@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+
+	"github.com/sainak/bitsb/pkg/repo"
+)
+
+// ListResponse is the envelope of a list, Total is only
+// set by lists that are not paginated
+type ListResponse struct {
+	Data       interface{} `json:"data"`
+	Pagination repo.Page   `json:"pagination"`
+	Total      *int64      `json:"total,omitempty"`
+}
+
+// RespondList responds with a page of a list, V1 responds with the bare
+// list and sets the cursors of the page in the X-Cursor headers
+func RespondList(w http.ResponseWriter, r *http.Request, data interface{}, page repo.Page) {
+	if Version(r) < V2 {
+		w.Header().Set("X-Cursor", page.Next)
+		w.Header().Set("X-Prev-Cursor", page.Prev)
+		w.Header().Set("X-Has-More", strconv.FormatBool(page.HasMore))
+		render.JSON(w, r, data)
+		return
+	}
+	render.JSON(w, r, ListResponse{Data: data, Pagination: page})
+}
+
+// RespondFullList responds with a list that is not paginated, total is its length
+func RespondFullList(w http.ResponseWriter, r *http.Request, data interface{}, total int) {
+	if Version(r) < V2 {
+		render.JSON(w, r, data)
+		return
+	}
+	count := int64(total)
+	render.JSON(w, r, ListResponse{
+		Data:       data,
+		Pagination: repo.Page{Limit: count},
+		Total:      &count,
+	})
+}
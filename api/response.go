@@ -1,20 +1,85 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/getsentry/sentry-go"
 	"github.com/go-chi/render"
+	"github.com/sirupsen/logrus"
 
 	"github.com/sainak/bitsb/apperrors"
 )
 
-// ErrorResponse represent the api error struct
+// ProblemContentType is the media type of a Problem
+const ProblemContentType = "application/problem+json"
+
+// ErrorResponse represent the api error struct,
+// Errors has the broken rules of a request that failed validation
 type ErrorResponse struct {
-	Message string `json:"message"`
+	Message string                 `json:"message"`
+	Code    string                 `json:"code"`
+	Errors  []apperrors.FieldError `json:"errors,omitempty"`
+	Details interface{}            `json:"details,omitempty"`
+}
+
+// Problem is an error response in the format of RFC 7807,
+// Code, Errors and Details are extension members
+type Problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail"`
+	Instance string                 `json:"instance,omitempty"`
+	Code     string                 `json:"code"`
+	Errors   []apperrors.FieldError `json:"errors,omitempty"`
+	Details  interface{}            `json:"details,omitempty"`
 }
 
+// RespondForError responds with the error err is parsed to, as a Problem when the request
+// is V2 or accepts problems. Server errors are reported to sentry with their cause.
 func RespondForError(w http.ResponseWriter, r *http.Request, err error) {
 	e := apperrors.ParseError(err)
+	if e.StatusCode >= http.StatusInternalServerError {
+		logrus.Error(err)
+		if hub := sentry.GetHubFromContext(r.Context()); hub != nil {
+			hub.CaptureException(err)
+		}
+	}
+
+	var fieldErrors []apperrors.FieldError
+	var v *apperrors.ValidationError
+	if errors.As(err, &v) {
+		fieldErrors = v.Errors
+	}
+
+	if Version(r) >= V2 || strings.Contains(r.Header.Get("Accept"), ProblemContentType) {
+		respondProblem(w, &Problem{
+			Type:     "urn:bitsb:error:" + e.Code,
+			Title:    http.StatusText(e.StatusCode),
+			Status:   e.StatusCode,
+			Detail:   e.Message,
+			Instance: r.URL.Path,
+			Code:     e.Code,
+			Errors:   fieldErrors,
+			Details:  e.Details,
+		})
+		return
+	}
 	render.Status(r, e.StatusCode)
-	render.JSON(w, r, ErrorResponse{e.Message})
+	render.JSON(w, r, ErrorResponse{Message: e.Message, Code: e.Code, Errors: fieldErrors, Details: e.Details})
+}
+
+func respondProblem(w http.ResponseWriter, problem *Problem) {
+	body, err := json.Marshal(problem)
+	if err != nil {
+		logrus.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_, _ = w.Write(body)
 }
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// versions of the api, a request is V1 unless its route sets another version
+const (
+	V1 = 1
+	// V2 wraps lists in a ListResponse
+	V2 = 2
+)
+
+type versionCtxKey struct{}
+
+// WithVersion sets the api version of the requests handled by next
+func WithVersion(version int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), versionCtxKey{}, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Version returns the api version of the request
+func Version(r *http.Request) int {
+	if version, ok := r.Context().Value(versionCtxKey{}).(int); ok {
+		return version
+	}
+	return V1
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/getsentry/sentry-go"
@@ -14,17 +19,30 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/bitsb"
 	_bitsbRouter "github.com/sainak/bitsb/bitsb/delivery/http/router"
 	_bitsbRepo "github.com/sainak/bitsb/bitsb/repo/postgres"
 	_bitsbService "github.com/sainak/bitsb/bitsb/service"
+	"github.com/sainak/bitsb/inmemory"
 	"github.com/sainak/bitsb/pkg/jwt"
+	"github.com/sainak/bitsb/pkg/mail"
+	"github.com/sainak/bitsb/pkg/repo"
 	_rootRouter "github.com/sainak/bitsb/root/delivery/http/router"
+	"github.com/sainak/bitsb/tickets"
+	_ticketRouter "github.com/sainak/bitsb/tickets/delivery/http/router"
+	_ticketRepo "github.com/sainak/bitsb/tickets/repo/postgres"
+	_ticketService "github.com/sainak/bitsb/tickets/service"
+	"github.com/sainak/bitsb/users"
 	middl "github.com/sainak/bitsb/users/delivery/http/middleware"
 	_userRouter "github.com/sainak/bitsb/users/delivery/http/router"
 	_userRepo "github.com/sainak/bitsb/users/repo/postgres"
 	_userService "github.com/sainak/bitsb/users/service"
 )
 
+// shutdownTimeout is how long the requests and mails in flight may take on shutdown
+const shutdownTimeout = 30 * time.Second
+
 var (
 	version     = "nil"
 	environment = ""
@@ -85,29 +103,66 @@ func main() {
 		})
 	}
 
-	dsn := viper.GetString("DB_DSN")
-	logrus.Debug("DB_DSN: ", dsn)
+	jwtInstance := jwt.New(
+		viper.GetString("JWT_SECRET"),
+		viper.GetString("JWT_EXPIRY"),
+		viper.GetString("JWT_REFRESH_EXPIRY"),
+	)
+	if keys := viper.GetString("JWT_KEYS"); keys != "" {
+		jwtInstance.Keys, err = jwt.LoadKeys(keys)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	repo.SetCursorSecret(viper.GetString("CURSOR_SECRET"))
 
-	dbConn, err := sql.Open("postgres", dsn)
+	ticketSigner, err := jwt.NewTicketSigner(viper.GetString("TICKET_SIGNING_KEY"))
 	if err != nil {
 		logrus.Fatal(err)
 	}
-	defer func() {
-		if err := dbConn.Close(); err != nil {
+
+	mailFrom := viper.GetString("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "BitsB <no-reply@localhost>"
+	}
+	var mailer mail.Mailer
+	mailerName := viper.GetString("MAILER")
+	if mailerName == "" {
+		// logged mails hold the tokens of the users, only fall back to them when running locally
+		if environment != "local" {
+			logrus.Fatalf("MAILER has to be set in the %s environment, use log, file, memory or smtp", environment)
+		}
+		logrus.Warn("MAILER is not set, mails are written to the log")
+	}
+	switch mailerName {
+	case "", "log":
+		mailer = mail.NewWriterMailer(os.Stderr, mailFrom)
+	case "file":
+		mailFile, err := os.OpenFile(viper.GetString("MAIL_FILE"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
 			logrus.Fatal(err)
 		}
-	}()
-
-	if err = dbConn.Ping(); err != nil {
-		logrus.Fatal(err)
+		defer func() {
+			if err := mailFile.Close(); err != nil {
+				logrus.Error(err)
+			}
+		}()
+		mailer = mail.NewWriterMailer(mailFile, mailFrom)
+	case "memory":
+		mailer = mail.NewMemoryMailer()
+	case "smtp":
+		mailer = mail.NewSMTPMailer(
+			viper.GetString("SMTP_HOST"),
+			viper.GetString("SMTP_PORT"),
+			viper.GetString("SMTP_USERNAME"),
+			viper.GetString("SMTP_PASSWORD"),
+			mailFrom,
+		)
+	default:
+		logrus.Fatalf("unknown MAILER %q, use log, file, memory or smtp", mailerName)
 	}
 
-	jwtInstance := jwt.New(
-		viper.GetString("JWT_SECRET"),
-		viper.GetString("JWT_EXPIRY"),
-		viper.GetString("JWT_REFRESH_EXPIRY"),
-	)
-
 	r := chi.NewRouter()
 	r.Use(
 		middleware.Maybe(middleware.CleanPath, func(r *http.Request) bool {
@@ -117,7 +172,7 @@ func main() {
 			return !strings.HasPrefix(r.URL.Path, "/debug/")
 		}),
 	)
-	r.Use(middleware.URLFormat)
+	r.Use(_rootRouter.URLFormat)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
@@ -132,21 +187,82 @@ func main() {
 	}
 
 	// Init dependencies
-	userRepo := _userRepo.NewUserRepository(dbConn)
-	locationRepo := _bitsbRepo.NewLocationRepository(dbConn)
-	busRouteRepo := _bitsbRepo.NewBusRouteRepository(dbConn)
+	var (
+		userRepo     users.UserStorer
+		sessionRepo  users.SessionStorer
+		resetRepo    users.PasswordResetStorer
+		locationRepo bitsb.LocationStorer
+		busRouteRepo bitsb.BusRouteStorer
+		calendarRepo bitsb.ServiceCalendarStorer
+		ticketRepo   tickets.TicketStorer
+		unitOfWork   repo.UnitOfWork
+	)
+	switch storage := viper.GetString("STORAGE"); storage {
+	case "", "postgres":
+		dsn := viper.GetString("DB_DSN")
+		logrus.Debug("DB_DSN: ", dsn)
+
+		dbConn, err := sql.Open("postgres", dsn)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer func() {
+			if err := dbConn.Close(); err != nil {
+				logrus.Fatal(err)
+			}
+		}()
+
+		if err = dbConn.Ping(); err != nil {
+			logrus.Fatal(err)
+		}
 
-	userService := _userService.NewUserService(userRepo, jwtInstance)
-	locationService := _bitsbService.NewLocationService(locationRepo)
-	busRouteService := _bitsbService.NewBusRouteService(busRouteRepo, locationRepo)
+		userRepo = _userRepo.NewUserRepository(dbConn)
+		sessionRepo = _userRepo.NewSessionRepository(dbConn)
+		resetRepo = _userRepo.NewPasswordResetRepository(dbConn)
+		locationRepo = _bitsbRepo.NewLocationRepository(dbConn)
+		busRouteRepo = _bitsbRepo.NewBusRouteRepository(dbConn)
+		calendarRepo = _bitsbRepo.NewServiceCalendarRepository(dbConn)
+		ticketRepo = _ticketRepo.NewTicketRepository(dbConn)
+		unitOfWork = repo.NewUnitOfWork(dbConn)
+	case "memory":
+		logrus.Warn("using in-memory storage, data is lost on restart")
+		store := inmemory.NewStore()
+		userRepo = inmemory.NewUserRepository(store)
+		sessionRepo = inmemory.NewSessionRepository(store)
+		resetRepo = inmemory.NewPasswordResetRepository(store)
+		locationRepo = inmemory.NewLocationRepository(store)
+		busRouteRepo = inmemory.NewBusRouteRepository(store)
+		calendarRepo = inmemory.NewServiceCalendarRepository(store)
+		ticketRepo = inmemory.NewTicketRepository(store)
+		unitOfWork = store
+	default:
+		logrus.Fatalf("unknown STORAGE %q, use postgres or memory", storage)
+	}
+
+	userService := _userService.NewUserService(userRepo, sessionRepo, resetRepo, jwtInstance, mailer, unitOfWork)
+	locationService := _bitsbService.NewLocationService(locationRepo, unitOfWork)
+	busRouteService := _bitsbService.NewBusRouteService(busRouteRepo, locationRepo, calendarRepo)
+	calendarService := _bitsbService.NewServiceCalendarService(calendarRepo)
+	journeyService := _bitsbService.NewJourneyService(busRouteRepo)
+	ticketService := _ticketService.NewTicketService(ticketRepo, busRouteService, ticketSigner, unitOfWork)
 
-	jwtMiddleware := middl.JWTAuth(jwtInstance, userRepo)
+	jwtMiddleware := middl.JWTAuth(jwtInstance, userRepo, sessionRepo)
 
 	// Register routes
-	_rootRouter.RegisterRoutes(r)
-	_userRouter.RegisterRoutes(r, userService, jwtMiddleware)
-	_bitsbRouter.RegisterLocationRoutes(r, locationService, jwtMiddleware)
-	_bitsbRouter.RegisterBusRouteRoutes(r, busRouteService, jwtMiddleware)
+	registerAPIRoutes := func(r chi.Router) {
+		_userRouter.RegisterRoutes(r, userService, jwtMiddleware)
+		_bitsbRouter.RegisterLocationRoutes(r, locationService, jwtMiddleware)
+		_bitsbRouter.RegisterBusRouteRoutes(r, busRouteService, jwtMiddleware)
+		_bitsbRouter.RegisterServiceCalendarRoutes(r, calendarService, jwtMiddleware)
+		_bitsbRouter.RegisterJourneyRoutes(r, journeyService, jwtMiddleware)
+		_ticketRouter.RegisterRoutes(r, ticketService, jwtMiddleware)
+	}
+	_rootRouter.RegisterRoutes(r, jwtInstance)
+	registerAPIRoutes(r)
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(api.WithVersion(api.V2))
+		registerAPIRoutes(r)
+	})
 
 	if viper.GetBool("SERVER_DEBUG") {
 		r.Mount("/debug", middleware.Profiler())
@@ -158,6 +274,25 @@ func main() {
 		ReadHeaderTimeout: time.Duration(viper.GetInt("SERVER_TIMEOUT")) * time.Second,
 	}
 
-	logrus.Info("Listening on: http://0.0.0.0" + server.Addr)
-	logrus.Fatal(server.ListenAndServe())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		logrus.Info("Listening on: http://0.0.0.0" + server.Addr)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			logrus.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+	stop()
+
+	// the requests in flight are served and the mails they started are sent before exiting
+	logrus.Info("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logrus.Error(err)
+	}
+	if err := userService.Close(ctx); err != nil {
+		logrus.Error("waiting for the mails to be sent: ", err)
+	}
 }
@@ -1,101 +1,252 @@
 package apperrors
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 )
 
-// Error is a custom error wrapper with more information
+// Error is a custom error wrapper with more information, Code is a stable
+// name of the error for clients to match on and Err is its cause, if any.
+// Details are sent to the client along with the message.
 type Error struct {
 	StatusCode int
+	Code       string
 	Message    string
+	Details    interface{}
+	Err        error
 }
 
+// New returns an error with the generic code of its status code
 func New(statusCode int, message string) error {
+	return NewWithCode(statusCode, genericCode(statusCode), message)
+}
+
+// NewWithCode returns an error with a code of its own
+func NewWithCode(statusCode int, code, message string) error {
 	return &Error{
 		StatusCode: statusCode,
+		Code:       code,
 		Message:    message,
 	}
 }
 
 func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
 	return e.Message
 }
 
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether e has the code of target, every error
+// also is the error with the generic code of its status code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code || t.Code == genericCode(e.StatusCode)
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// WithDetails returns a copy of e with details
+func (e *Error) WithDetails(details interface{}) *Error {
+	detailed := *e
+	detailed.Details = details
+	return &detailed
+}
+
+// genericCode returns the code of errors that have no code of their own,
+// it is the status text of the status code like "not_found"
+func genericCode(statusCode int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_")
+}
+
 func GetErrStatusCode(err error) int {
-	switch e := err.(type) {
-	case *Error:
+	var e *Error
+	if errors.As(err, &e) {
 		return e.StatusCode
-	default:
-		return http.StatusInternalServerError
 	}
+	return http.StatusInternalServerError
 }
 
 var (
 	// BadRequest apperrors
 	ErrBadRequest = &Error{
 		StatusCode: http.StatusBadRequest,
+		Code:       "bad_request",
 		Message:    "bad request",
 	}
 	ErrBadCursor = &Error{
 		StatusCode: http.StatusBadRequest,
+		Code:       "pagination.bad_cursor",
 		Message:    "bad cursor",
 	}
 	ErrBadInputParam = &Error{
 		StatusCode: http.StatusBadRequest,
+		Code:       "request.bad_param",
 		Message:    "bad input param, check your input params",
 	}
 	ErrEmptyRequest = &Error{
 		StatusCode: http.StatusBadRequest,
+		Code:       "request.empty_body",
 		Message:    "empty request body",
 	}
 	ErrInvalidLocation = &Error{
 		StatusCode: http.StatusBadRequest,
+		Code:       "location.invalid",
 		Message:    "invalid location",
 	}
 
 	// Unauthorized apperrors
 	ErrUnauthorized = &Error{
 		StatusCode: http.StatusUnauthorized,
+		Code:       "unauthorized",
 		Message:    "unauthorized",
 	}
+	ErrMissingToken = &Error{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "auth.token_missing",
+		Message:    "auth token not provided",
+	}
 	ErrInvalidToken = &Error{
 		StatusCode: http.StatusUnauthorized,
+		Code:       "auth.token_invalid",
 		Message:    "invalid token",
 	}
 	ErrExpiredToken = &Error{
 		StatusCode: http.StatusUnauthorized,
+		Code:       "auth.token_expired",
 		Message:    "expired token",
 	}
+	ErrTokenReused = &Error{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "auth.token_reused",
+		Message:    "the refresh token was already used, log in again",
+	}
+	ErrSessionRevoked = &Error{
+		StatusCode: http.StatusUnauthorized,
+		Code:       "auth.session_revoked",
+		Message:    "the session was signed out, log in again",
+	}
 	ErrInvalidCredentials = &Error{
 		StatusCode: http.StatusUnauthorized,
+		Code:       "auth.invalid_credentials",
 		Message:    "invalid credentials",
 	}
 
 	// Forbidden apperrors
 	ErrForbidden = &Error{
 		StatusCode: http.StatusForbidden,
+		Code:       "forbidden",
 		Message:    "forbidden",
 	}
+	ErrPermissionDenied = &Error{
+		StatusCode: http.StatusForbidden,
+		Code:       "auth.permission_denied",
+		Message:    "you don't have permission to perform this action",
+	}
+	ErrEmailNotVerified = &Error{
+		StatusCode: http.StatusForbidden,
+		Code:       "user.email_not_verified",
+		Message:    "verify your email to perform this action",
+	}
 
 	// Not Found apperrors
 	ErrNotFound = &Error{
 		StatusCode: http.StatusNotFound,
+		Code:       "not_found",
 		Message:    "entity not found",
 	}
+	ErrLocationNotFound = &Error{
+		StatusCode: http.StatusNotFound,
+		Code:       "location.not_found",
+		Message:    "location not found",
+	}
+	ErrRouteNotFound = &Error{
+		StatusCode: http.StatusNotFound,
+		Code:       "route.not_found",
+		Message:    "bus route not found",
+	}
+	ErrCalendarNotFound = &Error{
+		StatusCode: http.StatusNotFound,
+		Code:       "calendar.not_found",
+		Message:    "service calendar not found",
+	}
+	ErrTicketNotFound = &Error{
+		StatusCode: http.StatusNotFound,
+		Code:       "ticket.not_found",
+		Message:    "ticket not found",
+	}
+	ErrUserNotFound = &Error{
+		StatusCode: http.StatusNotFound,
+		Code:       "user.not_found",
+		Message:    "user not found",
+	}
+	ErrSessionNotFound = &Error{
+		StatusCode: http.StatusNotFound,
+		Code:       "session.not_found",
+		Message:    "session not found",
+	}
 
 	// Conflict apperrors
 	ErrConflict = &Error{
 		StatusCode: http.StatusConflict,
+		Code:       "conflict",
 		Message:    "database conflict occurred",
 	}
 	ErrEntityAlreadyExist = &Error{
 		StatusCode: http.StatusConflict,
+		Code:       "entity.already_exists",
 		Message:    "entity already exist",
 	}
+	ErrEntityInUse = &Error{
+		StatusCode: http.StatusConflict,
+		Code:       "entity.in_use",
+		Message:    "entity is still referenced by other records",
+	}
+	ErrLocationInUse = &Error{
+		StatusCode: http.StatusConflict,
+		Code:       "location.in_use",
+		Message:    "location is a stop of bus routes or tickets or the home or work location of users",
+	}
+	ErrLocationTicketed = &Error{
+		StatusCode: http.StatusConflict,
+		Code:       "location.ticketed",
+		Message:    "tickets are booked to the location or on bus routes stopping there",
+	}
+	ErrTooFewStops = &Error{
+		StatusCode: http.StatusConflict,
+		Code:       "route.too_few_stops",
+		Message:    "bus routes would be left with less than 2 stops",
+	}
+	ErrEmailAlreadyVerified = &Error{
+		StatusCode: http.StatusConflict,
+		Code:       "user.email_already_verified",
+		Message:    "the email is already verified",
+	}
+
+	// Unprocessable Entity apperrors
+	ErrValidation = &Error{
+		StatusCode: http.StatusUnprocessableEntity,
+		Code:       "validation.failed",
+		Message:    "validation failed",
+	}
 
 	// Internal Server apperrors
 	ErrInternalServerError = &Error{
 		StatusCode: http.StatusInternalServerError,
+		Code:       "internal_server_error",
 		Message:    "an internal server error occurred, we are checking...",
 	}
 )
@@ -0,0 +1,47 @@
+package apperrors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIs(t *testing.T) {
+	wrapped := ErrRouteNotFound.Wrap(sql.ErrNoRows)
+
+	require.ErrorIs(t, wrapped, ErrRouteNotFound)
+	require.ErrorIs(t, wrapped, ErrNotFound)
+	require.ErrorIs(t, wrapped, sql.ErrNoRows)
+	require.NotErrorIs(t, wrapped, ErrLocationNotFound)
+	require.NotErrorIs(t, ErrNotFound, ErrRouteNotFound)
+	require.ErrorIs(t, fmt.Errorf("selecting route: %w", wrapped), ErrRouteNotFound)
+	require.ErrorIs(t, New(http.StatusBadRequest, "bad date"), ErrBadRequest)
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		code  string
+		cause error
+	}{
+		{"when the error has a code", ErrExpiredToken, "auth.token_expired", nil},
+		{"when the error is wrapped", fmt.Errorf("booking: %w", ErrConflict), "conflict", nil},
+		{"when no row was found", sql.ErrNoRows, "not_found", sql.ErrNoRows},
+		{"when the error is unknown", errors.New("connection refused"), "internal_server_error", errors.New("connection refused")},
+		{"when the form is invalid", (&ValidationError{Errors: []FieldError{{Field: "name"}}}).Err(), "validation.failed", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := ParseError(tt.err)
+			require.Equal(t, tt.code, e.Code)
+			if tt.cause != nil {
+				require.Equal(t, tt.cause, e.Unwrap())
+			}
+		})
+	}
+}
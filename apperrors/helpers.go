@@ -2,6 +2,7 @@ package apperrors
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,42 +11,60 @@ import (
 	"github.com/lib/pq"
 )
 
+// ParseError returns the Error to respond with for err,
+// errors that are not an Error are kept as its cause
 func ParseError(err error) *Error {
-	switch e := err.(type) {
-	case *Error:
+	var (
+		e        *Error
+		validErr *ValidationError
+		numErr   *strconv.NumError
+		pqErr    *pq.Error
+	)
+	switch {
+	case errors.As(err, &validErr):
+		return ErrValidation.Wrap(err)
+
+	case errors.As(err, &e):
 		return e
 
-	case *strconv.NumError:
+	case errors.As(err, &numErr):
 		return &Error{
 			StatusCode: http.StatusBadRequest,
-			Message:    fmt.Sprintf(`%q is not a valid number.`, e.Num),
+			Code:       "request.bad_number",
+			Message:    fmt.Sprintf(`%q is not a valid number.`, numErr.Num),
+			Err:        err,
 		}
 
-	case *pq.Error:
+	case errors.As(err, &pqErr):
 		//https://github.com/lib/pq/blob/922c00e176fb3960d912dc2c7f67ea2cf18d27b0/error.go#L78
-		switch e.Code {
+		switch pqErr.Code {
 		case "23502":
 			// not-null constraint violation
 			return &Error{
 				StatusCode: http.StatusConflict,
-				Message:    fmt.Sprint("some required data was left out:", e.Message),
+				Code:       "entity.missing_data",
+				Message:    fmt.Sprint("some required data was left out:", pqErr.Message),
+				Err:        err,
 			}
+		case "23503":
+			// foreign key violation
+			return ErrEntityInUse.Wrap(err)
 		case "23505":
 			// unique constraint violation
 			return &Error{
 				StatusCode: http.StatusConflict,
-				Message:    fmt.Sprint("this record already exists:", e.Message),
+				Code:       "entity.already_exists",
+				Message:    fmt.Sprint("this record already exists:", pqErr.Message),
+				Err:        err,
 			}
 		}
 
-	default:
-		switch err {
-		case sql.ErrNoRows:
-			return ErrNotFound
-		case io.EOF:
-			return ErrEmptyRequest
-		}
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNotFound.Wrap(err)
+
+	case errors.Is(err, io.EOF):
+		return ErrEmptyRequest.Wrap(err)
 	}
 
-	return ErrInternalServerError
+	return ErrInternalServerError.Wrap(err)
 }
@@ -0,0 +1,48 @@
+package apperrors
+
+import (
+	"strings"
+)
+
+// FieldError is a validation rule broken by a field of a request
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// ValidationError collects every rule broken by the fields of a request
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Add adds a broken rule of field, code names the rule
+func (e *ValidationError) Add(field, code, detail string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Detail: detail})
+}
+
+// Has reports whether field broke any rule
+func (e *ValidationError) Has(field string) bool {
+	for _, fe := range e.Errors {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns the validation error, or nil when no rule was broken
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	details := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		details = append(details, fe.Detail)
+	}
+	return strings.Join(details, ", ")
+}
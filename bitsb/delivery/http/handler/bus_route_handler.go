@@ -4,10 +4,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v4"
 
 	"github.com/sainak/bitsb/api"
 	"github.com/sainak/bitsb/apperrors"
@@ -17,8 +19,56 @@ import (
 	"github.com/sainak/bitsb/users/delivery/http/middleware"
 )
 
-type TicketPriceResponse struct {
-	TicketPrice float64 `json:"ticket_price"`
+// getDate returns the `date` query param of the request,
+// or fallback when it is not provided
+func getDate(r *http.Request, fallback bitsb.Date) (bitsb.Date, error) {
+	d := r.URL.Query().Get("date")
+	if d == "" {
+		return fallback, nil
+	}
+	date, err := bitsb.ParseDate(d)
+	if err != nil {
+		return fallback, apperrors.ErrBadInputParam
+	}
+	return date, nil
+}
+
+// timeOfDay returns the local wall clock time of t on the clock of the route timings,
+// the first day of year 0 in UTC as parsed from "15:04". Trips of the day before
+// running past midnight are on that clock too, so no day has to be added after midnight.
+func timeOfDay(t time.Time) time.Time {
+	return time.Date(0, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// getSearch sets the search filters of the request on filters, they are `query`
+// for the name or number, `price_from` and `price_to`, `running_at` as a time
+// of day and any `filter[column:operator]` column filters
+func getSearch(r *http.Request, filters *bitsb.BusRouteFilters) error {
+	q := r.URL.Query()
+	filters.Query = q.Get("query")
+	if p := q.Get("price_from"); p != "" {
+		price, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return err
+		}
+		filters.PriceFrom = null.IntFrom(price)
+	}
+	if p := q.Get("price_to"); p != "" {
+		price, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return err
+		}
+		filters.PriceTo = null.IntFrom(price)
+	}
+	if t := q.Get("running_at"); t != "" {
+		runningAt, err := time.Parse("15:04", t)
+		if err != nil {
+			return apperrors.ErrBadInputParam
+		}
+		filters.RunningAt = null.TimeFrom(runningAt)
+	}
+	filters.Filters = handler.GetFilters(r)
+	return nil
 }
 
 type BusRouteHandler struct {
@@ -48,37 +98,84 @@ func (h *BusRouteHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 
 	logrus.Debug(locations)
 
-	busRoutes, nextCursor, err := h.service.ListAll(r.Context(), cursor, limit, locations)
+	date, err := getDate(r, bitsb.Date{})
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	// ordered only matches routes that go through the locations in the given order
+	ordered, _ := strconv.ParseBool(r.URL.Query().Get("ordered"))
+
+	filters := bitsb.BusRouteFilters{
+		Locations: locations,
+		Ordered:   ordered,
+		Date:      date,
+	}
+	if err = getSearch(r, &filters); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	busRoutes, page, err := h.service.ListAll(r.Context(), cursor, limit, filters, handler.GetSort(r))
 	if err != nil {
 		logrus.Error(err)
 		api.RespondForError(w, r, err)
 		return
 	}
 
-	w.Header().Set("X-Cursor", nextCursor)
-	render.JSON(w, r, busRoutes)
+	api.RespondList(w, r, busRoutes, page)
 }
 
 func (h *BusRouteHandler) BusesForUser(w http.ResponseWriter, r *http.Request) {
 	cursor := r.URL.Query().Get("cursor")
 	limit := handler.GetLimit(r)
 
-	user := r.Context().Value(middleware.UserCtxKey).(users.User)
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
 	homeLocation := user.HomeLocationID.ValueOrZero()
 	workLocation := user.WorkLocationID.ValueOrZero()
 	if homeLocation == 0 || workLocation == 0 {
-		api.RespondForError(w, r, apperrors.New(http.StatusBadRequest, "user has no home or work location"))
+		api.RespondForError(w, r, apperrors.NewWithCode(http.StatusBadRequest, "user.no_commute", "user has no home or work location"))
+		return
+	}
+
+	// direction is either "to_work" or "to_home", when it is not set
+	// routes going through both locations in any order are returned
+	locations := []int64{homeLocation, workLocation}
+	ordered := false
+	switch r.URL.Query().Get("direction") {
+	case "":
+	case "to_work":
+		ordered = true
+	case "to_home":
+		locations = []int64{workLocation, homeLocation}
+		ordered = true
+	default:
+		api.RespondForError(w, r, apperrors.ErrBadInputParam)
+		return
+	}
+
+	date, err := getDate(r, bitsb.Date{})
+	if err != nil {
+		api.RespondForError(w, r, err)
 		return
 	}
 
-	busRoutes, nextCursor, err := h.service.ListAll(r.Context(), cursor, limit, []int64{homeLocation, workLocation})
+	filters := bitsb.BusRouteFilters{
+		Locations: locations,
+		Ordered:   ordered,
+		Date:      date,
+	}
+	if err = getSearch(r, &filters); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	busRoutes, page, err := h.service.ListAll(r.Context(), cursor, limit, filters, handler.GetSort(r))
 	if err != nil {
 		api.RespondForError(w, r, err)
 		return
 	}
 
-	w.Header().Set("X-Cursor", nextCursor)
-	render.JSON(w, r, busRoutes)
+	api.RespondList(w, r, busRoutes, page)
 }
 
 func (h *BusRouteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -95,6 +192,57 @@ func (h *BusRouteHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, busRoute)
 }
 
+func (h *BusRouteHandler) Timetable(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	date, err := getDate(r, bitsb.Date{})
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	timetable, err := h.service.Timetable(r.Context(), id, date)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, timetable)
+}
+
+func (h *BusRouteHandler) Departures(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	limit := handler.GetLimit(r)
+
+	// after is a time of day, when it is not provided departures from now are returned
+	after := timeOfDay(time.Now())
+	if a := r.URL.Query().Get("after"); a != "" {
+		after, err = time.Parse("15:04", a)
+		if err != nil {
+			api.RespondForError(w, r, apperrors.ErrBadInputParam)
+			return
+		}
+	}
+
+	date, err := getDate(r, bitsb.NewDate(time.Now()))
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	departures, err := h.service.Departures(r.Context(), id, date, after, limit)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	api.RespondFullList(w, r, departures, len(departures))
+}
+
 func (h *BusRouteHandler) Create(w http.ResponseWriter, r *http.Request) {
 	data := &bitsb.BusRouteForm{}
 	err := render.Bind(r, data)
@@ -104,14 +252,19 @@ func (h *BusRouteHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	busRoute := &bitsb.BusRoute{
-		Name:        data.Name,
-		Number:      data.Number,
-		StartTime:   data.StartTime,
-		EndTime:     data.EndTime,
-		Interval:    data.Interval,
-		LocationIDS: data.LocationIDS,
-		MaxPrice:    data.MaxPrice,
-		MinPrice:    data.MinPrice,
+		Name:             data.Name,
+		Number:           data.Number,
+		StartTime:        data.StartTime,
+		EndTime:          data.EndTime,
+		Interval:         data.Interval,
+		LocationIDS:      data.LocationIDS,
+		CalendarID:       data.CalendarID,
+		SegmentDurations: data.SegmentDurations,
+		SegmentDistances: data.SegmentDistances,
+		MaxPrice:         data.MaxPrice,
+		MinPrice:         data.MinPrice,
+		FareStrategy:     data.FareStrategy,
+		FareConfig:       data.FareConfig,
 	}
 
 	if err = h.service.Create(r.Context(), busRoute); err != nil {
@@ -135,13 +288,20 @@ func (h *BusRouteHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	busRoute := &bitsb.BusRoute{
-		ID:          id,
-		Name:        data.Name,
-		Number:      data.Number,
-		StartTime:   data.StartTime,
-		EndTime:     data.EndTime,
-		Interval:    data.Interval,
-		LocationIDS: data.LocationIDS,
+		ID:               id,
+		Name:             data.Name,
+		Number:           data.Number,
+		StartTime:        data.StartTime,
+		EndTime:          data.EndTime,
+		Interval:         data.Interval,
+		LocationIDS:      data.LocationIDS,
+		CalendarID:       data.CalendarID,
+		SegmentDurations: data.SegmentDurations,
+		SegmentDistances: data.SegmentDistances,
+		MaxPrice:         data.MaxPrice,
+		MinPrice:         data.MinPrice,
+		FareStrategy:     data.FareStrategy,
+		FareConfig:       data.FareConfig,
 	}
 
 	if err = h.service.Update(r.Context(), busRoute); err != nil {
@@ -188,13 +348,11 @@ func (h *BusRouteHandler) TicketPrice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ticketPrice, err := h.service.CalculateTicketPrice(r.Context(), id, start, end)
+	fare, err := h.service.CalculateTicketPrice(r.Context(), id, start, end)
 	if err != nil {
 		api.RespondForError(w, r, err)
 		return
 	}
 
-	render.JSON(w, r, render.M{
-		"ticket_price": ticketPrice,
-	})
+	render.JSON(w, r, fare)
 }
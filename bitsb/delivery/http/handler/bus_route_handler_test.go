@@ -1 +1,87 @@
 package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/mocks"
+)
+
+type BusRouteHandlerTestSuite struct {
+	suite.Suite
+	handler *BusRouteHandler
+	service *mocks.BusRouteServiceProvider
+}
+
+func TestBusRouteHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(BusRouteHandlerTestSuite))
+}
+
+func (s *BusRouteHandlerTestSuite) SetupTest() {
+	s.service = new(mocks.BusRouteServiceProvider)
+	s.handler = NewBusRouteHandler(s.service)
+}
+
+func (s *BusRouteHandlerTestSuite) TestDepartures() {
+	t := s.T()
+
+	after, _ := time.Parse("15:04", "08:15")
+	date, _ := bitsb.ParseDate("2020-11-02")
+	departures := []*bitsb.Departure{
+		{BusRouteID: 8, Number: "8A", Time: after.Add(15 * time.Minute)},
+		{BusRouteID: 8, Number: "8A", Time: after.Add(35 * time.Minute)},
+	}
+
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/locations/2/departures?after=08:15&date=2020-11-02", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "2")
+		return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	t.Run("when the departures are listed", func(t *testing.T) {
+		s.service.
+			On("Departures", mock.Anything, int64(2), date, after, int64(10)).
+			Return(departures, nil).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Departures(w, request())
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var got []map[string]interface{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Len(t, got, 2)
+		require.Equal(t, "08:30", got[0]["time"])
+	})
+
+	t.Run("when the api version wraps the list in an envelope", func(t *testing.T) {
+		s.service.
+			On("Departures", mock.Anything, int64(2), date, after, int64(10)).
+			Return(departures, nil).
+			Once()
+
+		w := httptest.NewRecorder()
+		api.WithVersion(api.V2)(http.HandlerFunc(s.handler.Departures)).ServeHTTP(w, request())
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var got struct {
+			Data  []map[string]interface{} `json:"data"`
+			Total *int64                   `json:"total"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Len(t, got.Data, 2)
+		require.Equal(t, int64(2), *got.Total)
+	})
+}
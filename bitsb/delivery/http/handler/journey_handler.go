@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+)
+
+type JourneyHandler struct {
+	service bitsb.JourneyServiceProvider
+}
+
+func NewJourneyHandler(service bitsb.JourneyServiceProvider) *JourneyHandler {
+	return &JourneyHandler{
+		service: service,
+	}
+}
+
+func (h *JourneyHandler) Plan(w http.ResponseWriter, r *http.Request) {
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, apperrors.ErrBadInputParam)
+		return
+	}
+	to, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, apperrors.ErrBadInputParam)
+		return
+	}
+
+	// depart_at is a time of day on the clock of the route timings,
+	// when it is not provided the journey starts now
+	departAt := timeOfDay(time.Now())
+	if d := r.URL.Query().Get("depart_at"); d != "" {
+		departAt, err = time.Parse("15:04", d)
+		if err != nil {
+			api.RespondForError(w, r, apperrors.ErrBadInputParam)
+			return
+		}
+	}
+
+	journeys, err := h.service.Plan(r.Context(), from, to, departAt)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	api.RespondFullList(w, r, journeys, len(journeys))
+}
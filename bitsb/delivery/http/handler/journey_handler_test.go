@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/undefinedlabs/go-mpatch"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/mocks"
+)
+
+type JourneyHandlerTestSuite struct {
+	suite.Suite
+	handler *JourneyHandler
+	service *mocks.JourneyServiceProvider
+}
+
+func TestJourneyHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(JourneyHandlerTestSuite))
+}
+
+func (s *JourneyHandlerTestSuite) SetupTest() {
+	s.service = new(mocks.JourneyServiceProvider)
+	s.handler = NewJourneyHandler(s.service)
+}
+
+func (s *JourneyHandlerTestSuite) TestPlan() {
+	t := s.T()
+
+	departAt, _ := time.Parse("15:04", "08:15")
+
+	t.Run("when the journey is planned", func(t *testing.T) {
+		s.service.
+			On("Plan", mock.Anything, int64(1), int64(4), departAt).
+			Return([]*bitsb.Journey{}, nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, "/journeys?from=1&to=4&depart_at=08:15", nil)
+		w := httptest.NewRecorder()
+
+		s.handler.Plan(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("when depart_at defaults to the local time of day", func(t *testing.T) {
+		patch, err := mpatch.PatchMethod(time.Now, func() time.Time {
+			return time.Date(2020, 11, 2, 0, 20, 45, 0, time.FixedZone("IST", 5*60*60+30*60))
+		})
+		require.NoError(t, err)
+		defer func(patch *mpatch.Patch) {
+			require.NoError(t, patch.Unpatch())
+		}(patch)
+
+		now, _ := time.Parse("15:04", "00:20")
+		s.service.
+			On("Plan", mock.Anything, int64(1), int64(4), now).
+			Return([]*bitsb.Journey{}, nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, "/journeys?from=1&to=4", nil)
+		w := httptest.NewRecorder()
+
+		s.handler.Plan(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	for _, query := range []string{
+		"to=4",
+		"from=one&to=4",
+		"from=1",
+		"from=1&to=four",
+		"from=1&to=4&depart_at=8am",
+		"from=1&to=4&depart_at=25:00",
+	} {
+		t.Run("when the query is "+query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/journeys?"+query, nil)
+			w := httptest.NewRecorder()
+
+			s.handler.Plan(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			require.Contains(t, w.Body.String(), apperrors.ErrBadInputParam.Code)
+		})
+	}
+
+	t.Run("when the origin and destination are the same", func(t *testing.T) {
+		s.service.
+			On("Plan", mock.Anything, int64(1), int64(1), departAt).
+			Return([]*bitsb.Journey{}, apperrors.ErrInvalidLocation).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, "/journeys?from=1&to=1&depart_at=08:15", nil)
+		w := httptest.NewRecorder()
+
+		s.handler.Plan(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"math"
 	"net/http"
 	"strconv"
 
@@ -9,11 +10,17 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/bitsb"
 	"github.com/sainak/bitsb/pkg/handler"
 	"github.com/sainak/bitsb/pkg/repo"
 )
 
+const (
+	defaultNearbyRadius = 1000
+	maxNearbyRadius     = 50000
+)
+
 type LocationHandler struct {
 	service bitsb.LocationServiceProvider
 }
@@ -36,14 +43,63 @@ func (l *LocationHandler) ListAll(w http.ResponseWriter, r *http.Request) {
 		filters["name:ilike"] = query
 	}
 
-	locations, nextCursor, err := l.service.ListAll(r.Context(), cursor, limit, filters)
+	locations, page, err := l.service.ListAll(r.Context(), cursor, limit, filters)
 	if err != nil {
 		api.RespondForError(w, r, err)
 		return
 	}
 
-	w.Header().Set("X-Cursor", nextCursor)
-	render.JSON(w, r, locations)
+	api.RespondList(w, r, locations, page)
+}
+
+// finite reports whether f is neither NaN nor infinite, strconv.ParseFloat accepts both
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func (l *LocationHandler) ListNearby(w http.ResponseWriter, r *http.Request) {
+	lat, err := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	lng, err := strconv.ParseFloat(r.URL.Query().Get("lng"), 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	if !finite(lat) || !finite(lng) || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		api.RespondForError(w, r, apperrors.ErrBadInputParam)
+		return
+	}
+
+	// radius is in meters
+	radius := float64(defaultNearbyRadius)
+	if rad := r.URL.Query().Get("radius"); rad != "" {
+		radius, err = strconv.ParseFloat(rad, 64)
+		if err != nil {
+			api.RespondForError(w, r, err)
+			return
+		}
+		if !finite(radius) {
+			api.RespondForError(w, r, apperrors.ErrBadInputParam)
+			return
+		}
+	}
+	if radius <= 0 {
+		radius = defaultNearbyRadius
+	} else if radius > maxNearbyRadius {
+		radius = maxNearbyRadius
+	}
+	limit := handler.GetLimit(r)
+
+	locations, err := l.service.ListNearby(r.Context(), lat, lng, radius, limit)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	api.RespondFullList(w, r, locations, len(locations))
 }
 
 func (l *LocationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -70,7 +126,9 @@ func (l *LocationHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	location := &bitsb.Location{
-		Name: data.Name,
+		Name:      data.Name,
+		Latitude:  data.Latitude,
+		Longitude: data.Longitude,
 	}
 
 	if err := l.service.Create(r.Context(), location); err != nil {
@@ -95,8 +153,10 @@ func (l *LocationHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	location := &bitsb.Location{
-		ID:   id,
-		Name: data.Name,
+		ID:        id,
+		Name:      data.Name,
+		Latitude:  data.Latitude,
+		Longitude: data.Longitude,
 	}
 
 	if err = l.service.Update(r.Context(), location); err != nil {
@@ -114,7 +174,19 @@ func (l *LocationHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = l.service.Delete(r.Context(), id); err != nil {
+	// cascade=detach removes the location from the routes and users depending on it,
+	// it is refused while tickets depend on the location or on a route stopping there
+	detach := false
+	switch r.URL.Query().Get("cascade") {
+	case "":
+	case "detach":
+		detach = true
+	default:
+		api.RespondForError(w, r, apperrors.ErrBadInputParam)
+		return
+	}
+
+	if err = l.service.Delete(r.Context(), id, detach); err != nil {
 		api.RespondForError(w, r, err)
 		return
 	}
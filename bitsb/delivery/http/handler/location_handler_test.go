@@ -3,6 +3,7 @@ package handler
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -16,6 +17,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/undefinedlabs/go-mpatch"
 
+	"github.com/sainak/bitsb/api"
 	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/bitsb"
 	"github.com/sainak/bitsb/mocks"
@@ -63,7 +65,7 @@ func (s *LocationHandlerTestSuite) TestListAll() {
 	t.Run("when service returns locations successfully", func(t *testing.T) {
 		s.service.
 			On("ListAll", mock.Anything, "", int64(10), repo.Filters{}).
-			Return(locations, "", nil)
+			Return(locations, repo.Page{}, nil)
 
 		r := httptest.NewRequest(http.MethodGet, url, nil)
 		w := httptest.NewRecorder()
@@ -73,10 +75,57 @@ func (s *LocationHandlerTestSuite) TestListAll() {
 		require.Equal(t, http.StatusOK, w.Code)
 	})
 
+	t.Run("when the cursors are returned in headers", func(t *testing.T) {
+		page := repo.Page{Next: "next", Limit: 2, HasMore: true}
+		s.service.
+			On("ListAll", mock.Anything, "", int64(2), repo.Filters{}).
+			Return(locations[:2], page, nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "limit=2"
+		w := httptest.NewRecorder()
+
+		s.handler.ListAll(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "next", w.Header().Get("X-Cursor"))
+		require.Equal(t, "true", w.Header().Get("X-Has-More"))
+		var got []*bitsb.Location
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Len(t, got, 2)
+	})
+
+	t.Run("when the api version wraps the list in an envelope", func(t *testing.T) {
+		page := repo.Page{Next: "next", Prev: "prev", Limit: 2, HasMore: true}
+		s.service.
+			On("ListAll", mock.Anything, "", int64(2), repo.Filters{}).
+			Return(locations[:2], page, nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "limit=2"
+		w := httptest.NewRecorder()
+
+		api.WithVersion(api.V2)(http.HandlerFunc(s.handler.ListAll)).ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, w.Header().Get("X-Cursor"))
+		var got struct {
+			Data       []*bitsb.Location `json:"data"`
+			Pagination repo.Page         `json:"pagination"`
+			Total      *int64            `json:"total"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Len(t, got.Data, 2)
+		require.Equal(t, page, got.Pagination)
+		require.Nil(t, got.Total)
+	})
+
 	t.Run("when service returns locations for filters successfully", func(t *testing.T) {
 		s.service.
 			On("ListAll", mock.Anything, "", int64(10), repo.Filters{"name:ilike": "Test Location 1"}).
-			Return(locations, "", nil)
+			Return(locations, repo.Page{}, nil)
 
 		r := httptest.NewRequest(http.MethodGet, url, nil)
 		r.URL.RawQuery = "query=Test Location 1"
@@ -90,7 +139,7 @@ func (s *LocationHandlerTestSuite) TestListAll() {
 	t.Run("when service returns error", func(t *testing.T) {
 		s.service.
 			On("ListAll", mock.Anything, "", int64(1), repo.Filters{}).
-			Return([]*bitsb.Location{}, "", apperrors.ErrInternalServerError)
+			Return([]*bitsb.Location{}, repo.Page{}, apperrors.ErrInternalServerError)
 
 		r := httptest.NewRequest(http.MethodGet, url, nil)
 		r.URL.RawQuery = "limit=1"
@@ -102,6 +151,101 @@ func (s *LocationHandlerTestSuite) TestListAll() {
 	})
 }
 
+func (s *LocationHandlerTestSuite) TestListNearby() {
+	t := s.T()
+
+	url := "/locations/nearby"
+
+	locations := []*bitsb.NearbyLocation{
+		{Location: bitsb.Location{ID: 1, Name: "Test Location 1"}, Distance: 10},
+		{Location: bitsb.Location{ID: 2, Name: "Test Location 2"}, Distance: 250},
+	}
+
+	t.Run("when service returns nearby locations successfully", func(t *testing.T) {
+		s.service.
+			On("ListNearby", mock.Anything, 12.97, 77.59, float64(1000), int64(10)).
+			Return(locations, nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "lat=12.97&lng=77.59"
+		w := httptest.NewRecorder()
+
+		s.handler.ListNearby(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("when radius is above the maximum", func(t *testing.T) {
+		s.service.
+			On("ListNearby", mock.Anything, 12.97, 77.59, float64(maxNearbyRadius), int64(10)).
+			Return(locations, nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "lat=12.97&lng=77.59&radius=100000"
+		w := httptest.NewRecorder()
+
+		s.handler.ListNearby(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("when coordinates are missing", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "lat=12.97"
+		w := httptest.NewRecorder()
+
+		s.handler.ListNearby(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	for _, query := range []string{
+		"lat=NaN&lng=77.59",
+		"lat=12.97&lng=nan",
+		"lat=-Inf&lng=77.59",
+		"lat=12.97&lng=77.59&radius=NaN",
+		"lat=12.97&lng=77.59&radius=Inf",
+	} {
+		t.Run("when the query is "+query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, url, nil)
+			r.URL.RawQuery = query
+			w := httptest.NewRecorder()
+
+			s.handler.ListNearby(w, r)
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			require.Contains(t, w.Body.String(), apperrors.ErrBadInputParam.Code)
+		})
+	}
+
+	t.Run("when coordinates are out of range", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "lat=120&lng=77.59"
+		w := httptest.NewRecorder()
+
+		s.handler.ListNearby(w, r)
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("when service returns error", func(t *testing.T) {
+		s.service.
+			On("ListNearby", mock.Anything, 12.97, 77.59, float64(500), int64(10)).
+			Return([]*bitsb.NearbyLocation{}, apperrors.ErrInternalServerError).
+			Once()
+
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.URL.RawQuery = "lat=12.97&lng=77.59&radius=500"
+		w := httptest.NewRecorder()
+
+		s.handler.ListNearby(w, r)
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
 func (s *LocationHandlerTestSuite) TestGetByID() {
 	t := s.T()
 
@@ -137,6 +281,33 @@ func (s *LocationHandlerTestSuite) TestGetByID() {
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 
+	t.Run("when the error is rendered as a problem", func(t *testing.T) {
+		s.service.
+			On("GetByID", mock.Anything, int64(4)).
+			Return(&bitsb.Location{}, apperrors.ErrLocationNotFound.Wrap(sql.ErrNoRows))
+
+		r := httptest.NewRequest(http.MethodGet, "/location/4", nil)
+		r.Header.Set("Accept", api.ProblemContentType)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "4")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		s.handler.GetByID(w, r)
+		require.Equal(t, http.StatusNotFound, w.Code)
+		require.Equal(t, api.ProblemContentType, w.Header().Get("Content-Type"))
+		var got api.Problem
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Equal(t, api.Problem{
+			Type:     "urn:bitsb:error:location.not_found",
+			Title:    "Not Found",
+			Status:   http.StatusNotFound,
+			Detail:   "location not found",
+			Instance: "/location/4",
+			Code:     "location.not_found",
+		}, got)
+	})
+
 	t.Run("when the url param is invalid", func(t *testing.T) {
 		s.service.
 			On("GetByID", mock.Anything, int64(3)).
@@ -201,6 +372,26 @@ func (s *LocationHandlerTestSuite) TestCreate() {
 	})
 }
 
+func (s *LocationHandlerTestSuite) TestCreateValidation() {
+	t := s.T()
+
+	r := httptest.NewRequest(http.MethodPost, "/locations", strings.NewReader(`{"latitude": 91}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	s.handler.Create(w, r)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var got api.ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, apperrors.ErrValidation.Message, got.Message)
+	require.Equal(t, []apperrors.FieldError{
+		{Field: "name", Code: "required", Detail: "'name' is required"},
+		{Field: "latitude", Code: "max", Detail: "'latitude' should be at most 90"},
+		{Field: "latitude", Code: "required_with", Detail: "'latitude' and 'longitude' should be provided together"},
+	}, got.Errors)
+}
+
 func (s *LocationHandlerTestSuite) TestUpdate() {}
 
 func (s *LocationHandlerTestSuite) TestDelete() {
@@ -208,7 +399,7 @@ func (s *LocationHandlerTestSuite) TestDelete() {
 
 	t.Run("when service returns location successfully", func(t *testing.T) {
 		s.service.
-			On("Delete", mock.Anything, int64(1)).
+			On("Delete", mock.Anything, int64(1), false).
 			Return(nil)
 
 		r := httptest.NewRequest(http.MethodDelete, "/location/1", nil)
@@ -223,7 +414,7 @@ func (s *LocationHandlerTestSuite) TestDelete() {
 
 	t.Run("when service returns error", func(t *testing.T) {
 		s.service.
-			On("Delete", mock.Anything, int64(3)).
+			On("Delete", mock.Anything, int64(3), false).
 			Return(apperrors.ErrInternalServerError)
 
 		r := httptest.NewRequest(http.MethodDelete, "/location/3", nil)
@@ -236,9 +427,63 @@ func (s *LocationHandlerTestSuite) TestDelete() {
 		require.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 
+	t.Run("when the location is removed from its dependents", func(t *testing.T) {
+		s.service.
+			On("Delete", mock.Anything, int64(4), true).
+			Return(nil).
+			Once()
+
+		r := httptest.NewRequest(http.MethodDelete, "/location/4?cascade=detach", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "4")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		s.handler.Delete(w, r)
+		require.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("when the location is in use", func(t *testing.T) {
+		dependents := &bitsb.LocationDependents{
+			BusRoutes: []*bitsb.BusRouteSummary{{ID: 2, Name: "City Loop", Number: "2B"}},
+			UserIDs:   []int64{7},
+		}
+		s.service.
+			On("Delete", mock.Anything, int64(5), false).
+			Return(apperrors.ErrLocationInUse.WithDetails(dependents)).
+			Once()
+
+		r := httptest.NewRequest(http.MethodDelete, "/location/5", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "5")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		s.handler.Delete(w, r)
+		require.Equal(t, http.StatusConflict, w.Code)
+		var got struct {
+			Code    string                   `json:"code"`
+			Details bitsb.LocationDependents `json:"details"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+		require.Equal(t, "location.in_use", got.Code)
+		require.Equal(t, *dependents, got.Details)
+	})
+
+	t.Run("when the cascade is unknown", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodDelete, "/location/4?cascade=delete", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "4")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+
+		s.handler.Delete(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
 	t.Run("when the url param is invalid", func(t *testing.T) {
 		s.service.
-			On("Delete", mock.Anything, int64(3)).
+			On("Delete", mock.Anything, int64(3), false).
 			Return(apperrors.ErrInternalServerError)
 
 		r := httptest.NewRequest(http.MethodDelete, "/location/invalid", nil)
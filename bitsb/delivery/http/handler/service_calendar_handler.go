@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/bitsb"
+)
+
+type ServiceCalendarHandler struct {
+	service bitsb.ServiceCalendarServiceProvider
+}
+
+func NewServiceCalendarHandler(service bitsb.ServiceCalendarServiceProvider) *ServiceCalendarHandler {
+	return &ServiceCalendarHandler{
+		service: service,
+	}
+}
+
+func (h *ServiceCalendarHandler) ListAll(w http.ResponseWriter, r *http.Request) {
+	calendars, err := h.service.ListAll(r.Context())
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	api.RespondFullList(w, r, calendars, len(calendars))
+}
+
+func (h *ServiceCalendarHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	calendar, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, calendar)
+}
+
+func (h *ServiceCalendarHandler) Create(w http.ResponseWriter, r *http.Request) {
+	data := &bitsb.ServiceCalendarForm{}
+	if err := render.Bind(r, data); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	calendar := &bitsb.ServiceCalendar{
+		Name:         data.Name,
+		Weekdays:     data.Weekdays,
+		StartDate:    data.StartDate,
+		EndDate:      data.EndDate,
+		AddedDates:   data.AddedDates,
+		RemovedDates: data.RemovedDates,
+	}
+
+	if err := h.service.Create(r.Context(), calendar); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, calendar)
+}
+
+func (h *ServiceCalendarHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	data := &bitsb.ServiceCalendarForm{}
+	if err = render.Bind(r, data); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	calendar := &bitsb.ServiceCalendar{
+		ID:           id,
+		Name:         data.Name,
+		Weekdays:     data.Weekdays,
+		StartDate:    data.StartDate,
+		EndDate:      data.EndDate,
+		AddedDates:   data.AddedDates,
+		RemovedDates: data.RemovedDates,
+	}
+
+	if err = h.service.Update(r.Context(), calendar); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, calendar)
+}
+
+func (h *ServiceCalendarHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	if err = h.service.Delete(r.Context(), id); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -12,7 +12,7 @@ import (
 )
 
 func RegisterLocationRoutes(
-	router *chi.Mux,
+	router chi.Router,
 	service bitsb.LocationServiceProvider,
 	jwtMiddleware func(next http.Handler) http.Handler,
 ) {
@@ -22,6 +22,7 @@ func RegisterLocationRoutes(
 		r.Use(jwtMiddleware)
 		r.Route("/locations", func(r chi.Router) {
 			r.Get("/", h.ListAll)
+			r.Get("/nearby", h.ListNearby)
 			r.With(middleware.AccessAbove(users.Admin)).Post("/", h.Create)
 		})
 		r.Route("/location", func(r chi.Router) {
@@ -33,7 +34,7 @@ func RegisterLocationRoutes(
 }
 
 func RegisterBusRouteRoutes(
-	router *chi.Mux,
+	router chi.Router,
 	service bitsb.BusRouteServiceProvider,
 	jwtMiddleware func(next http.Handler) http.Handler,
 ) {
@@ -48,8 +49,42 @@ func RegisterBusRouteRoutes(
 		r.Route("/bus-route", func(r chi.Router) {
 			r.Get("/{id}", h.GetByID)
 			r.Get("/{id}/ticket-price", h.TicketPrice)
+			r.Get("/{id}/timetable", h.Timetable)
 			r.With(middleware.AccessAbove(users.Admin)).Patch("/{id}", h.Update)
 			r.With(middleware.AccessAbove(users.Admin)).Delete("/{id}", h.Delete)
 		})
+		r.Get("/location/{id}/departures", h.Departures)
+	})
+}
+
+func RegisterServiceCalendarRoutes(
+	router chi.Router,
+	service bitsb.ServiceCalendarServiceProvider,
+	jwtMiddleware func(next http.Handler) http.Handler,
+) {
+	h := locationHandler.NewServiceCalendarHandler(service)
+	router.Group(func(r chi.Router) {
+		r.Use(jwtMiddleware)
+		r.Route("/calendars", func(r chi.Router) {
+			r.Get("/", h.ListAll)
+			r.With(middleware.AccessAbove(users.Admin)).Post("/", h.Create)
+		})
+		r.Route("/calendar", func(r chi.Router) {
+			r.Get("/{id}", h.GetByID)
+			r.With(middleware.AccessAbove(users.Admin)).Patch("/{id}", h.Update)
+			r.With(middleware.AccessAbove(users.Admin)).Delete("/{id}", h.Delete)
+		})
+	})
+}
+
+func RegisterJourneyRoutes(
+	router chi.Router,
+	service bitsb.JourneyServiceProvider,
+	jwtMiddleware func(next http.Handler) http.Handler,
+) {
+	h := locationHandler.NewJourneyHandler(service)
+	router.Group(func(r chi.Router) {
+		r.Use(jwtMiddleware)
+		r.Get("/journeys", h.Plan)
 	})
 }
@@ -2,69 +2,135 @@ package bitsb
 
 import (
 	"context"
+	"database/sql/driver"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
+	"gopkg.in/guregu/null.v4"
+
 	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/pkg/utils"
+	"github.com/sainak/bitsb/pkg/validate"
 )
 
 // ---- Location ----
 
 type Location struct {
-	ID        int64     `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	CreatedAt time.Time `json:"created_at" db:"createdAt"`
-	UpdatedAt time.Time `json:"updated_at" db:"updatedAt"`
+	ID        int64      `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Latitude  null.Float `json:"latitude" db:"latitude"`
+	Longitude null.Float `json:"longitude" db:"longitude"`
+	CreatedAt time.Time  `json:"created_at" db:"createdAt"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updatedAt"`
+}
+
+// NearbyLocation is a location along with its distance in meters
+// from the point it was searched from
+type NearbyLocation struct {
+	Location
+	Distance float64 `json:"distance"`
 }
 
 type LocationForm struct {
-	Name string `json:"name"`
+	Name      string     `json:"name" binding:"required"`
+	Latitude  null.Float `json:"latitude" binding:"min=-90,max=90"`
+	Longitude null.Float `json:"longitude" binding:"min=-180,max=180"`
 }
 
 func (l *LocationForm) Bind(r *http.Request) error {
-	if l.Name == "" {
-		return fmt.Errorf("'name' is required")
+	errs := validate.Struct(l)
+	if l.Latitude.Valid != l.Longitude.Valid {
+		errs.Add("latitude", "required_with", "'latitude' and 'longitude' should be provided together")
 	}
-	return nil
+	return errs.Err()
+}
+
+// LocationDependents are the bus routes stopping at a location, the users
+// with it as their home or work location and the tickets from or to it
+type LocationDependents struct {
+	BusRoutes []*BusRouteSummary `json:"bus_routes"`
+	UserIDs   []int64            `json:"user_ids"`
+	TicketIDs []int64            `json:"ticket_ids"`
+}
+
+func (d *LocationDependents) Empty() bool {
+	return len(d.BusRoutes) == 0 && len(d.UserIDs) == 0 && len(d.TicketIDs) == 0
+}
+
+type BusRouteSummary struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Number string `json:"number"`
 }
 
 type (
 	LocationStorer interface {
-		SelectAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*Location, string, error)
+		SelectAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*Location, repo.Page, error)
 		SelectByID(ctx context.Context, id int64) (*Location, error)
 		SelectByIDArray(ctx context.Context, ids []int64) ([]*Location, error)
+		SelectNearby(ctx context.Context, lat, lng, radius float64, limit int64) ([]*NearbyLocation, error)
+		// SelectDependents returns the bus routes, users and tickets depending on the location,
+		// in a transaction it locks the location so none can be added until it ends
+		SelectDependents(ctx context.Context, id int64) (*LocationDependents, error)
 		Insert(ctx context.Context, location *Location) error
 		Update(ctx context.Context, location *Location) error
 		Delete(ctx context.Context, id int64) error
+		// DeleteDetached removes the location from the bus routes and users
+		// depending on it and deletes it, all in one transaction. Tickets are
+		// not detached, it fails with apperrors.ErrLocationTicketed while a ticket
+		// is from or to the location or is booked on a bus route stopping there
+		DeleteDetached(ctx context.Context, id int64) error
 	}
 	LocationServiceProvider interface {
-		ListAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*Location, string, error)
+		ListAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*Location, repo.Page, error)
 		GetByID(ctx context.Context, id int64) (*Location, error)
+		ListNearby(ctx context.Context, lat, lng, radius float64, limit int64) ([]*NearbyLocation, error)
 		Create(ctx context.Context, location *Location) error
 		Update(ctx context.Context, location *Location) error
-		Delete(ctx context.Context, id int64) error
+		Delete(ctx context.Context, id int64, detach bool) error
 	}
 )
 
 // ---- BusRoute ----
 
+// BusRoute is a bus service running through LocationIDS in order.
+// SegmentDurations (in seconds) and SegmentDistances (in meters) have an entry
+// for each consecutive pair of stops, and are empty when unknown.
+// BoardingIndex and AlightingIndex are the positions in LocationIDS
+// of the origin and destination a route was searched for.
 type BusRoute struct {
-	ID          int64           `json:"id" db:"id"`
-	Name        string          `json:"name" db:"name"`
-	Number      string          `json:"number" db:"number"`
-	StartTime   time.Time       `json:"start_time" db:"start_time"`
-	EndTime     time.Time       `json:"end_time" db:"end_time"`
-	Interval    int64           `json:"interval" db:"interval"`
-	LocationIDS []int64         `json:"location_ids" db:"locations"`
-	MinPrice    int64           `json:"min_price"`
-	MaxPrice    int64           `json:"max_price"`
-	CreatedAt   time.Time       `json:"created_at" db:"createdAt"`
-	UpdatedAt   time.Time       `json:"updated_at" db:"updatedAt"`
-	Locations   []*LocationForm `json:"stops,omitempty"`
+	ID               int64           `json:"id" db:"id"`
+	Name             string          `json:"name" db:"name"`
+	Number           string          `json:"number" db:"number"`
+	StartTime        time.Time       `json:"start_time" db:"start_time"`
+	EndTime          time.Time       `json:"end_time" db:"end_time"`
+	Interval         int64           `json:"interval" db:"interval"`
+	LocationIDS      []int64         `json:"location_ids" db:"locations"`
+	CalendarID       null.Int        `json:"calendar_id" db:"calendar_id"`
+	SegmentDurations []int64         `json:"segment_durations" db:"segment_durations"`
+	SegmentDistances []int64         `json:"segment_distances" db:"segment_distances"`
+	MinPrice         int64           `json:"min_price"`
+	MaxPrice         int64           `json:"max_price"`
+	FareStrategy     string          `json:"fare_strategy" db:"fare_strategy"`
+	FareConfig       FareConfig      `json:"fare_config" db:"fare_config"`
+	CreatedAt        time.Time       `json:"created_at" db:"createdAt"`
+	UpdatedAt        time.Time       `json:"updated_at" db:"updatedAt"`
+	Locations        []*LocationForm `json:"stops,omitempty"`
+	Segments         []*Segment      `json:"segments,omitempty"`
+	BoardingIndex    *int            `json:"boarding_index,omitempty"`
+	AlightingIndex   *int            `json:"alighting_index,omitempty"`
+}
+
+// Segment is the stretch of a route between two consecutive stops
+type Segment struct {
+	FromLocationID int64 `json:"from_location_id"`
+	ToLocationID   int64 `json:"to_location_id"`
+	// Duration is the travel time in seconds
+	Duration null.Int `json:"duration"`
+	// Distance is in meters
+	Distance null.Int `json:"distance"`
 }
 
 func (b *BusRoute) MarshalJSON() ([]byte, error) {
@@ -82,46 +148,142 @@ func (b *BusRoute) MarshalJSON() ([]byte, error) {
 	})
 }
 
-type BusRouteForm struct {
-	Name        string    `json:"name"`
-	Number      string    `json:"number"`
-	StartTime   time.Time `json:"start_time"`
-	EndTime     time.Time `json:"end_time"`
-	Interval    int64     `json:"interval"`
-	MinPrice    int64     `json:"min_price"`
-	MaxPrice    int64     `json:"max_price"`
-	LocationIDS []int64   `json:"location_ids"`
+// DefaultStopTravelTime is the estimated time it takes a bus
+// to get from one stop to the next
+const DefaultStopTravelTime = 5 * time.Minute
+
+// DepartureTimes returns every departure from the first stop,
+// starting at StartTime and repeating every Interval minutes until EndTime.
+// Routes whose EndTime is before StartTime are assumed to run past midnight.
+func (b *BusRoute) DepartureTimes() []time.Time {
+	if b.Interval <= 0 {
+		return []time.Time{}
+	}
+	end := b.EndTime
+	if end.Before(b.StartTime) {
+		end = end.Add(24 * time.Hour)
+	}
+	interval := time.Duration(b.Interval) * time.Minute
+	departures := make([]time.Time, 0, end.Sub(b.StartTime)/interval+1)
+	for t := b.StartTime; !t.After(end); t = t.Add(interval) {
+		departures = append(departures, t)
+	}
+	return departures
 }
 
-func (b *BusRouteForm) Bind(r *http.Request) error {
-	const MaxStops = 10
-	var errs []string
-	if b.Name == "" {
-		errs = append(errs, "'name' is required")
+// SegmentDuration returns the travel time from the stop at index i
+// to the next one, falling back to DefaultStopTravelTime when unknown
+func (b *BusRoute) SegmentDuration(i int) time.Duration {
+	if i < len(b.SegmentDurations) && b.SegmentDurations[i] > 0 {
+		return time.Duration(b.SegmentDurations[i]) * time.Second
 	}
-	if b.Number == "" {
-		errs = append(errs, "'number' is required")
+	return DefaultStopTravelTime
+}
+
+// StopOffsets returns the estimated time taken to reach
+// each stop in LocationIDS from the first stop
+func (b *BusRoute) StopOffsets() []time.Duration {
+	offsets := make([]time.Duration, len(b.LocationIDS))
+	for i := 1; i < len(b.LocationIDS); i++ {
+		offsets[i] = offsets[i-1] + b.SegmentDuration(i-1)
 	}
-	if b.StartTime.IsZero() {
-		errs = append(errs, "'start_time' is required")
+	return offsets
+}
+
+// RemoveStop takes the location out of the stops of the route, the segments
+// around the stop are merged into one and its fare zone is dropped
+func (b *BusRoute) RemoveStop(locationID int64) {
+	for i := utils.IndexOf(b.LocationIDS, locationID); i != -1; i = utils.IndexOf(b.LocationIDS, locationID) {
+		segments := len(b.LocationIDS) - 1
+		if len(b.SegmentDurations) == segments {
+			b.SegmentDurations = mergeSegments(b.SegmentDurations, i)
+		}
+		if len(b.SegmentDistances) == segments {
+			b.SegmentDistances = mergeSegments(b.SegmentDistances, i)
+		}
+		if i < len(b.FareConfig.Zones) {
+			b.FareConfig.Zones = append(b.FareConfig.Zones[:i:i], b.FareConfig.Zones[i+1:]...)
+		}
+		b.LocationIDS = append(b.LocationIDS[:i:i], b.LocationIDS[i+1:]...)
 	}
-	if b.EndTime.IsZero() {
-		errs = append(errs, "'end_time' is required")
+}
+
+// mergeSegments returns the values of the segments without the stop at index i,
+// the segments before and after it become one
+func mergeSegments(values []int64, i int) []int64 {
+	switch {
+	case len(values) == 0:
+		return values
+	case i == 0:
+		return append([]int64{}, values[1:]...)
+	case i == len(values):
+		return append([]int64{}, values[:i-1]...)
 	}
-	if b.Interval == 0 {
-		errs = append(errs, "'interval' is required")
+	merged := append([]int64{}, values[:i-1]...)
+	merged = append(merged, values[i-1]+values[i])
+	return append(merged, values[i+1:]...)
+}
+
+// BuildSegments returns the segments between each consecutive pair of stops
+func (b *BusRoute) BuildSegments() []*Segment {
+	if len(b.LocationIDS) < 2 {
+		return []*Segment{}
 	}
-	if len(b.LocationIDS) == 0 {
-		errs = append(errs, "'location_ids' is required")
-	} else if len(b.LocationIDS) < 2 {
-		errs = append(errs, "'location_ids' should have atleast 2 stops")
-	} else if len(b.LocationIDS) > MaxStops {
-		errs = append(errs, fmt.Sprintf("'location_ids' should have atmost %d stops", MaxStops))
+	segments := make([]*Segment, 0, len(b.LocationIDS)-1)
+	for i := 0; i < len(b.LocationIDS)-1; i++ {
+		segment := &Segment{
+			FromLocationID: b.LocationIDS[i],
+			ToLocationID:   b.LocationIDS[i+1],
+		}
+		if i < len(b.SegmentDurations) {
+			segment.Duration = null.IntFrom(b.SegmentDurations[i])
+		}
+		if i < len(b.SegmentDistances) {
+			segment.Distance = null.IntFrom(b.SegmentDistances[i])
+		}
+		segments = append(segments, segment)
 	}
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, ", "))
+	return segments
+}
+
+type BusRouteForm struct {
+	Name             string     `json:"name" binding:"required"`
+	Number           string     `json:"number" binding:"required"`
+	StartTime        time.Time  `json:"start_time" binding:"required"`
+	EndTime          time.Time  `json:"end_time" binding:"required"`
+	Interval         int64      `json:"interval" binding:"required,min=1"`
+	MinPrice         int64      `json:"min_price" binding:"min=0"`
+	MaxPrice         int64      `json:"max_price" binding:"min=0"`
+	FareStrategy     string     `json:"fare_strategy"`
+	FareConfig       FareConfig `json:"fare_config"`
+	LocationIDS      []int64    `json:"location_ids" binding:"required,min=2,max=10"`
+	CalendarID       null.Int   `json:"calendar_id"`
+	SegmentDurations []int64    `json:"segment_durations"`
+	SegmentDistances []int64    `json:"segment_distances"`
+}
+
+func (b *BusRouteForm) Bind(r *http.Request) error {
+	errs := validate.Struct(b)
+	segments := len(b.LocationIDS) - 1
+	if len(b.SegmentDurations) > 0 && len(b.SegmentDurations) != segments {
+		errs.Add("segment_durations", "len", "'segment_durations' should have one value for each pair of consecutive stops")
 	}
-	return nil
+	for _, d := range b.SegmentDurations {
+		if d <= 0 {
+			errs.Add("segment_durations", "min", "'segment_durations' should only have positive values")
+			break
+		}
+	}
+	if len(b.SegmentDistances) > 0 && len(b.SegmentDistances) != segments {
+		errs.Add("segment_distances", "len", "'segment_distances' should have one value for each pair of consecutive stops")
+	}
+	for _, d := range b.SegmentDistances {
+		if d < 0 {
+			errs.Add("segment_distances", "min", "'segment_distances' should not have negative values")
+			break
+		}
+	}
+	return errs.Err()
 }
 
 func (b *BusRouteForm) UnmarshalJSON(data []byte) error {
@@ -141,9 +303,315 @@ func (b *BusRouteForm) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ---- ServiceCalendar ----
+
+const dateLayout = "2006-01-02"
+
+// Date is a day without a time of day, formatted as YYYY-MM-DD.
+// The zero Date is stored and marshaled as null.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the day t falls on
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses a date in the YYYY-MM-DD format
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+// Equal reports whether both dates are the same day
+func (d Date) Equal(other Date) bool {
+	return d.String() == other.String()
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil {
+		*d = Date{}
+		return nil
+	}
+	date, err := ParseDate(*value)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Date{}
+	case time.Time:
+		*d = NewDate(v)
+	case []byte:
+		return d.Scan(string(v))
+	case string:
+		date, err := ParseDate(v[:utils.Min(len(v), len(dateLayout))])
+		if err != nil {
+			return err
+		}
+		*d = date
+	default:
+		return fmt.Errorf("cannot scan %T into Date", src)
+	}
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+// ServiceCalendar describes the days a bus route operates on.
+// Weekdays are numbered from 0 (Sunday) to 6 (Saturday), and the calendar
+// only applies between StartDate and EndDate when they are set.
+// AddedDates and RemovedDates are exceptions to the regular schedule.
+type ServiceCalendar struct {
+	ID           int64     `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Weekdays     []int64   `json:"weekdays" db:"weekdays"`
+	StartDate    Date      `json:"start_date" db:"start_date"`
+	EndDate      Date      `json:"end_date" db:"end_date"`
+	AddedDates   []Date    `json:"added_dates" db:"added_dates"`
+	RemovedDates []Date    `json:"removed_dates" db:"removed_dates"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RunsOn reports whether the calendar has service on the given date
+func (c *ServiceCalendar) RunsOn(date Date) bool {
+	for _, d := range c.AddedDates {
+		if d.Equal(date) {
+			return true
+		}
+	}
+	for _, d := range c.RemovedDates {
+		if d.Equal(date) {
+			return false
+		}
+	}
+	if !c.StartDate.IsZero() && date.Before(c.StartDate.Time) {
+		return false
+	}
+	if !c.EndDate.IsZero() && date.After(c.EndDate.Time) {
+		return false
+	}
+	return utils.IndexOf(c.Weekdays, int64(date.Weekday())) != -1
+}
+
+type ServiceCalendarForm struct {
+	Name         string  `json:"name" binding:"required"`
+	Weekdays     []int64 `json:"weekdays"`
+	StartDate    Date    `json:"start_date"`
+	EndDate      Date    `json:"end_date"`
+	AddedDates   []Date  `json:"added_dates"`
+	RemovedDates []Date  `json:"removed_dates"`
+}
+
+func (c *ServiceCalendarForm) Bind(r *http.Request) error {
+	errs := validate.Struct(c)
+	for _, day := range c.Weekdays {
+		if day < 0 || day > 6 {
+			errs.Add("weekdays", "weekday", "'weekdays' should be between 0 (sunday) and 6 (saturday)")
+			break
+		}
+	}
+	if !c.StartDate.IsZero() && !c.EndDate.IsZero() && c.EndDate.Before(c.StartDate.Time) {
+		errs.Add("end_date", "after", "'end_date' should not be before 'start_date'")
+	}
+	return errs.Err()
+}
+
+type (
+	ServiceCalendarStorer interface {
+		SelectAll(ctx context.Context) ([]*ServiceCalendar, error)
+		SelectByID(ctx context.Context, id int64) (*ServiceCalendar, error)
+		Insert(ctx context.Context, calendar *ServiceCalendar) error
+		Update(ctx context.Context, calendar *ServiceCalendar) error
+		Delete(ctx context.Context, id int64) error
+	}
+	ServiceCalendarServiceProvider interface {
+		ListAll(ctx context.Context) ([]*ServiceCalendar, error)
+		GetByID(ctx context.Context, id int64) (*ServiceCalendar, error)
+		Create(ctx context.Context, calendar *ServiceCalendar) error
+		Update(ctx context.Context, calendar *ServiceCalendar) error
+		Delete(ctx context.Context, id int64) error
+	}
+)
+
+// ---- Timetable ----
+
+type StopTime struct {
+	LocationID int64     `json:"location_id"`
+	Name       string    `json:"name"`
+	Time       time.Time `json:"time"`
+}
+
+func (s *StopTime) MarshalJSON() ([]byte, error) {
+	type Alias StopTime
+	return json.Marshal(&struct {
+		*Alias
+		Time string `json:"time"`
+	}{
+		Alias: (*Alias)(s),
+		Time:  s.Time.Format("15:04"),
+	})
+}
+
+type Trip struct {
+	Departure time.Time   `json:"departure"`
+	Stops     []*StopTime `json:"stops"`
+}
+
+func (t *Trip) MarshalJSON() ([]byte, error) {
+	type Alias Trip
+	return json.Marshal(&struct {
+		*Alias
+		Departure string `json:"departure"`
+	}{
+		Alias:     (*Alias)(t),
+		Departure: t.Departure.Format("15:04"),
+	})
+}
+
+// Departure is a bus leaving a stop, Direction is the
+// name of the last stop of the route it is heading towards
+type Departure struct {
+	BusRouteID            int64     `json:"bus_route_id"`
+	Number                string    `json:"number"`
+	Name                  string    `json:"name"`
+	DestinationLocationID int64     `json:"destination_location_id"`
+	Direction             string    `json:"direction"`
+	Time                  time.Time `json:"time"`
+}
+
+func (d *Departure) MarshalJSON() ([]byte, error) {
+	type Alias Departure
+	return json.Marshal(&struct {
+		*Alias
+		Time string `json:"time"`
+	}{
+		Alias: (*Alias)(d),
+		Time:  d.Time.Format("15:04"),
+	})
+}
+
+type Timetable struct {
+	BusRouteID int64   `json:"bus_route_id"`
+	Name       string  `json:"name"`
+	Number     string  `json:"number"`
+	Date       Date    `json:"date"`
+	Trips      []*Trip `json:"trips"`
+}
+
+// ---- Fare ----
+
+const (
+	FareFlat     = "flat"
+	FarePerStop  = "per_stop"
+	FareZone     = "zone"
+	FareDistance = "distance"
+)
+
+// FareConfig holds the settings of the fare strategy of a route,
+// only the fields used by the chosen strategy need to be set.
+// Zones has the zone number of each stop in LocationIDS and ZonePrices
+// has the price for travelling through one, two, three... zones.
+type FareConfig struct {
+	FlatPrice  int64   `json:"flat_price,omitempty"`
+	Zones      []int64 `json:"zones,omitempty"`
+	ZonePrices []int64 `json:"zone_prices,omitempty"`
+	BasePrice  int64   `json:"base_price,omitempty"`
+	PricePerKM int64   `json:"price_per_km,omitempty"`
+}
+
+func (f *FareConfig) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*f = FareConfig{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, f)
+	case string:
+		return json.Unmarshal([]byte(v), f)
+	default:
+		return fmt.Errorf("cannot scan %T into FareConfig", src)
+	}
+}
+
+func (f FareConfig) Value() (driver.Value, error) {
+	return json.Marshal(f)
+}
+
+// FareComponent is one step of a fare calculation
+type FareComponent struct {
+	Description string `json:"description"`
+	Amount      int64  `json:"amount"`
+}
+
+// Fare is the ticket price between two stops of a route
+// along with a breakdown of how it was calculated
+type Fare struct {
+	Strategy    string           `json:"strategy"`
+	TicketPrice int64            `json:"ticket_price"`
+	Stops       int              `json:"stops"`
+	Components  []*FareComponent `json:"components"`
+}
+
+// FareStrategy calculates the fare of travelling on a bus route
+type FareStrategy interface {
+	// Calculate returns the fare between the stops at the start and end index of the route
+	Calculate(busRoute *BusRoute, start, end int) (*Fare, error)
+	// Validate checks that the route has everything the strategy needs
+	Validate(busRoute *BusRoute) error
+}
+
+// BusRouteFilters narrow down bus route listings, Ordered only matches
+// routes going through Locations in the given order and Date only
+// matches routes whose service calendar runs on that day.
+// Query searches the name and number of routes, PriceFrom and PriceTo match
+// routes with fares in the range and RunningAt matches routes operating at
+// that time of day. Filters are conditions on the other columns of routes.
+type BusRouteFilters struct {
+	Locations []int64
+	Ordered   bool
+	Date      Date
+	Query     string
+	PriceFrom null.Int
+	PriceTo   null.Int
+	RunningAt null.Time
+	Filters   repo.Filters
+}
+
 type (
 	BusRouteStorer interface {
-		SelectAll(ctx context.Context, cursor string, limit int64, locations []int64) ([]*BusRoute, string, error)
+		SelectAll(ctx context.Context, cursor string, limit int64, filters BusRouteFilters, sort repo.Sort) ([]*BusRoute, repo.Page, error)
+		SelectByLocations(ctx context.Context, locations []int64) ([]*BusRoute, error)
 		SelectByID(ctx context.Context, id int64) (*BusRoute, error)
 		Insert(ctx context.Context, busRoute *BusRoute) error
 		Update(ctx context.Context, busRoute *BusRoute) error
@@ -151,11 +619,70 @@ type (
 	}
 
 	BusRouteServiceProvider interface {
-		ListAll(ctx context.Context, cursor string, limit int64, locations []int64) ([]*BusRoute, string, error)
+		ListAll(ctx context.Context, cursor string, limit int64, filters BusRouteFilters, sort repo.Sort) ([]*BusRoute, repo.Page, error)
 		GetByID(ctx context.Context, id int64) (*BusRoute, error)
-		CalculateTicketPrice(ctx context.Context, id, start, end int64) (int64, error)
+		CalculateTicketPrice(ctx context.Context, id, start, end int64) (*Fare, error)
+		Timetable(ctx context.Context, id int64, date Date) (*Timetable, error)
+		Departures(ctx context.Context, locationID int64, date Date, after time.Time, limit int64) ([]*Departure, error)
 		Create(ctx context.Context, busRoute *BusRoute) error
 		Update(ctx context.Context, busRoute *BusRoute) error
 		Delete(ctx context.Context, id int64) error
 	}
 )
+
+// ---- Journey ----
+
+// JourneyLeg is a ride on a single bus route
+type JourneyLeg struct {
+	BusRouteID     int64     `json:"bus_route_id"`
+	Number         string    `json:"number"`
+	Name           string    `json:"name"`
+	FromLocationID int64     `json:"from_location_id"`
+	ToLocationID   int64     `json:"to_location_id"`
+	Stops          int       `json:"stops"`
+	Departure      time.Time `json:"departure"`
+	Arrival        time.Time `json:"arrival"`
+	Fare           int64     `json:"fare"`
+}
+
+func (l *JourneyLeg) MarshalJSON() ([]byte, error) {
+	type Alias JourneyLeg
+	return json.Marshal(&struct {
+		*Alias
+		Departure string `json:"departure"`
+		Arrival   string `json:"arrival"`
+	}{
+		Alias:     (*Alias)(l),
+		Departure: l.Departure.Format("15:04"),
+		Arrival:   l.Arrival.Format("15:04"),
+	})
+}
+
+// Journey is an itinerary between two locations made up of one or more legs,
+// TransferLocationIDS are the stops where the passenger changes buses
+type Journey struct {
+	Legs                []*JourneyLeg `json:"legs"`
+	TransferLocationIDS []int64       `json:"transfer_location_ids"`
+	Departure           time.Time     `json:"departure"`
+	Arrival             time.Time     `json:"arrival"`
+	// Duration is the total time in seconds from the first departure to the final arrival
+	Duration int64 `json:"duration"`
+	Fare     int64 `json:"fare"`
+}
+
+func (j *Journey) MarshalJSON() ([]byte, error) {
+	type Alias Journey
+	return json.Marshal(&struct {
+		*Alias
+		Departure string `json:"departure"`
+		Arrival   string `json:"arrival"`
+	}{
+		Alias:     (*Alias)(j),
+		Departure: j.Departure.Format("15:04"),
+		Arrival:   j.Arrival.Format("15:04"),
+	})
+}
+
+type JourneyServiceProvider interface {
+	Plan(ctx context.Context, from, to int64, departAt time.Time) ([]*Journey, error)
+}
@@ -0,0 +1,92 @@
+package fare
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/utils"
+)
+
+var strategies = map[string]bitsb.FareStrategy{
+	bitsb.FareFlat:     Flat{},
+	bitsb.FarePerStop:  PerStop{},
+	bitsb.FareZone:     Zone{},
+	bitsb.FareDistance: Distance{},
+}
+
+// ForRoute returns the fare strategy of the route,
+// routes without one are charged per stop
+func ForRoute(busRoute *bitsb.BusRoute) (bitsb.FareStrategy, error) {
+	if busRoute.FareStrategy == "" {
+		return strategies[bitsb.FarePerStop], nil
+	}
+	strategy, ok := strategies[busRoute.FareStrategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown fare strategy %q", busRoute.FareStrategy)
+	}
+	return strategy, nil
+}
+
+// Calculate returns the fare for travelling between the start and end locations of the route.
+// Every strategy is bound by the MinPrice and MaxPrice of the route.
+func Calculate(busRoute *bitsb.BusRoute, start, end int64) (*bitsb.Fare, error) {
+	startIndex := utils.IndexOf(busRoute.LocationIDS, start)
+	endIndex := utils.IndexOf(busRoute.LocationIDS, end)
+	if startIndex == -1 || endIndex == -1 || startIndex == endIndex {
+		return &bitsb.Fare{}, apperrors.ErrInvalidLocation
+	}
+	if startIndex > endIndex {
+		startIndex, endIndex = endIndex, startIndex
+	}
+
+	strategy, err := ForRoute(busRoute)
+	if err != nil {
+		return &bitsb.Fare{}, err
+	}
+	fare, err := strategy.Calculate(busRoute, startIndex, endIndex)
+	if err != nil {
+		return &bitsb.Fare{}, err
+	}
+	fare.Stops = endIndex - startIndex
+
+	if busRoute.MaxPrice > 0 && fare.TicketPrice > busRoute.MaxPrice {
+		fare.Components = append(fare.Components, &bitsb.FareComponent{
+			Description: fmt.Sprintf("capped at the maximum price of %d", busRoute.MaxPrice),
+			Amount:      busRoute.MaxPrice - fare.TicketPrice,
+		})
+		fare.TicketPrice = busRoute.MaxPrice
+	} else if fare.TicketPrice < busRoute.MinPrice {
+		fare.Components = append(fare.Components, &bitsb.FareComponent{
+			Description: fmt.Sprintf("raised to the minimum price of %d", busRoute.MinPrice),
+			Amount:      busRoute.MinPrice - fare.TicketPrice,
+		})
+		fare.TicketPrice = busRoute.MinPrice
+	}
+	return fare, nil
+}
+
+// Validate checks that the route has everything its fare strategy needs
+func Validate(busRoute *bitsb.BusRoute) error {
+	strategy, err := ForRoute(busRoute)
+	if err != nil {
+		return apperrors.NewWithCode(http.StatusBadRequest, "fare.unknown_strategy", err.Error())
+	}
+	if err = strategy.Validate(busRoute); err != nil {
+		return apperrors.NewWithCode(http.StatusBadRequest, "fare.invalid_config", err.Error())
+	}
+	return nil
+}
+
+// newFare returns a fare made up of the given components
+func newFare(strategy string, components ...*bitsb.FareComponent) *bitsb.Fare {
+	fare := &bitsb.Fare{
+		Strategy:   strategy,
+		Components: components,
+	}
+	for _, c := range components {
+		fare.TicketPrice += c.Amount
+	}
+	return fare
+}
@@ -0,0 +1,129 @@
+package fare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+)
+
+func TestCalculate(t *testing.T) {
+	busRoute := &bitsb.BusRoute{
+		ID:               1,
+		LocationIDS:      []int64{1, 2, 3, 4},
+		SegmentDistances: []int64{1200, 800, 2500},
+		MinPrice:         3,
+		MaxPrice:         20,
+	}
+
+	t.Run("when the route has no strategy it is charged per stop", func(t *testing.T) {
+		fare, err := Calculate(busRoute, 1, 3)
+		require.NoError(t, err)
+		require.Equal(t, bitsb.FarePerStop, fare.Strategy)
+		require.Equal(t, int64(6), fare.TicketPrice)
+		require.Equal(t, 2, fare.Stops)
+		require.Len(t, fare.Components, 1)
+	})
+
+	t.Run("when travelling in the opposite direction", func(t *testing.T) {
+		fare, err := Calculate(busRoute, 3, 1)
+		require.NoError(t, err)
+		require.Equal(t, int64(6), fare.TicketPrice)
+	})
+
+	t.Run("when the price is above the maximum price", func(t *testing.T) {
+		r := *busRoute
+		r.MaxPrice = 5
+		fare, err := Calculate(&r, 1, 4)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), fare.TicketPrice)
+		require.Len(t, fare.Components, 2)
+		require.Equal(t, int64(-4), fare.Components[1].Amount)
+	})
+
+	t.Run("when the route has a flat fare", func(t *testing.T) {
+		r := *busRoute
+		r.FareStrategy = bitsb.FareFlat
+		r.FareConfig = bitsb.FareConfig{FlatPrice: 8}
+		fare, err := Calculate(&r, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, int64(8), fare.TicketPrice)
+	})
+
+	t.Run("when the route has zone fares", func(t *testing.T) {
+		r := *busRoute
+		r.FareStrategy = bitsb.FareZone
+		r.FareConfig = bitsb.FareConfig{Zones: []int64{1, 1, 2, 3}, ZonePrices: []int64{5, 9}}
+
+		fare, err := Calculate(&r, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, int64(5), fare.TicketPrice)
+
+		fare, err = Calculate(&r, 2, 3)
+		require.NoError(t, err)
+		require.Equal(t, int64(9), fare.TicketPrice)
+
+		fare, err = Calculate(&r, 1, 4)
+		require.NoError(t, err)
+		require.Equal(t, int64(9), fare.TicketPrice)
+	})
+
+	t.Run("when the route has distance fares", func(t *testing.T) {
+		r := *busRoute
+		r.FareStrategy = bitsb.FareDistance
+		r.FareConfig = bitsb.FareConfig{BasePrice: 2, PricePerKM: 3}
+		fare, err := Calculate(&r, 1, 3)
+		require.NoError(t, err)
+		require.Equal(t, int64(8), fare.TicketPrice)
+		require.Len(t, fare.Components, 2)
+	})
+
+	t.Run("when the locations are not on the route", func(t *testing.T) {
+		_, err := Calculate(busRoute, 1, 9)
+		require.ErrorIs(t, err, apperrors.ErrInvalidLocation)
+
+		_, err = Calculate(busRoute, 2, 2)
+		require.ErrorIs(t, err, apperrors.ErrInvalidLocation)
+	})
+
+	t.Run("when the strategy is unknown", func(t *testing.T) {
+		r := *busRoute
+		r.FareStrategy = "surge"
+		_, err := Calculate(&r, 1, 2)
+		require.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	busRoute := bitsb.BusRoute{LocationIDS: []int64{1, 2, 3}}
+
+	tests := []struct {
+		name     string
+		strategy string
+		config   bitsb.FareConfig
+		valid    bool
+	}{
+		{"per stop", bitsb.FarePerStop, bitsb.FareConfig{}, true},
+		{"flat", bitsb.FareFlat, bitsb.FareConfig{FlatPrice: 5}, true},
+		{"flat without a price", bitsb.FareFlat, bitsb.FareConfig{}, false},
+		{"zone", bitsb.FareZone, bitsb.FareConfig{Zones: []int64{1, 1, 2}, ZonePrices: []int64{5}}, true},
+		{"zone with missing zones", bitsb.FareZone, bitsb.FareConfig{Zones: []int64{1}, ZonePrices: []int64{5}}, false},
+		{"distance without distances", bitsb.FareDistance, bitsb.FareConfig{PricePerKM: 2}, false},
+		{"unknown", "surge", bitsb.FareConfig{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := busRoute
+			r.FareStrategy = tt.strategy
+			r.FareConfig = tt.config
+			err := Validate(&r)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
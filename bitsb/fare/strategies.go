@@ -0,0 +1,106 @@
+package fare
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/utils"
+)
+
+// Flat charges the same price for any journey on the route
+type Flat struct{}
+
+func (Flat) Calculate(busRoute *bitsb.BusRoute, start, end int) (*bitsb.Fare, error) {
+	return newFare(bitsb.FareFlat, &bitsb.FareComponent{
+		Description: "flat price",
+		Amount:      busRoute.FareConfig.FlatPrice,
+	}), nil
+}
+
+func (Flat) Validate(busRoute *bitsb.BusRoute) error {
+	if busRoute.FareConfig.FlatPrice <= 0 {
+		return errors.New("'fare_config.flat_price' is required for flat fares")
+	}
+	return nil
+}
+
+// PerStop charges the minimum price of the route for every stop travelled
+type PerStop struct{}
+
+func (PerStop) Calculate(busRoute *bitsb.BusRoute, start, end int) (*bitsb.Fare, error) {
+	stops := int64(end - start)
+	return newFare(bitsb.FarePerStop, &bitsb.FareComponent{
+		Description: fmt.Sprintf("%d stops at %d per stop", stops, busRoute.MinPrice),
+		Amount:      stops * busRoute.MinPrice,
+	}), nil
+}
+
+func (PerStop) Validate(busRoute *bitsb.BusRoute) error {
+	return nil
+}
+
+// Zone charges by the number of fare zones travelled through
+type Zone struct{}
+
+func (Zone) Calculate(busRoute *bitsb.BusRoute, start, end int) (*bitsb.Fare, error) {
+	config := busRoute.FareConfig
+	if err := (Zone{}).Validate(busRoute); err != nil {
+		return nil, err
+	}
+	zones := utils.Abs(config.Zones[end]-config.Zones[start]) + 1
+	price := config.ZonePrices[utils.Min(int(zones), len(config.ZonePrices))-1]
+	return newFare(bitsb.FareZone, &bitsb.FareComponent{
+		Description: fmt.Sprintf("%d zones", zones),
+		Amount:      price,
+	}), nil
+}
+
+func (Zone) Validate(busRoute *bitsb.BusRoute) error {
+	config := busRoute.FareConfig
+	if len(config.Zones) != len(busRoute.LocationIDS) {
+		return errors.New("'fare_config.zones' should have a zone for every stop")
+	}
+	if len(config.ZonePrices) == 0 {
+		return errors.New("'fare_config.zone_prices' is required for zone fares")
+	}
+	return nil
+}
+
+// Distance charges a base price and a price for every kilometer travelled,
+// it needs the distances between the stops of the route to be known
+type Distance struct{}
+
+func (Distance) Calculate(busRoute *bitsb.BusRoute, start, end int) (*bitsb.Fare, error) {
+	if err := (Distance{}).Validate(busRoute); err != nil {
+		return nil, err
+	}
+	var meters int64
+	for _, d := range busRoute.SegmentDistances[start:end] {
+		meters += d
+	}
+	config := busRoute.FareConfig
+	// round up to the next whole price
+	distancePrice := (meters*config.PricePerKM + 999) / 1000
+	return newFare(
+		bitsb.FareDistance,
+		&bitsb.FareComponent{
+			Description: "base price",
+			Amount:      config.BasePrice,
+		},
+		&bitsb.FareComponent{
+			Description: fmt.Sprintf("%.1f km at %d per km", float64(meters)/1000, config.PricePerKM),
+			Amount:      distancePrice,
+		},
+	), nil
+}
+
+func (Distance) Validate(busRoute *bitsb.BusRoute) error {
+	if len(busRoute.SegmentDistances) != len(busRoute.LocationIDS)-1 {
+		return errors.New("'segment_distances' are required for distance fares")
+	}
+	if busRoute.FareConfig.PricePerKM <= 0 {
+		return errors.New("'fare_config.price_per_km' is required for distance fares")
+	}
+	return nil
+}
@@ -3,6 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,6 +17,30 @@ import (
 	"github.com/sainak/bitsb/pkg/repo"
 )
 
+// busRouteColumns are the columns bus routes can be filtered on
+var busRouteColumns = repo.Columns{
+	"name":          "name",
+	"number":        "number",
+	"interval":      "interval",
+	"min_price":     "min_price",
+	"max_price":     "max_price",
+	"fare_strategy": "fare_strategy",
+	"calendar_id":   "calendar_id",
+}
+
+// busRouteSorts are the columns bus routes can be sorted on
+var busRouteSorts = repo.SortColumns{
+	"created_at": {Column: "created_at", Type: "timestamp"},
+	"name":       {Column: "name", Type: "varchar"},
+	"number":     {Column: "number", Type: "varchar"},
+	"min_price":  {Column: "min_price", Type: "integer"},
+	"max_price":  {Column: "max_price", Type: "integer"},
+	"start_time": {Column: "start_time", Type: "time"},
+	"end_time":   {Column: "end_time", Type: "time"},
+}
+
+var defaultBusRouteSort = repo.Sort{Key: "created_at", Desc: true}
+
 type BusRouteRepository struct {
 	Conn *sql.DB
 }
@@ -21,33 +49,10 @@ func NewBusRouteRepository(conn *sql.DB) bitsb.BusRouteStorer {
 	return &BusRouteRepository{conn}
 }
 
-func (b *BusRouteRepository) SelectAll(
-	ctx context.Context,
-	cursor string,
-	limit int64,
-	locations []int64,
-) ([]*bitsb.BusRoute, string, error) {
-	query := `SELECT id, name, number, start_time, end_time, interval, location_ids, min_price, max_price, created_at, updated_at  FROM bus_routes 
-				WHERE created_at < $1 ORDER BY created_at DESC LIMIT $2;`
-
-	queryWithStops := `SELECT  id, name, number, start_time, end_time, interval, location_ids, min_price, max_price, created_at, updated_at FROM bus_routes
-    						WHERE location_ids @> cast($3 as int[]) AND created_at < $1 ORDER BY created_at DESC LIMIT $2;`
-
-	decodedCursor, err := repo.DecodeCursor(cursor)
-	if err != nil {
-		err = apperrors.ErrBadCursor
-		return []*bitsb.BusRoute{}, "", err
-	}
-
-	var rows *sql.Rows
-	if len(locations) == 0 {
-		rows, err = b.Conn.QueryContext(ctx, query, decodedCursor, limit)
-	} else {
-		rows, err = b.Conn.QueryContext(ctx, queryWithStops, decodedCursor, limit, pq.Array(locations))
-	}
-
+func (b *BusRouteRepository) fetchBusRoutes(ctx context.Context, query string, args ...interface{}) ([]*bitsb.BusRoute, error) {
+	rows, err := repo.Conn(ctx, b.Conn).QueryContext(ctx, query, args...)
 	if err != nil {
-		return []*bitsb.BusRoute{}, "", err
+		return []*bitsb.BusRoute{}, err
 	}
 	defer func(rows *sql.Rows) {
 		err := rows.Close()
@@ -56,7 +61,7 @@ func (b *BusRouteRepository) SelectAll(
 		}
 	}(rows)
 
-	busRoutes := make([]*bitsb.BusRoute, 0, limit)
+	busRoutes := make([]*bitsb.BusRoute, 0)
 	for rows.Next() {
 		busRoute := bitsb.BusRoute{}
 		err = rows.Scan(
@@ -67,28 +72,167 @@ func (b *BusRouteRepository) SelectAll(
 			&busRoute.EndTime,
 			&busRoute.Interval,
 			pq.Array(&busRoute.LocationIDS),
+			&busRoute.CalendarID,
+			pq.Array(&busRoute.SegmentDurations),
+			pq.Array(&busRoute.SegmentDistances),
+			&busRoute.FareStrategy,
+			&busRoute.FareConfig,
 			&busRoute.MinPrice,
 			&busRoute.MaxPrice,
 			&busRoute.CreatedAt,
 			&busRoute.UpdatedAt,
 		)
 		if err != nil {
-			return []*bitsb.BusRoute{}, "", err
+			return []*bitsb.BusRoute{}, err
 		}
 		busRoutes = append(busRoutes, &busRoute)
 	}
+	if err = rows.Err(); err != nil {
+		return []*bitsb.BusRoute{}, err
+	}
+	return busRoutes, nil
+}
 
-	var nextCursor string
-	if len(busRoutes) > 0 {
-		nextCursor = repo.EncodeCursor(busRoutes[len(busRoutes)-1].CreatedAt)
+func (b *BusRouteRepository) SelectAll(
+	ctx context.Context,
+	cursor string,
+	limit int64,
+	filters bitsb.BusRouteFilters,
+	sort repo.Sort,
+) ([]*bitsb.BusRoute, repo.Page, error) {
+	if sort.Key == "" {
+		sort = defaultBusRouteSort
 	}
-	return busRoutes, nextCursor, nil
+	paginator, err := repo.NewPaginator(cursor, limit, sort, busRouteSorts, filters)
+	if err != nil {
+		return []*bitsb.BusRoute{}, repo.Page{}, err
+	}
+
+	conditions, args, err := busRouteConditions(filters, []interface{}{paginator.Limit()})
+	if err != nil {
+		return []*bitsb.BusRoute{}, repo.Page{}, err
+	}
+	after, args := paginator.Condition(args)
+	if after != "" {
+		conditions = append(conditions, after)
+	}
+
+	query := `SELECT id, name, number, start_time, end_time, interval, location_ids, calendar_id, segment_durations, segment_distances, fare_strategy, fare_config, min_price, max_price, created_at, updated_at
+				FROM bus_routes`
+	if len(conditions) > 0 {
+		query += `
+				WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += `
+				ORDER BY ` + paginator.OrderBy() + ` LIMIT $1;`
+
+	busRoutes, err := b.fetchBusRoutes(ctx, query, args...)
+	if err != nil {
+		return []*bitsb.BusRoute{}, repo.Page{}, err
+	}
+
+	busRoutes, page := repo.Paginate(paginator, busRoutes, func(busRoute *bitsb.BusRoute) (string, int64) {
+		return busRouteSortValue(busRoute, sort.Key), busRoute.ID
+	})
+	return busRoutes, page, nil
+}
+
+// busRouteConditions returns the WHERE conditions of the filters,
+// their values are appended to args as positional params
+func busRouteConditions(filters bitsb.BusRouteFilters, args []interface{}) ([]string, []interface{}, error) {
+	conditions := make([]string, 0)
+	if len(filters.Locations) > 0 {
+		args = append(args, pq.Array(filters.Locations))
+		conditions = append(conditions, fmt.Sprintf("location_ids @> cast($%d as int[])", len(args)))
+		if filters.Ordered {
+			// only matches routes that reach the stops in the given order
+			conditions = append(conditions, fmt.Sprintf(`NOT EXISTS (
+				SELECT 1
+				FROM unnest(cast($%[1]d as int[])) WITH ORDINALITY AS a(id, n)
+					JOIN unnest(cast($%[1]d as int[])) WITH ORDINALITY AS b(id, n) ON b.n = a.n + 1
+				WHERE array_position(location_ids, a.id) >= array_position(location_ids, b.id)
+			)`, len(args)))
+		}
+	}
+	if !filters.Date.IsZero() {
+		args = append(args, filters.Date)
+		conditions = append(conditions, fmt.Sprintf(`(calendar_id IS NULL OR EXISTS (
+				SELECT 1
+				FROM service_calendars c
+				WHERE c.id = bus_routes.calendar_id AND (
+					cast($%[1]d as date) = ANY(c.added_dates) OR (
+						NOT cast($%[1]d as date) = ANY(c.removed_dates)
+						AND extract(dow FROM cast($%[1]d as date))::int = ANY(c.weekdays)
+						AND (c.start_date IS NULL OR c.start_date <= cast($%[1]d as date))
+						AND (c.end_date IS NULL OR cast($%[1]d as date) <= c.end_date)
+					)
+				)
+			))`, len(args)))
+	}
+	if filters.Query != "" {
+		args = append(args, "%"+repo.EscapeLike(filters.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE $%[1]d OR number ILIKE $%[1]d)", len(args)))
+	}
+	// routes with any fare in the price range
+	if filters.PriceFrom.Valid {
+		args = append(args, filters.PriceFrom)
+		conditions = append(conditions, fmt.Sprintf("max_price >= $%d", len(args)))
+	}
+	if filters.PriceTo.Valid {
+		args = append(args, filters.PriceTo)
+		conditions = append(conditions, fmt.Sprintf("min_price <= $%d", len(args)))
+	}
+	if filters.RunningAt.Valid {
+		args = append(args, filters.RunningAt.Time.Format("15:04:05"))
+		// routes with an end time before their start time run past midnight
+		conditions = append(conditions, fmt.Sprintf(`(
+				(start_time <= end_time AND cast($%[1]d as time) BETWEEN start_time AND end_time)
+				OR (start_time > end_time AND (cast($%[1]d as time) >= start_time OR cast($%[1]d as time) <= end_time))
+			)`, len(args)))
+	}
+
+	columnConditions, args, err := filters.Filters.Build(busRouteColumns, args)
+	if err != nil {
+		return conditions, args, err
+	}
+	if columnConditions != "" {
+		conditions = append(conditions, columnConditions)
+	}
+	return conditions, args, nil
+}
+
+// busRouteSortValue returns the value of the sort key of the route as it is stored in the cursor
+func busRouteSortValue(busRoute *bitsb.BusRoute, key string) string {
+	switch key {
+	case "name":
+		return busRoute.Name
+	case "number":
+		return busRoute.Number
+	case "min_price":
+		return strconv.FormatInt(busRoute.MinPrice, 10)
+	case "max_price":
+		return strconv.FormatInt(busRoute.MaxPrice, 10)
+	case "start_time":
+		return busRoute.StartTime.Format("15:04:05")
+	case "end_time":
+		return busRoute.EndTime.Format("15:04:05")
+	default:
+		return busRoute.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// SelectByLocations returns every route that stops at any of the given locations
+func (b *BusRouteRepository) SelectByLocations(ctx context.Context, locations []int64) ([]*bitsb.BusRoute, error) {
+	query := `SELECT id, name, number, start_time, end_time, interval, location_ids, calendar_id, segment_durations, segment_distances, fare_strategy, fare_config, min_price, max_price, created_at, updated_at
+				FROM bus_routes
+				WHERE location_ids && cast($1 as int[]) ORDER BY id;`
+	return b.fetchBusRoutes(ctx, query, pq.Array(locations))
 }
 
 func (b *BusRouteRepository) SelectByID(ctx context.Context, id int64) (*bitsb.BusRoute, error) {
-	query := `SELECT id, name, number, start_time, end_time, interval, location_ids, min_price, max_price, created_at, updated_at FROM bus_routes WHERE id=$1;`
+	query := `SELECT id, name, number, start_time, end_time, interval, location_ids, calendar_id, segment_durations, segment_distances, fare_strategy, fare_config, min_price, max_price, created_at, updated_at FROM bus_routes WHERE id=$1;`
 	busRoute := &bitsb.BusRoute{}
-	err := b.Conn.QueryRowContext(ctx, query, id).Scan(
+	err := repo.Conn(ctx, b.Conn).QueryRowContext(ctx, query, id).Scan(
 		&busRoute.ID,
 		&busRoute.Name,
 		&busRoute.Number,
@@ -96,77 +240,123 @@ func (b *BusRouteRepository) SelectByID(ctx context.Context, id int64) (*bitsb.B
 		&busRoute.EndTime,
 		&busRoute.Interval,
 		pq.Array(&busRoute.LocationIDS),
+		&busRoute.CalendarID,
+		pq.Array(&busRoute.SegmentDurations),
+		pq.Array(&busRoute.SegmentDistances),
+		&busRoute.FareStrategy,
+		&busRoute.FareConfig,
 		&busRoute.MinPrice,
 		&busRoute.MaxPrice,
 		&busRoute.CreatedAt,
 		&busRoute.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrRouteNotFound.Wrap(err)
+	}
 	return busRoute, err
 }
 
 func (b *BusRouteRepository) Insert(ctx context.Context, busRoute *bitsb.BusRoute) error {
-	query := `INSERT INTO bus_routes (name, number, start_time, end_time, interval, location_ids, min_price, max_price, created_at, updated_at)
-    	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	query := `INSERT INTO bus_routes (name, number, start_time, end_time, interval, location_ids, calendar_id, segment_durations, segment_distances, fare_strategy, fare_config, min_price, max_price, created_at, updated_at)
+    	VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::integer[], '{}'), COALESCE($9::integer[], '{}'), $10, $11, $12, $13, $14, $15) RETURNING id`
 
 	currentTime := time.Now()
 	busRoute.CreatedAt = currentTime
 	busRoute.UpdatedAt = currentTime
 
-	return b.Conn.QueryRowContext(
-		ctx,
-		query,
-		busRoute.Name,
-		busRoute.Number,
-		busRoute.StartTime,
-		busRoute.EndTime,
-		busRoute.Interval,
-		pq.Array(busRoute.LocationIDS),
-		busRoute.MinPrice,
-		busRoute.MaxPrice,
-		busRoute.CreatedAt,
-		busRoute.UpdatedAt,
-	).Scan(&busRoute.ID)
+	return repo.InTx(ctx, b.Conn, func(ctx context.Context) error {
+		if err := b.lockStops(ctx, busRoute.LocationIDS); err != nil {
+			return err
+		}
+		return repo.Conn(ctx, b.Conn).QueryRowContext(
+			ctx,
+			query,
+			busRoute.Name,
+			busRoute.Number,
+			busRoute.StartTime,
+			busRoute.EndTime,
+			busRoute.Interval,
+			pq.Array(busRoute.LocationIDS),
+			busRoute.CalendarID,
+			pq.Array(busRoute.SegmentDurations),
+			pq.Array(busRoute.SegmentDistances),
+			busRoute.FareStrategy,
+			busRoute.FareConfig,
+			busRoute.MinPrice,
+			busRoute.MaxPrice,
+			busRoute.CreatedAt,
+			busRoute.UpdatedAt,
+		).Scan(&busRoute.ID)
+	})
 }
 
 func (b *BusRouteRepository) Update(ctx context.Context, busRoute *bitsb.BusRoute) error {
 	query := `UPDATE bus_routes 
-				SET name=$2, number=$3, start_time=$4, end_time=$5, interval=$6, location_ids=$7, min_price=$8, max_price=$9, updated_at=$10
+				SET name=$2, number=$3, start_time=$4, end_time=$5, interval=$6, location_ids=$7, calendar_id=$8,
+				    segment_durations=COALESCE($9::integer[], '{}'), segment_distances=COALESCE($10::integer[], '{}'),
+				    fare_strategy=$11, fare_config=$12, min_price=$13, max_price=$14, updated_at=$15
 				WHERE id=$1`
 
 	busRoute.UpdatedAt = time.Now()
 
-	res, err := b.Conn.ExecContext(
-		ctx,
-		query,
-		busRoute.ID,
-		busRoute.Name,
-		busRoute.Number,
-		busRoute.StartTime,
-		busRoute.EndTime,
-		busRoute.Interval,
-		pq.Array(busRoute.LocationIDS),
-		busRoute.MinPrice,
-		busRoute.MaxPrice,
-		busRoute.UpdatedAt,
-	)
-	if err != nil {
+	return repo.InTx(ctx, b.Conn, func(ctx context.Context) error {
+		if err := b.lockStops(ctx, busRoute.LocationIDS); err != nil {
+			return err
+		}
+		res, err := repo.Conn(ctx, b.Conn).ExecContext(
+			ctx,
+			query,
+			busRoute.ID,
+			busRoute.Name,
+			busRoute.Number,
+			busRoute.StartTime,
+			busRoute.EndTime,
+			busRoute.Interval,
+			pq.Array(busRoute.LocationIDS),
+			busRoute.CalendarID,
+			pq.Array(busRoute.SegmentDurations),
+			pq.Array(busRoute.SegmentDistances),
+			busRoute.FareStrategy,
+			busRoute.FareConfig,
+			busRoute.MinPrice,
+			busRoute.MaxPrice,
+			busRoute.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+			err = apperrors.ErrRouteNotFound
+		}
+		return err
+	})
+}
+
+// lockStops locks the locations the route stops at until the transaction ends,
+// so a location cannot be deleted while a route starts stopping at it. It fails
+// with apperrors.ErrInvalidLocation when a stop was deleted in the meantime.
+func (b *BusRouteRepository) lockStops(ctx context.Context, locationIDs []int64) error {
+	query := `SELECT count(*) FROM (SELECT id FROM locations WHERE id = ANY($1) FOR SHARE) AS stops;`
+
+	var count int
+	if err := repo.Conn(ctx, b.Conn).QueryRowContext(ctx, query, pq.Array(locationIDs)).Scan(&count); err != nil {
 		return err
 	}
-	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-		err = apperrors.ErrNotFound
+	if count != len(locationIDs) {
+		return apperrors.ErrInvalidLocation
 	}
-	return err
+	return nil
 }
 
 func (b *BusRouteRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM bus_routes WHERE id=$1`
 
-	res, err := b.Conn.ExecContext(ctx, query, id)
+	res, err := repo.Conn(ctx, b.Conn).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
 	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-		err = apperrors.ErrNotFound
+		err = apperrors.ErrRouteNotFound
 	}
 	return err
 }
@@ -1 +1,223 @@
 package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+)
+
+type BusRouteRepositoryTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+	repo bitsb.BusRouteStorer
+}
+
+func (s *BusRouteRepositoryTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.db = db
+	s.mock = mock
+	s.repo = NewBusRouteRepository(db)
+}
+
+func TestBusRouteRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(BusRouteRepositoryTestSuite))
+}
+
+var busRouteRows = []string{
+	"id",
+	"name",
+	"number",
+	"start_time",
+	"end_time",
+	"interval",
+	"location_ids",
+	"calendar_id",
+	"segment_durations",
+	"segment_distances",
+	"fare_strategy",
+	"fare_config",
+	"min_price",
+	"max_price",
+	"created_at",
+	"updated_at",
+}
+
+func (s *BusRouteRepositoryTestSuite) TestSelectAll() {
+	t := s.T()
+
+	clock := func(value string) time.Time {
+		c, _ := time.Parse("15:04", value)
+		return c
+	}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(busRouteRows).
+			AddRow(1, "Airport Express", "1A", clock("06:00"), clock("22:00"), 15, "{1,2,3}", nil, "{}", "{}", "per_stop", "{}", 5, 25, time.Now(), time.Now()).
+			AddRow(2, "City Loop", "2B", clock("07:00"), clock("01:00"), 30, "{3,4}", nil, "{}", "{}", "flat", `{"flat_price": 10}`, 10, 10, time.Now(), time.Now())
+	}
+
+	t.Run("when the routes are listed in the default order", func(t *testing.T) {
+		s.mock.ExpectQuery(regexp.QuoteMeta("FROM bus_routes\n\t\t\t\tORDER BY created_at DESC, id DESC LIMIT $1;")).
+			WithArgs(int64(11)).
+			WillReturnRows(rows())
+
+		got, page, err := s.repo.SelectAll(context.Background(), "", 10, bitsb.BusRouteFilters{}, repo.Sort{})
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, int64(10), got[1].FareConfig.FlatPrice)
+		require.Equal(t, repo.Page{Limit: 10}, page)
+	})
+
+	t.Run("when there are more routes than the limit", func(t *testing.T) {
+		s.mock.ExpectQuery(regexp.QuoteMeta("ORDER BY created_at DESC, id DESC LIMIT $1;")).
+			WithArgs(int64(2)).
+			WillReturnRows(rows())
+
+		got, page, err := s.repo.SelectAll(context.Background(), "", 1, bitsb.BusRouteFilters{}, repo.Sort{})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.True(t, page.HasMore)
+		require.Empty(t, page.Prev)
+
+		next, err := repo.DecodeCursor(page.Next)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), next.ID)
+		require.Equal(t, "-created_at", next.Sort)
+	})
+
+	filters := bitsb.BusRouteFilters{
+		Query:     "50% off",
+		PriceFrom: null.IntFrom(5),
+		PriceTo:   null.IntFrom(20),
+		RunningAt: null.TimeFrom(clock("08:30")),
+		Filters:   repo.Filters{"fare_strategy:eq": "flat"},
+	}
+
+	t.Run("when the routes are searched and sorted by name", func(t *testing.T) {
+		cursor := &repo.Cursor{Sort: "name", Value: "Airport Express", ID: 1, Filters: repo.Fingerprint(filters)}
+		s.mock.ExpectQuery(regexp.QuoteMeta("(name ILIKE $2 OR number ILIKE $2)")+
+			".+"+regexp.QuoteMeta("max_price >= $3 AND min_price <= $4")+
+			".+"+regexp.QuoteMeta("cast($5 as time) BETWEEN start_time AND end_time")+
+			".+"+regexp.QuoteMeta("AND fare_strategy = $6 AND (name, id) > (cast($7 as varchar), $8)")+
+			".+"+regexp.QuoteMeta("ORDER BY name ASC, id ASC LIMIT $1;")).
+			WithArgs(int64(11), `%50\% off%`, null.IntFrom(5), null.IntFrom(20), "08:30:00", "flat", "Airport Express", int64(1)).
+			WillReturnRows(rows())
+
+		got, page, err := s.repo.SelectAll(context.Background(), cursor.Encode(), 10, filters, repo.ParseSort("name"))
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.False(t, page.HasMore)
+		require.Empty(t, page.Next)
+
+		prev, err := repo.DecodeCursor(page.Prev)
+		require.NoError(t, err)
+		require.True(t, prev.Before)
+		require.Equal(t, int64(1), prev.ID)
+	})
+
+	t.Run("when paging backward", func(t *testing.T) {
+		cursor := &repo.Cursor{Sort: "name", Value: "Metro Link", ID: 3, Before: true, Filters: repo.Fingerprint(bitsb.BusRouteFilters{})}
+		s.mock.ExpectQuery(regexp.QuoteMeta("WHERE (name, id) < (cast($2 as varchar), $3)")+
+			".+"+regexp.QuoteMeta("ORDER BY name DESC, id DESC LIMIT $1;")).
+			WithArgs(int64(2), "Metro Link", int64(3)).
+			WillReturnRows(sqlmock.NewRows(busRouteRows).
+				AddRow(2, "City Loop", "2B", clock("07:00"), clock("01:00"), 30, "{3,4}", nil, "{}", "{}", "flat", `{"flat_price": 10}`, 10, 10, time.Now(), time.Now()).
+				AddRow(1, "Airport Express", "1A", clock("06:00"), clock("22:00"), 15, "{1,2,3}", nil, "{}", "{}", "per_stop", "{}", 5, 25, time.Now(), time.Now()))
+
+		got, page, err := s.repo.SelectAll(context.Background(), cursor.Encode(), 1, bitsb.BusRouteFilters{}, repo.ParseSort("name"))
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, int64(2), got[0].ID)
+		require.True(t, page.HasMore)
+		require.NotEmpty(t, page.Prev)
+		require.NotEmpty(t, page.Next)
+	})
+
+	t.Run("when reading the routes fails midway", func(t *testing.T) {
+		s.mock.ExpectQuery(regexp.QuoteMeta("ORDER BY created_at DESC, id DESC LIMIT $1;")).
+			WithArgs(int64(11)).
+			WillReturnRows(rows().RowError(1, sql.ErrConnDone))
+
+		got, _, err := s.repo.SelectAll(context.Background(), "", 10, bitsb.BusRouteFilters{}, repo.Sort{})
+		require.ErrorIs(t, err, sql.ErrConnDone)
+		require.Empty(t, got)
+	})
+
+	t.Run("when the cursor was made for other filters", func(t *testing.T) {
+		cursor := &repo.Cursor{Sort: "name", Value: "Airport Express", ID: 1, Filters: repo.Fingerprint(bitsb.BusRouteFilters{})}
+		got, _, err := s.repo.SelectAll(context.Background(), cursor.Encode(), 10, filters, repo.ParseSort("name"))
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+		require.Empty(t, got)
+	})
+
+	t.Run("when the cursor is tampered with", func(t *testing.T) {
+		cursor := (&repo.Cursor{Sort: "name", Value: "Airport Express", ID: 1}).Encode()
+		forged := (&repo.Cursor{Sort: "name", Value: "Airport Express", ID: 2}).Encode()
+		payload, _, _ := strings.Cut(forged, ".")
+		_, signature, _ := strings.Cut(cursor, ".")
+
+		got, _, err := s.repo.SelectAll(context.Background(), payload+"."+signature, 10, bitsb.BusRouteFilters{}, repo.ParseSort("name"))
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+		require.Empty(t, got)
+	})
+
+	t.Run("when the sort key is not allowed", func(t *testing.T) {
+		got, _, err := s.repo.SelectAll(context.Background(), "", 10, bitsb.BusRouteFilters{}, repo.ParseSort("-location_ids"))
+		require.Error(t, err)
+		require.Empty(t, got)
+	})
+
+	t.Run("when the cursor is invalid", func(t *testing.T) {
+		got, _, err := s.repo.SelectAll(context.Background(), "invalid", 10, bitsb.BusRouteFilters{}, repo.Sort{})
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+		require.Empty(t, got)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *BusRouteRepositoryTestSuite) TestInsert() {
+	t := s.T()
+
+	lockStops := regexp.QuoteMeta("SELECT count(*) FROM (SELECT id FROM locations WHERE id = ANY($1) FOR SHARE) AS stops;")
+
+	t.Run("when the stops are locked before the route is written", func(t *testing.T) {
+		busRoute := &bitsb.BusRoute{Name: "Airport Express", Number: "1A", LocationIDS: []int64{1, 2, 3}}
+		s.mock.ExpectBegin()
+		s.mock.ExpectQuery(lockStops).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		s.mock.ExpectQuery("INSERT INTO bus_routes").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		s.mock.ExpectCommit()
+
+		require.NoError(t, s.repo.Insert(context.Background(), busRoute))
+		require.Equal(t, int64(1), busRoute.ID)
+	})
+
+	t.Run("when a stop was deleted in the meantime", func(t *testing.T) {
+		busRoute := &bitsb.BusRoute{Name: "Airport Express", Number: "1A", LocationIDS: []int64{1, 2, 3}}
+		s.mock.ExpectBegin()
+		s.mock.ExpectQuery(lockStops).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		s.mock.ExpectRollback()
+
+		require.ErrorIs(t, s.repo.Insert(context.Background(), busRoute), apperrors.ErrInvalidLocation)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -13,6 +15,20 @@ import (
 	"github.com/sainak/bitsb/pkg/repo"
 )
 
+// locationColumns are the columns locations can be filtered on
+var locationColumns = repo.Columns{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+}
+
+// locationSorts are the columns locations can be sorted on
+var locationSorts = repo.SortColumns{
+	"created_at": {Column: "created_at", Type: "timestamptz"},
+}
+
+var defaultLocationSort = repo.Sort{Key: "created_at", Desc: true}
+
 type LocationRepository struct {
 	conn *sql.DB
 }
@@ -26,26 +42,35 @@ func (l LocationRepository) SelectAll(
 	cursor string,
 	limit int64,
 	filters repo.Filters,
-) ([]*bitsb.Location, string, error) {
-	q := filters.BuildQuery()
-	query := `SELECT id, name, created_at, updated_at 
-				FROM locations 
-				WHERE created_at < $1`
-	if q != "" {
-		query += " AND " + q
+) ([]*bitsb.Location, repo.Page, error) {
+	locations := make([]*bitsb.Location, 0, limit)
+	paginator, err := repo.NewPaginator(cursor, limit, defaultLocationSort, locationSorts, filters)
+	if err != nil {
+		return locations, repo.Page{}, err
 	}
-	query += ` ORDER BY created_at DESC  LIMIT $2;`
 
-	locations := make([]*bitsb.Location, 0, limit)
-	decodedCursor, err := repo.DecodeCursor(cursor)
+	conditions := make([]string, 0, 2)
+	filterConditions, args, err := filters.Build(locationColumns, []interface{}{paginator.Limit()})
 	if err != nil {
-		err = apperrors.ErrBadCursor
-		return locations, "", err
+		return locations, repo.Page{}, err
+	}
+	if filterConditions != "" {
+		conditions = append(conditions, filterConditions)
+	}
+	after, args := paginator.Condition(args)
+	if after != "" {
+		conditions = append(conditions, after)
+	}
+	query := `SELECT id, name, latitude, longitude, created_at, updated_at 
+				FROM locations`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
+	query += ` ORDER BY ` + paginator.OrderBy() + ` LIMIT $1;`
 
-	rows, err := l.conn.QueryContext(ctx, query, decodedCursor, limit)
+	rows, err := repo.Conn(ctx, l.conn).QueryContext(ctx, query, args...)
 	if err != nil {
-		return locations, "", err
+		return locations, repo.Page{}, err
 	}
 	defer func(rows *sql.Rows) {
 		if err := rows.Close(); err != nil {
@@ -58,36 +83,44 @@ func (l LocationRepository) SelectAll(
 		err = rows.Scan(
 			&location.ID,
 			&location.Name,
+			&location.Latitude,
+			&location.Longitude,
 			&location.CreatedAt,
 			&location.UpdatedAt,
 		)
 		if err != nil {
-			return locations, "", err
+			return locations, repo.Page{}, err
 		}
 		locations = append(locations, &location)
 	}
-
-	var nextCursor string
-	if len(locations) == int(limit) {
-		nextCursor = repo.EncodeCursor(locations[len(locations)-1].CreatedAt)
+	if err = rows.Err(); err != nil {
+		return locations, repo.Page{}, err
 	}
 
-	return locations, nextCursor, nil
+	locations, page := repo.Paginate(paginator, locations, func(l *bitsb.Location) (string, int64) {
+		return l.CreatedAt.Format(time.RFC3339Nano), l.ID
+	})
+	return locations, page, nil
 }
 
 func (l LocationRepository) SelectByID(ctx context.Context, id int64) (*bitsb.Location, error) {
-	query := `SELECT id, name, created_at, updated_at 
+	query := `SELECT id, name, latitude, longitude, created_at, updated_at 
 				FROM locations 
 				WHERE id = $1;`
 
-	row := l.conn.QueryRowContext(ctx, query, id)
+	row := repo.Conn(ctx, l.conn).QueryRowContext(ctx, query, id)
 	location := &bitsb.Location{}
 	err := row.Scan(
 		&location.ID,
 		&location.Name,
+		&location.Latitude,
+		&location.Longitude,
 		&location.CreatedAt,
 		&location.UpdatedAt,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrLocationNotFound.Wrap(err)
+	}
 	return location, err
 }
 
@@ -97,14 +130,14 @@ func (l LocationRepository) SelectByIDArray(ctx context.Context, ids []int64) ([
 			SELECT id, row_number() OVER (ORDER BY position) AS order_position
 			FROM unnest(cast($1 as integer[])) WITH ORDINALITY AS t(id, position)
 		)
-		SELECT  locations.id, name, created_at, updated_at
+		SELECT  locations.id, name, latitude, longitude, created_at, updated_at
 		FROM locations
 			JOIN location_order
 				ON locations.id = location_order.id
 		ORDER BY location_order.order_position, locations.id
 	`
 	locations := make([]*bitsb.Location, 0, len(ids))
-	rows, err := l.conn.QueryContext(ctx, query, pq.Array(ids))
+	rows, err := repo.Conn(ctx, l.conn).QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
 		return locations, err
 	}
@@ -120,42 +153,106 @@ func (l LocationRepository) SelectByIDArray(ctx context.Context, ids []int64) ([
 		err = rows.Scan(
 			&location.ID,
 			&location.Name,
+			&location.Latitude,
+			&location.Longitude,
+			&location.CreatedAt,
+			&location.UpdatedAt,
+		)
+		if err != nil {
+			return locations, err
+		}
+		locations = append(locations, &location)
+	}
+	return locations, rows.Err()
+}
+
+// SelectNearby returns the locations within radius meters of the given point,
+// nearest first. Distances are calculated with the haversine formula.
+func (l LocationRepository) SelectNearby(
+	ctx context.Context,
+	lat, lng, radius float64,
+	limit int64,
+) ([]*bitsb.NearbyLocation, error) {
+	query := `
+		SELECT id, name, latitude, longitude, created_at, updated_at, distance
+		FROM (
+			SELECT id, name, latitude, longitude, created_at, updated_at,
+				6371000 * 2 * asin(least(1, sqrt(
+					power(sin(radians(latitude - $1) / 2), 2) +
+					cos(radians($1)) * cos(radians(latitude)) * power(sin(radians(longitude - $2) / 2), 2)
+				))) AS distance
+			FROM locations
+			WHERE latitude IS NOT NULL AND longitude IS NOT NULL
+		) AS nearby
+		WHERE distance <= $3
+		ORDER BY distance, id
+		LIMIT $4;`
+
+	locations := make([]*bitsb.NearbyLocation, 0, limit)
+	rows, err := repo.Conn(ctx, l.conn).QueryContext(ctx, query, lat, lng, radius, limit)
+	if err != nil {
+		return locations, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		location := bitsb.NearbyLocation{}
+		err = rows.Scan(
+			&location.ID,
+			&location.Name,
+			&location.Latitude,
+			&location.Longitude,
 			&location.CreatedAt,
 			&location.UpdatedAt,
+			&location.Distance,
 		)
 		if err != nil {
 			return locations, err
 		}
 		locations = append(locations, &location)
 	}
-	return locations, nil
+	return locations, rows.Err()
 }
 
 func (l LocationRepository) Insert(ctx context.Context, location *bitsb.Location) error {
-	query := `INSERT INTO locations (name, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id;`
+	query := `INSERT INTO locations (name, latitude, longitude, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id;`
 
 	currentTime := time.Now()
 	location.CreatedAt = currentTime
 	location.UpdatedAt = currentTime
 
-	return l.conn.QueryRowContext(
+	return repo.Conn(ctx, l.conn).QueryRowContext(
 		ctx,
 		query,
 		location.Name,
+		location.Latitude,
+		location.Longitude,
 		location.CreatedAt,
 		location.UpdatedAt,
 	).Scan(&location.ID)
 }
 
 func (l LocationRepository) Update(ctx context.Context, location *bitsb.Location) error {
-	query := `UPDATE locations SET name = $2, updated_at = $3 WHERE id = $1;`
+	query := `UPDATE locations SET name = $2, latitude = $3, longitude = $4, updated_at = $5 WHERE id = $1;`
 
-	res, err := l.conn.ExecContext(ctx, query, location.ID, location.Name, location.UpdatedAt)
+	res, err := repo.Conn(ctx, l.conn).ExecContext(
+		ctx,
+		query,
+		location.ID,
+		location.Name,
+		location.Latitude,
+		location.Longitude,
+		location.UpdatedAt,
+	)
 	if err != nil {
 		return err
 	}
 	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-		err = apperrors.ErrNotFound
+		err = apperrors.ErrLocationNotFound
 	}
 	return err
 }
@@ -163,12 +260,198 @@ func (l LocationRepository) Update(ctx context.Context, location *bitsb.Location
 func (l LocationRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM locations WHERE id = $1;`
 
-	res, err := l.conn.ExecContext(ctx, query, id)
+	res, err := repo.Conn(ctx, l.conn).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
 	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
-		err = apperrors.ErrNotFound
+		err = apperrors.ErrLocationNotFound
 	}
 	return err
 }
+
+func (l LocationRepository) SelectDependents(ctx context.Context, id int64) (*bitsb.LocationDependents, error) {
+	dependents := &bitsb.LocationDependents{
+		BusRoutes: make([]*bitsb.BusRouteSummary, 0),
+		UserIDs:   make([]int64, 0),
+		TicketIDs: make([]int64, 0),
+	}
+
+	if err := l.lock(ctx, id); err != nil {
+		return dependents, err
+	}
+
+	query := `SELECT id, name, number FROM bus_routes WHERE location_ids @> ARRAY[$1]::integer[] ORDER BY id;`
+	rows, err := repo.Conn(ctx, l.conn).QueryContext(ctx, query, id)
+	if err != nil {
+		return dependents, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+	for rows.Next() {
+		busRoute := &bitsb.BusRouteSummary{}
+		if err = rows.Scan(&busRoute.ID, &busRoute.Name, &busRoute.Number); err != nil {
+			return dependents, err
+		}
+		dependents.BusRoutes = append(dependents.BusRoutes, busRoute)
+	}
+	if err = rows.Err(); err != nil {
+		return dependents, err
+	}
+
+	query = `SELECT id FROM users WHERE home_location_id = $1 OR work_location_id = $1 ORDER BY id;`
+	if dependents.UserIDs, err = l.selectIDs(ctx, query, id); err != nil {
+		return dependents, err
+	}
+
+	query = `SELECT id FROM tickets WHERE from_location_id = $1 OR to_location_id = $1 ORDER BY id;`
+	if dependents.TicketIDs, err = l.selectIDs(ctx, query, id); err != nil {
+		return dependents, err
+	}
+	return dependents, nil
+}
+
+// selectIDs returns the ids selected by the query
+func (l LocationRepository) selectIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := repo.Conn(ctx, l.conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (l LocationRepository) lock(ctx context.Context, id int64) error {
+	query := `SELECT id FROM locations WHERE id = $1 FOR UPDATE;`
+
+	err := repo.Conn(ctx, l.conn).QueryRowContext(ctx, query, id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrLocationNotFound
+	}
+	return err
+}
+
+func (l LocationRepository) DeleteDetached(ctx context.Context, id int64) error {
+	return repo.InTx(ctx, l.conn, func(ctx context.Context) error {
+		if err := l.lock(ctx, id); err != nil {
+			return err
+		}
+		busRoutes, err := l.selectRoutesStoppingAt(ctx, id)
+		if err != nil {
+			return err
+		}
+		// booked tickets are timed and priced on the stops of their route, it is not changed under them
+		query := `SELECT id FROM tickets
+					WHERE from_location_id = $1 OR to_location_id = $1
+						OR (status = 'booked' AND bus_route_id IN (SELECT id FROM bus_routes WHERE location_ids @> ARRAY[$1]::integer[]))
+					ORDER BY id;`
+		ticketIDs, err := l.selectIDs(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		if len(ticketIDs) > 0 {
+			return apperrors.ErrLocationTicketed.WithDetails(ticketIDs)
+		}
+		tooShort := make([]*bitsb.BusRouteSummary, 0)
+		for _, busRoute := range busRoutes {
+			busRoute.RemoveStop(id)
+			if len(busRoute.LocationIDS) < 2 {
+				tooShort = append(tooShort, &bitsb.BusRouteSummary{ID: busRoute.ID, Name: busRoute.Name, Number: busRoute.Number})
+			}
+		}
+		if len(tooShort) > 0 {
+			return apperrors.ErrTooFewStops.WithDetails(tooShort)
+		}
+
+		conn := repo.Conn(ctx, l.conn)
+		query = `UPDATE bus_routes
+					SET location_ids=$2, segment_durations=$3, segment_distances=$4, fare_config=$5, updated_at=$6
+					WHERE id=$1;`
+		for _, busRoute := range busRoutes {
+			busRoute.UpdatedAt = time.Now()
+			_, err = conn.ExecContext(
+				ctx,
+				query,
+				busRoute.ID,
+				pq.Array(busRoute.LocationIDS),
+				pq.Array(busRoute.SegmentDurations),
+				pq.Array(busRoute.SegmentDistances),
+				busRoute.FareConfig,
+				busRoute.UpdatedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		query = `UPDATE users
+					SET home_location_id = NULLIF(home_location_id, $1), work_location_id = NULLIF(work_location_id, $1)
+					WHERE home_location_id = $1 OR work_location_id = $1;`
+		if _, err = conn.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+
+		res, err := conn.ExecContext(ctx, `DELETE FROM locations WHERE id = $1;`, id)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+			return apperrors.ErrLocationNotFound
+		}
+		return nil
+	})
+}
+
+// selectRoutesStoppingAt locks the bus routes stopping at the location for the
+// rest of the transaction, only the columns changed by removing a stop are selected
+func (l LocationRepository) selectRoutesStoppingAt(ctx context.Context, id int64) ([]*bitsb.BusRoute, error) {
+	query := `SELECT id, name, number, location_ids, segment_durations, segment_distances, fare_config
+				FROM bus_routes
+				WHERE location_ids @> ARRAY[$1]::integer[]
+				ORDER BY id
+				FOR UPDATE;`
+	rows, err := repo.Conn(ctx, l.conn).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+
+	busRoutes := make([]*bitsb.BusRoute, 0)
+	for rows.Next() {
+		busRoute := &bitsb.BusRoute{}
+		err = rows.Scan(
+			&busRoute.ID,
+			&busRoute.Name,
+			&busRoute.Number,
+			pq.Array(&busRoute.LocationIDS),
+			pq.Array(&busRoute.SegmentDurations),
+			pq.Array(&busRoute.SegmentDistances),
+			&busRoute.FareConfig,
+		)
+		if err != nil {
+			return nil, err
+		}
+		busRoutes = append(busRoutes, busRoute)
+	}
+	return busRoutes, rows.Err()
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/undefinedlabs/go-mpatch"
 
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/bitsb"
 	"github.com/sainak/bitsb/pkg/repo"
 )
@@ -58,75 +60,120 @@ func (s *LocationRepositoryTestSuite) TestSelectAll() {
 				NewRows([]string{
 					"id",
 					"name",
+					"latitude",
+					"longitude",
 					"created_at",
 					"updated_at",
 				}).
 				AddRow(
 					1,
 					"Test Location",
+					nil,
+					nil,
 					time.Now(),
 					time.Now(),
 				).
 				AddRow(
 					2,
 					"Test Location 2",
+					nil,
+					nil,
 					time.Now(),
 					time.Now(),
 				),
 			)
 
-		got, cursor, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{})
+		got, page, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{})
 		require.NoError(t, err)
 		require.NotEmpty(t, got)
-		require.Equal(t, "", cursor)
+		require.Equal(t, repo.Page{Limit: 10}, page)
 	})
 
 	t.Run("when select all locations is not successful", func(t *testing.T) {
 		s.mock.ExpectQuery("SELECT (.+) FROM locations").
 			WillReturnError(sql.ErrNoRows)
 
-		got, cursor, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{})
+		got, page, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{})
 		require.Error(t, err)
 		require.Empty(t, got)
-		require.Equal(t, "", cursor)
+		require.Equal(t, repo.Page{}, page)
 	})
 
-	t.Run("when select all locations filter is successful", func(t *testing.T) {
+	t.Run("when reading the locations fails midway", func(t *testing.T) {
 		s.mock.ExpectQuery("SELECT (.+) FROM locations").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "latitude", "longitude", "created_at", "updated_at"}).
+				AddRow(1, "Test Location", nil, nil, time.Now(), time.Now()).
+				AddRow(2, "Test Location 2", nil, nil, time.Now(), time.Now()).
+				RowError(1, sql.ErrConnDone))
+
+		_, page, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{})
+		require.ErrorIs(t, err, sql.ErrConnDone)
+		require.Equal(t, repo.Page{}, page)
+	})
+
+	t.Run("when select all locations filter is successful", func(t *testing.T) {
+		s.mock.ExpectQuery(`SELECT (.+) FROM locations WHERE name ILIKE \$2 ORDER BY created_at DESC, id DESC LIMIT \$1`).
+			WithArgs(int64(11), "%Test Location%").
 			WillReturnRows(sqlmock.
 				NewRows([]string{
 					"id",
 					"name",
+					"latitude",
+					"longitude",
 					"created_at",
 					"updated_at",
 				}).
 				AddRow(
 					1,
 					"Test Location",
+					nil,
+					nil,
 					time.Now(),
 					time.Now(),
 				).
 				AddRow(
 					2,
 					"Test Location 2",
+					nil,
+					nil,
 					time.Now(),
 					time.Now(),
 				),
 			)
 
-		got, cursor, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{
+		got, page, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{
 			"name:ilike": "Test Location",
 		})
 		require.NoError(t, err)
 		require.NotEmpty(t, got)
-		require.Equal(t, "", cursor)
+		require.Empty(t, page.Next)
 	})
 
-	t.Run("when select all locations fails for bad cursor", func(t *testing.T) {
-		got, cursor, err := s.repo.SelectAll(context.Background(), "invalid", int64(10), repo.Filters{})
+	t.Run("when the filter value has quotes it is passed as an arg", func(t *testing.T) {
+		s.mock.ExpectQuery(`SELECT (.+) FROM locations WHERE name = \$2`).
+			WithArgs(int64(11), "St. Mary's' OR '1'='1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "latitude", "longitude", "created_at", "updated_at"}))
+
+		got, _, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{
+			"name:eq": "St. Mary's' OR '1'='1",
+		})
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+
+	t.Run("when the filter column is not allowed", func(t *testing.T) {
+		got, _, err := s.repo.SelectAll(context.Background(), "", int64(10), repo.Filters{
+			"1=1; DROP TABLE locations; --:eq": "x",
+		})
 		require.Error(t, err)
 		require.Empty(t, got)
-		require.Equal(t, "", cursor)
+	})
+
+	t.Run("when select all locations fails for bad cursor", func(t *testing.T) {
+		got, page, err := s.repo.SelectAll(context.Background(), "invalid", int64(10), repo.Filters{})
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+		require.Empty(t, got)
+		require.Equal(t, repo.Page{}, page)
 	})
 
 	t.Run("when select all locations returns a valid cursor", func(t *testing.T) {
@@ -135,27 +182,58 @@ func (s *LocationRepositoryTestSuite) TestSelectAll() {
 				NewRows([]string{
 					"id",
 					"name",
+					"latitude",
+					"longitude",
 					"created_at",
 					"updated_at",
 				}).
 				AddRow(
 					1,
 					"Test Location",
+					nil,
+					nil,
 					time.Now(),
 					time.Now(),
 				).
 				AddRow(
 					2,
 					"Test Location 2",
+					nil,
+					nil,
 					time.Now(),
 					time.Now(),
 				),
 			)
 
-		got, cursor, err := s.repo.SelectAll(context.Background(), "", int64(2), repo.Filters{})
+		got, page, err := s.repo.SelectAll(context.Background(), "", int64(1), repo.Filters{})
 		require.NoError(t, err)
-		require.NotEmpty(t, got)
-		require.NotEmpty(t, cursor)
+		require.Len(t, got, 1)
+		require.True(t, page.HasMore)
+
+		next, err := repo.DecodeCursor(page.Next)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), next.ID)
+		require.Equal(t, time.Now().Format(time.RFC3339Nano), next.Value)
+	})
+
+	t.Run("when the next page is selected with the cursor", func(t *testing.T) {
+		cursor := &repo.Cursor{
+			Sort:    "-created_at",
+			Value:   time.Now().Format(time.RFC3339Nano),
+			ID:      1,
+			Filters: repo.Fingerprint(repo.Filters{}),
+		}
+		s.mock.ExpectQuery(`SELECT (.+) FROM locations WHERE \(created_at, id\) < \(cast\(\$2 as timestamptz\), \$3\) ORDER BY created_at DESC, id DESC LIMIT \$1`).
+			WithArgs(int64(2), cursor.Value, int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "latitude", "longitude", "created_at", "updated_at"}).
+				AddRow(2, "Test Location 2", nil, nil, time.Now(), time.Now()))
+
+		got, page, err := s.repo.SelectAll(context.Background(), cursor.Encode(), int64(1), repo.Filters{})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.False(t, page.HasMore)
+		require.Empty(t, page.Next)
+		require.NotEmpty(t, page.Prev)
 	})
 }
 
@@ -174,12 +252,16 @@ func (s *LocationRepositoryTestSuite) TestSelectByID() {
 				NewRows([]string{
 					"id",
 					"name",
+					"latitude",
+					"longitude",
 					"created_at",
 					"updated_at",
 				}).
 				AddRow(
 					location.ID,
 					location.Name,
+					location.Latitude,
+					location.Longitude,
 					location.CreatedAt,
 					location.UpdatedAt,
 				))
@@ -200,11 +282,9 @@ func (s *LocationRepositoryTestSuite) TestSelectByID() {
 	})
 }
 
-func (s *LocationRepositoryTestSuite) SelectByIDArray() {
+func (s *LocationRepositoryTestSuite) TestSelectByIDArray() {
 	t := s.T()
 
-	t.Skip() // TODO: fix test not working
-
 	location := &bitsb.Location{
 		ID:   1,
 		Name: "Test Location",
@@ -217,12 +297,16 @@ func (s *LocationRepositoryTestSuite) SelectByIDArray() {
 				NewRows([]string{
 					"id",
 					"name",
+					"latitude",
+					"longitude",
 					"created_at",
 					"updated_at",
 				}).
 				AddRow(
 					location.ID,
 					location.Name,
+					location.Latitude,
+					location.Longitude,
 					location.CreatedAt,
 					location.UpdatedAt,
 				))
@@ -234,13 +318,93 @@ func (s *LocationRepositoryTestSuite) SelectByIDArray() {
 
 	t.Run("when select by location id array is not successful", func(t *testing.T) {
 		s.mock.ExpectQuery("SELECT (.+) FROM locations").
-			WithArgs([]int64{2}).
-			WillReturnError(sql.ErrNoRows)
+			WithArgs(pq.Array([]int64{2})).
+			WillReturnError(sql.ErrConnDone)
 
 		got, err := s.repo.SelectByIDArray(context.Background(), []int64{2})
+		require.ErrorIs(t, err, sql.ErrConnDone)
+		require.Empty(t, got)
+	})
+
+	t.Run("when reading the locations fails midway", func(t *testing.T) {
+		s.mock.ExpectQuery("WITH").
+			WithArgs(pq.Array([]int64{1, 2})).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "latitude", "longitude", "created_at", "updated_at"}).
+				AddRow(1, "Test Location", nil, nil, time.Now(), time.Now()).
+				AddRow(2, "Test Location 2", nil, nil, time.Now(), time.Now()).
+				RowError(1, sql.ErrConnDone))
+
+		_, err := s.repo.SelectByIDArray(context.Background(), []int64{1, 2})
+		require.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *LocationRepositoryTestSuite) TestSelectNearby() {
+	t := s.T()
+
+	t.Run("when select nearby locations is successful", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM locations").
+			WithArgs(12.97, 77.59, float64(1000), int64(10)).
+			WillReturnRows(sqlmock.
+				NewRows([]string{
+					"id",
+					"name",
+					"latitude",
+					"longitude",
+					"created_at",
+					"updated_at",
+					"distance",
+				}).
+				AddRow(
+					1,
+					"Test Location",
+					12.97,
+					77.59,
+					time.Now(),
+					time.Now(),
+					0,
+				).
+				AddRow(
+					2,
+					"Test Location 2",
+					12.975,
+					77.59,
+					time.Now(),
+					time.Now(),
+					556.6,
+				),
+			)
+
+		got, err := s.repo.SelectNearby(context.Background(), 12.97, 77.59, 1000, int64(10))
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, int64(1), got[0].ID)
+		require.Equal(t, 556.6, got[1].Distance)
+	})
+
+	t.Run("when select nearby locations is not successful", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM locations").
+			WithArgs(12.97, 77.59, float64(1000), int64(10)).
+			WillReturnError(sql.ErrConnDone)
+
+		got, err := s.repo.SelectNearby(context.Background(), 12.97, 77.59, 1000, int64(10))
 		require.Error(t, err)
 		require.Empty(t, got)
 	})
+
+	t.Run("when reading the locations fails midway", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM locations").
+			WithArgs(12.97, 77.59, float64(1000), int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "latitude", "longitude", "created_at", "updated_at", "distance"}).
+				AddRow(1, "Test Location", 12.97, 77.59, time.Now(), time.Now(), 0).
+				AddRow(2, "Test Location 2", 12.975, 77.59, time.Now(), time.Now(), 556.6).
+				RowError(1, sql.ErrConnDone))
+
+		_, err := s.repo.SelectNearby(context.Background(), 12.97, 77.59, 1000, int64(10))
+		require.ErrorIs(t, err, sql.ErrConnDone)
+	})
 }
 
 func (s *LocationRepositoryTestSuite) TestInsert() {
@@ -254,6 +418,8 @@ func (s *LocationRepositoryTestSuite) TestInsert() {
 		s.mock.ExpectQuery("INSERT INTO locations").
 			WithArgs(
 				location.Name,
+				location.Latitude,
+				location.Longitude,
 				time.Now(),
 				time.Now(),
 			).
@@ -289,7 +455,7 @@ func (s *LocationRepositoryTestSuite) TestUpdate() {
 
 	t.Run("when update location is successful", func(t *testing.T) {
 		s.mock.ExpectExec("UPDATE locations").
-			WithArgs(location.ID, location.Name, time.Now()).
+			WithArgs(location.ID, location.Name, location.Latitude, location.Longitude, time.Now()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := s.repo.Update(context.Background(), location)
@@ -298,7 +464,7 @@ func (s *LocationRepositoryTestSuite) TestUpdate() {
 
 	t.Run("when update location is not successful", func(t *testing.T) {
 		s.mock.ExpectExec("UPDATE locations").
-			WithArgs(location.ID, location.Name, time.Now()).
+			WithArgs(location.ID, location.Name, location.Latitude, location.Longitude, time.Now()).
 			WillReturnError(sql.ErrNoRows)
 
 		err := s.repo.Update(context.Background(), location)
@@ -312,7 +478,7 @@ func (s *LocationRepositoryTestSuite) TestUpdate() {
 			UpdatedAt: time.Now(),
 		}
 		s.mock.ExpectExec("UPDATE locations").
-			WithArgs(lc.ID, lc.Name, time.Now()).
+			WithArgs(lc.ID, lc.Name, lc.Latitude, lc.Longitude, time.Now()).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 
 		err := s.repo.Update(context.Background(), &lc)
@@ -350,3 +516,156 @@ func (s *LocationRepositoryTestSuite) TestDelete() {
 		require.Error(t, err)
 	})
 }
+
+func (s *LocationRepositoryTestSuite) TestDeleteDetached() {
+	t := s.T()
+
+	routeRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "number", "location_ids", "segment_durations", "segment_distances", "fare_config"})
+	}
+
+	t.Run("when the location is removed from its dependents", func(t *testing.T) {
+		s.mock.ExpectBegin()
+		s.mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM locations WHERE id = $1 FOR UPDATE;")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		s.mock.ExpectQuery("SELECT (.+) FROM bus_routes (.+) FOR UPDATE").
+			WithArgs(int64(2)).
+			WillReturnRows(routeRows().
+				AddRow(1, "Airport Express", "1A", "{1,2,3}", "{60,120}", "{500,700}", `{"zones": [1, 1, 2]}`))
+		s.mock.ExpectQuery("SELECT id FROM tickets").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		s.mock.ExpectExec("UPDATE bus_routes").
+			WithArgs(int64(1), pq.Array([]int64{1, 3}), pq.Array([]int64{180}), pq.Array([]int64{1200}), bitsb.FareConfig{Zones: []int64{1, 2}}, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectExec("UPDATE users").
+			WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectExec("DELETE FROM locations").
+			WithArgs(int64(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		s.mock.ExpectCommit()
+
+		err := s.repo.DeleteDetached(context.Background(), int64(2))
+		require.NoError(t, err)
+	})
+
+	t.Run("when a route would be left with one stop", func(t *testing.T) {
+		s.mock.ExpectBegin()
+		s.mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM locations WHERE id = $1 FOR UPDATE;")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		s.mock.ExpectQuery("SELECT (.+) FROM bus_routes (.+) FOR UPDATE").
+			WithArgs(int64(2)).
+			WillReturnRows(routeRows().
+				AddRow(3, "Shuttle", "3C", "{2,4}", "{}", "{}", "{}"))
+		s.mock.ExpectQuery("SELECT id FROM tickets").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		s.mock.ExpectRollback()
+
+		err := s.repo.DeleteDetached(context.Background(), int64(2))
+		require.ErrorIs(t, err, apperrors.ErrTooFewStops)
+	})
+
+	t.Run("when tickets are booked on a route stopping there", func(t *testing.T) {
+		s.mock.ExpectBegin()
+		s.mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM locations WHERE id = $1 FOR UPDATE;")).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		s.mock.ExpectQuery("SELECT (.+) FROM bus_routes (.+) FOR UPDATE").
+			WithArgs(int64(2)).
+			WillReturnRows(routeRows().
+				AddRow(1, "Airport Express", "1A", "{1,2,3}", "{60,120}", "{500,700}", "{}"))
+		s.mock.ExpectQuery("SELECT id FROM tickets (.+)status = 'booked' AND bus_route_id").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(9))
+		s.mock.ExpectRollback()
+
+		err := s.repo.DeleteDetached(context.Background(), int64(2))
+		require.ErrorIs(t, err, apperrors.ErrLocationTicketed)
+		require.Equal(t, []int64{4, 9}, apperrors.ParseError(err).Details)
+	})
+
+	t.Run("when the location does not exist", func(t *testing.T) {
+		s.mock.ExpectBegin()
+		s.mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM locations WHERE id = $1 FOR UPDATE;")).
+			WithArgs(int64(9)).
+			WillReturnError(sql.ErrNoRows)
+		s.mock.ExpectRollback()
+
+		err := s.repo.DeleteDetached(context.Background(), int64(9))
+		require.ErrorIs(t, err, apperrors.ErrLocationNotFound)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *LocationRepositoryTestSuite) TestSelectDependents() {
+	t := s.T()
+
+	lock := regexp.QuoteMeta("SELECT id FROM locations WHERE id = $1 FOR UPDATE;")
+
+	t.Run("when the location has dependents", func(t *testing.T) {
+		s.mock.ExpectQuery(lock).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		s.mock.ExpectQuery("SELECT (.+) FROM bus_routes").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}).AddRow(1, "Airport Express", "1A"))
+		s.mock.ExpectQuery("SELECT id FROM users").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		s.mock.ExpectQuery("SELECT id FROM tickets").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+		got, err := s.repo.SelectDependents(context.Background(), 2)
+		require.NoError(t, err)
+		require.Equal(t, []*bitsb.BusRouteSummary{{ID: 1, Name: "Airport Express", Number: "1A"}}, got.BusRoutes)
+		require.Equal(t, []int64{7}, got.UserIDs)
+		require.Equal(t, []int64{3}, got.TicketIDs)
+	})
+
+	t.Run("when reading the routes fails midway", func(t *testing.T) {
+		s.mock.ExpectQuery(lock).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		s.mock.ExpectQuery("SELECT (.+) FROM bus_routes").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}).
+				AddRow(1, "Airport Express", "1A").
+				AddRow(2, "Metro Link", "2B").
+				RowError(1, sql.ErrConnDone))
+
+		_, err := s.repo.SelectDependents(context.Background(), 2)
+		require.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("when reading the users fails midway", func(t *testing.T) {
+		s.mock.ExpectQuery(lock).
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		s.mock.ExpectQuery("SELECT (.+) FROM bus_routes").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}))
+		s.mock.ExpectQuery("SELECT id FROM users").
+			WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(8).RowError(1, sql.ErrConnDone))
+
+		_, err := s.repo.SelectDependents(context.Background(), 2)
+		require.ErrorIs(t, err, sql.ErrConnDone)
+	})
+
+	t.Run("when the location does not exist", func(t *testing.T) {
+		s.mock.ExpectQuery(lock).
+			WithArgs(int64(9)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := s.repo.SelectDependents(context.Background(), 9)
+		require.ErrorIs(t, err, apperrors.ErrLocationNotFound)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
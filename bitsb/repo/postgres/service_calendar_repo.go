@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+)
+
+type ServiceCalendarRepository struct {
+	conn *sql.DB
+}
+
+func NewServiceCalendarRepository(conn *sql.DB) bitsb.ServiceCalendarStorer {
+	return &ServiceCalendarRepository{conn}
+}
+
+func (c ServiceCalendarRepository) SelectAll(ctx context.Context) ([]*bitsb.ServiceCalendar, error) {
+	query := `SELECT id, name, weekdays, start_date, end_date, added_dates, removed_dates, created_at, updated_at
+				FROM service_calendars
+				ORDER BY id;`
+
+	calendars := make([]*bitsb.ServiceCalendar, 0)
+	rows, err := repo.Conn(ctx, c.conn).QueryContext(ctx, query)
+	if err != nil {
+		return calendars, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+
+	for rows.Next() {
+		calendar := bitsb.ServiceCalendar{}
+		err = rows.Scan(
+			&calendar.ID,
+			&calendar.Name,
+			pq.Array(&calendar.Weekdays),
+			&calendar.StartDate,
+			&calendar.EndDate,
+			pq.Array(&calendar.AddedDates),
+			pq.Array(&calendar.RemovedDates),
+			&calendar.CreatedAt,
+			&calendar.UpdatedAt,
+		)
+		if err != nil {
+			return calendars, err
+		}
+		calendars = append(calendars, &calendar)
+	}
+	return calendars, nil
+}
+
+func (c ServiceCalendarRepository) SelectByID(ctx context.Context, id int64) (*bitsb.ServiceCalendar, error) {
+	query := `SELECT id, name, weekdays, start_date, end_date, added_dates, removed_dates, created_at, updated_at
+				FROM service_calendars
+				WHERE id = $1;`
+
+	calendar := &bitsb.ServiceCalendar{}
+	err := repo.Conn(ctx, c.conn).QueryRowContext(ctx, query, id).Scan(
+		&calendar.ID,
+		&calendar.Name,
+		pq.Array(&calendar.Weekdays),
+		&calendar.StartDate,
+		&calendar.EndDate,
+		pq.Array(&calendar.AddedDates),
+		pq.Array(&calendar.RemovedDates),
+		&calendar.CreatedAt,
+		&calendar.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrCalendarNotFound.Wrap(err)
+	}
+	return calendar, err
+}
+
+func (c ServiceCalendarRepository) Insert(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	query := `INSERT INTO service_calendars (name, weekdays, start_date, end_date, added_dates, removed_dates, created_at, updated_at)
+				VALUES ($1, COALESCE($2::integer[], '{}'), $3, $4, COALESCE($5::date[], '{}'), COALESCE($6::date[], '{}'), $7, $8)
+				RETURNING id;`
+
+	currentTime := time.Now()
+	calendar.CreatedAt = currentTime
+	calendar.UpdatedAt = currentTime
+
+	return repo.Conn(ctx, c.conn).QueryRowContext(
+		ctx,
+		query,
+		calendar.Name,
+		pq.Array(calendar.Weekdays),
+		calendar.StartDate,
+		calendar.EndDate,
+		pq.Array(calendar.AddedDates),
+		pq.Array(calendar.RemovedDates),
+		calendar.CreatedAt,
+		calendar.UpdatedAt,
+	).Scan(&calendar.ID)
+}
+
+func (c ServiceCalendarRepository) Update(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	query := `UPDATE service_calendars
+				SET name = $2, weekdays = COALESCE($3::integer[], '{}'), start_date = $4, end_date = $5,
+				    added_dates = COALESCE($6::date[], '{}'), removed_dates = COALESCE($7::date[], '{}'), updated_at = $8
+				WHERE id = $1;`
+
+	calendar.UpdatedAt = time.Now()
+
+	res, err := repo.Conn(ctx, c.conn).ExecContext(
+		ctx,
+		query,
+		calendar.ID,
+		calendar.Name,
+		pq.Array(calendar.Weekdays),
+		calendar.StartDate,
+		calendar.EndDate,
+		pq.Array(calendar.AddedDates),
+		pq.Array(calendar.RemovedDates),
+		calendar.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		err = apperrors.ErrCalendarNotFound
+	}
+	return err
+}
+
+func (c ServiceCalendarRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM service_calendars WHERE id = $1;`
+
+	res, err := repo.Conn(ctx, c.conn).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		err = apperrors.ErrCalendarNotFound
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+)
+
+type ServiceCalendarRepositoryTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+	repo bitsb.ServiceCalendarStorer
+}
+
+func (s *ServiceCalendarRepositoryTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.db = db
+	s.mock = mock
+	s.repo = NewServiceCalendarRepository(db)
+}
+
+func TestServiceCalendarRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(ServiceCalendarRepositoryTestSuite))
+}
+
+var serviceCalendarRows = []string{
+	"id",
+	"name",
+	"weekdays",
+	"start_date",
+	"end_date",
+	"added_dates",
+	"removed_dates",
+	"created_at",
+	"updated_at",
+}
+
+func date(value string) bitsb.Date {
+	d, _ := bitsb.ParseDate(value)
+	return d
+}
+
+func (s *ServiceCalendarRepositoryTestSuite) TestSelectAll() {
+	t := s.T()
+
+	t.Run("when the dates are scanned", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM service_calendars ORDER BY id").
+			WillReturnRows(sqlmock.NewRows(serviceCalendarRows).
+				AddRow(1, "Weekdays", "{1,2,3,4,5}", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), nil,
+					"{2020-01-04}", "{2020-12-25,2021-01-01}", time.Now(), time.Now()).
+				AddRow(2, "Daily", "{0,1,2,3,4,5,6}", nil, "2020-12-31", "{}", "{}", time.Now(), time.Now()))
+
+		got, err := s.repo.SelectAll(context.Background())
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, got[0].Weekdays)
+		require.Equal(t, date("2020-01-01"), got[0].StartDate)
+		require.True(t, got[0].EndDate.IsZero())
+		require.Equal(t, []bitsb.Date{date("2020-01-04")}, got[0].AddedDates)
+		require.Equal(t, []bitsb.Date{date("2020-12-25"), date("2021-01-01")}, got[0].RemovedDates)
+
+		require.True(t, got[1].StartDate.IsZero())
+		require.Equal(t, date("2020-12-31"), got[1].EndDate)
+		require.Empty(t, got[1].AddedDates)
+	})
+
+	t.Run("when a date cannot be scanned", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM service_calendars ORDER BY id").
+			WillReturnRows(sqlmock.NewRows(serviceCalendarRows).
+				AddRow(1, "Weekdays", "{1}", nil, nil, "{not-a-date}", "{}", time.Now(), time.Now()))
+
+		_, err := s.repo.SelectAll(context.Background())
+		require.Error(t, err)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *ServiceCalendarRepositoryTestSuite) TestSelectByID() {
+	t := s.T()
+
+	t.Run("when the calendar does not exist", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM service_calendars WHERE id = \\$1").
+			WithArgs(int64(404)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := s.repo.SelectByID(context.Background(), 404)
+		require.ErrorIs(t, err, apperrors.ErrCalendarNotFound)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *ServiceCalendarRepositoryTestSuite) TestInsert() {
+	t := s.T()
+
+	t.Run("when the dates are written", func(t *testing.T) {
+		calendar := &bitsb.ServiceCalendar{
+			Name:         "Weekdays",
+			Weekdays:     []int64{1, 2, 3, 4, 5},
+			StartDate:    date("2020-01-01"),
+			AddedDates:   []bitsb.Date{date("2020-01-04")},
+			RemovedDates: []bitsb.Date{date("2020-12-25"), date("2021-01-01")},
+		}
+		s.mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO service_calendars")).
+			WithArgs(
+				"Weekdays",
+				"{1,2,3,4,5}",
+				"2020-01-01",
+				nil,
+				`{"2020-01-04"}`,
+				`{"2020-12-25","2021-01-01"}`,
+				sqlmock.AnyArg(),
+				sqlmock.AnyArg(),
+			).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		require.NoError(t, s.repo.Insert(context.Background(), calendar))
+		require.Equal(t, int64(1), calendar.ID)
+	})
+
+	t.Run("when there are no dates", func(t *testing.T) {
+		calendar := &bitsb.ServiceCalendar{Name: "Daily", Weekdays: []int64{0, 1, 2, 3, 4, 5, 6}}
+		s.mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO service_calendars")).
+			WithArgs("Daily", "{0,1,2,3,4,5,6}", nil, nil, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+		require.NoError(t, s.repo.Insert(context.Background(), calendar))
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *ServiceCalendarRepositoryTestSuite) TestUpdate() {
+	t := s.T()
+
+	t.Run("when the calendar does not exist", func(t *testing.T) {
+		calendar := &bitsb.ServiceCalendar{ID: 404, Name: "Missing", RemovedDates: []bitsb.Date{date("2020-12-25")}}
+		s.mock.ExpectExec("UPDATE service_calendars").
+			WithArgs(int64(404), "Missing", nil, nil, nil, nil, `{"2020-12-25"}`, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.ErrorIs(t, s.repo.Update(context.Background(), calendar), apperrors.ErrCalendarNotFound)
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
+
+func (s *ServiceCalendarRepositoryTestSuite) TestDelete() {
+	t := s.T()
+
+	t.Run("when the calendar is deleted", func(t *testing.T) {
+		s.mock.ExpectExec("DELETE FROM service_calendars").
+			WithArgs(int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, s.repo.Delete(context.Background(), 1))
+	})
+
+	require.NoError(t, s.mock.ExpectationsWereMet())
+}
@@ -2,21 +2,32 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/bitsb/fare"
+	"github.com/sainak/bitsb/pkg/repo"
 	"github.com/sainak/bitsb/pkg/utils"
 )
 
 type BusRouteService struct {
 	repo         bitsb.BusRouteStorer
 	locationRepo bitsb.LocationStorer
+	calendarRepo bitsb.ServiceCalendarStorer
 }
 
-func NewBusRouteService(r bitsb.BusRouteStorer, l bitsb.LocationStorer) bitsb.BusRouteServiceProvider {
+func NewBusRouteService(
+	r bitsb.BusRouteStorer,
+	l bitsb.LocationStorer,
+	c bitsb.ServiceCalendarStorer,
+) bitsb.BusRouteServiceProvider {
 	return &BusRouteService{
 		repo:         r,
 		locationRepo: l,
+		calendarRepo: c,
 	}
 }
 
@@ -24,9 +35,21 @@ func (b *BusRouteService) ListAll(
 	ctx context.Context,
 	cursor string,
 	limit int64,
-	locations []int64,
-) ([]*bitsb.BusRoute, string, error) {
-	return b.repo.SelectAll(ctx, cursor, limit, locations)
+	filters bitsb.BusRouteFilters,
+	order repo.Sort,
+) ([]*bitsb.BusRoute, repo.Page, error) {
+	busRoutes, page, err := b.repo.SelectAll(ctx, cursor, limit, filters, order)
+	locations := filters.Locations
+	if err != nil || !filters.Ordered || len(locations) < 2 {
+		return busRoutes, page, err
+	}
+	for _, busRoute := range busRoutes {
+		boarding := utils.IndexOf(busRoute.LocationIDS, locations[0])
+		alighting := utils.IndexOf(busRoute.LocationIDS, locations[len(locations)-1])
+		busRoute.BoardingIndex = &boarding
+		busRoute.AlightingIndex = &alighting
+	}
+	return busRoutes, page, nil
 }
 
 func (b *BusRouteService) GetByID(ctx context.Context, id int64) (*bitsb.BusRoute, error) {
@@ -39,36 +62,248 @@ func (b *BusRouteService) GetByID(ctx context.Context, id int64) (*bitsb.BusRout
 		return &bitsb.BusRoute{}, err
 	}
 	for _, l := range locations {
-		loc := &bitsb.LocationForm{Name: l.Name}
+		loc := &bitsb.LocationForm{Name: l.Name, Latitude: l.Latitude, Longitude: l.Longitude}
 		busRoute.Locations = append(busRoute.Locations, loc)
 	}
+	busRoute.Segments = busRoute.BuildSegments()
 	return busRoute, err
 }
 
-func (b *BusRouteService) CalculateTicketPrice(ctx context.Context, id, start, end int64) (int64, error) {
+func (b *BusRouteService) CalculateTicketPrice(ctx context.Context, id, start, end int64) (*bitsb.Fare, error) {
+	busRoute, err := b.repo.SelectByID(ctx, id)
+	if err != nil {
+		return &bitsb.Fare{}, err
+	}
+	return fare.Calculate(busRoute, start, end)
+}
+
+// Timetable lists every trip of the route, when date is set
+// it is empty if the route does not run on that day
+func (b *BusRouteService) Timetable(ctx context.Context, id int64, date bitsb.Date) (*bitsb.Timetable, error) {
 	busRoute, err := b.repo.SelectByID(ctx, id)
 	if err != nil {
-		return 0, err
+		return &bitsb.Timetable{}, err
 	}
-	startIndex := utils.IndexOf(busRoute.LocationIDS, start)
-	endIndex := utils.IndexOf(busRoute.LocationIDS, end)
-	distance := utils.Abs(endIndex - startIndex)
-	if startIndex == -1 || endIndex == -1 || distance == 0 {
-		return 0, apperrors.ErrInvalidLocation
+	runs := true
+	if !date.IsZero() {
+		runs, err = b.runsOn(ctx, busRoute, date, nil)
+		if err != nil {
+			return &bitsb.Timetable{}, err
+		}
+	}
+	locations, err := b.locationRepo.SelectByIDArray(ctx, busRoute.LocationIDS)
+	if err != nil {
+		return &bitsb.Timetable{}, err
+	}
+	names := make(map[int64]string, len(locations))
+	for _, l := range locations {
+		names[l.ID] = l.Name
 	}
 
-	price := utils.Min(int64(distance)*busRoute.MinPrice, busRoute.MaxPrice)
-	return price, err
+	offsets := busRoute.StopOffsets()
+	departures := busRoute.DepartureTimes()
+	if !runs {
+		departures = departures[:0]
+	}
+	timetable := &bitsb.Timetable{
+		BusRouteID: busRoute.ID,
+		Name:       busRoute.Name,
+		Number:     busRoute.Number,
+		Date:       date,
+		Trips:      make([]*bitsb.Trip, 0, len(departures)),
+	}
+	for _, departure := range departures {
+		trip := &bitsb.Trip{
+			Departure: departure,
+			Stops:     make([]*bitsb.StopTime, 0, len(busRoute.LocationIDS)),
+		}
+		for i, locationID := range busRoute.LocationIDS {
+			trip.Stops = append(trip.Stops, &bitsb.StopTime{
+				LocationID: locationID,
+				Name:       names[locationID],
+				Time:       departure.Add(offsets[i]),
+			})
+		}
+		timetable.Trips = append(timetable.Trips, trip)
+	}
+	return timetable, nil
+}
+
+// Departures returns the next buses leaving the location on the given date at or after the given time
+// across every route that stops there, routes ending at the location are left out. The trips of the
+// day before that run past midnight are included, times past midnight are on the day after the date.
+func (b *BusRouteService) Departures(
+	ctx context.Context,
+	locationID int64,
+	date bitsb.Date,
+	after time.Time,
+	limit int64,
+) ([]*bitsb.Departure, error) {
+	busRoutes, err := b.repo.SelectByLocations(ctx, []int64{locationID})
+	if err != nil {
+		return []*bitsb.Departure{}, err
+	}
+
+	departures := make([]*bitsb.Departure, 0)
+	destinations := make([]int64, 0, len(busRoutes))
+	calendars := make(map[int64]*bitsb.ServiceCalendar)
+	for _, busRoute := range busRoutes {
+		index := utils.IndexOf(busRoute.LocationIDS, locationID)
+		if index == -1 || index == len(busRoute.LocationIDS)-1 {
+			continue
+		}
+		offset := busRoute.StopOffsets()[index]
+		times := make([]time.Time, 0)
+		// the trips of the day before running past midnight leave on the date too
+		for days := -1; days <= 0; days++ {
+			runs, err := b.runsOn(ctx, busRoute, bitsb.Date{Time: date.AddDate(0, 0, days)}, calendars)
+			if err != nil {
+				return []*bitsb.Departure{}, err
+			}
+			if !runs {
+				continue
+			}
+			for _, departure := range busRoute.DepartureTimes() {
+				if t := departure.Add(offset).AddDate(0, 0, days); !t.Before(after) {
+					times = append(times, t)
+				}
+			}
+		}
+		if len(times) == 0 {
+			continue
+		}
+		destination := busRoute.LocationIDS[len(busRoute.LocationIDS)-1]
+		destinations = append(destinations, destination)
+		for _, t := range times {
+			departures = append(departures, &bitsb.Departure{
+				BusRouteID:            busRoute.ID,
+				Number:                busRoute.Number,
+				Name:                  busRoute.Name,
+				DestinationLocationID: destination,
+				Time:                  t,
+			})
+		}
+	}
+	sort.SliceStable(departures, func(i, j int) bool {
+		return departures[i].Time.Before(departures[j].Time)
+	})
+	if int64(len(departures)) > limit {
+		departures = departures[:limit]
+	}
+	if len(departures) == 0 {
+		return departures, nil
+	}
+
+	locations, err := b.locationRepo.SelectByIDArray(ctx, destinations)
+	if err != nil {
+		return []*bitsb.Departure{}, err
+	}
+	names := make(map[int64]string, len(locations))
+	for _, l := range locations {
+		names[l.ID] = l.Name
+	}
+	for _, departure := range departures {
+		departure.Direction = names[departure.DestinationLocationID]
+	}
+	return departures, nil
+}
+
+// runsOn reports whether the route operates on the given date, routes without
+// a service calendar run every day. Calendars are cached in the given map when it is not nil.
+func (b *BusRouteService) runsOn(
+	ctx context.Context,
+	busRoute *bitsb.BusRoute,
+	date bitsb.Date,
+	calendars map[int64]*bitsb.ServiceCalendar,
+) (bool, error) {
+	if !busRoute.CalendarID.Valid {
+		return true, nil
+	}
+	calendar, ok := calendars[busRoute.CalendarID.Int64]
+	if !ok {
+		var err error
+		calendar, err = b.calendarRepo.SelectByID(ctx, busRoute.CalendarID.Int64)
+		if err != nil {
+			return false, err
+		}
+		if calendars != nil {
+			calendars[busRoute.CalendarID.Int64] = calendar
+		}
+	}
+	return calendar.RunsOn(date), nil
 }
 
 func (b *BusRouteService) Create(ctx context.Context, busRoute *bitsb.BusRoute) error {
+	if busRoute.FareStrategy == "" {
+		busRoute.FareStrategy = bitsb.FarePerStop
+	}
+	if err := b.validate(ctx, busRoute); err != nil {
+		return err
+	}
+	if err := fare.Validate(busRoute); err != nil {
+		return err
+	}
 	return b.repo.Insert(ctx, busRoute)
 }
 
 func (b *BusRouteService) Update(ctx context.Context, busRoute *bitsb.BusRoute) error {
+	if busRoute.FareStrategy == "" {
+		busRoute.FareStrategy = bitsb.FarePerStop
+	}
+	if err := b.validate(ctx, busRoute); err != nil {
+		return err
+	}
+	if err := fare.Validate(busRoute); err != nil {
+		return err
+	}
 	return b.repo.Update(ctx, busRoute)
 }
 
+// validate checks that the route can be run, every stop has to be an existing
+// location that is visited once. An EndTime before StartTime is a route
+// running past midnight, so only a route starting when it ends is rejected.
+func (b *BusRouteService) validate(ctx context.Context, busRoute *bitsb.BusRoute) error {
+	errs := &apperrors.ValidationError{}
+	if busRoute.Interval <= 0 {
+		errs.Add("interval", "min", "'interval' should be at least 1")
+	}
+	if busRoute.EndTime.Equal(busRoute.StartTime) {
+		errs.Add("end_time", "ne", "'end_time' should not be the same as 'start_time'")
+	}
+	if busRoute.MinPrice < 0 {
+		errs.Add("min_price", "min", "'min_price' should be at least 0")
+	}
+	if busRoute.MaxPrice > 0 && busRoute.MinPrice > busRoute.MaxPrice {
+		errs.Add("min_price", "lte", "'min_price' should not be more than 'max_price'")
+	}
+	if len(busRoute.LocationIDS) < 2 {
+		errs.Add("location_ids", "min", "'location_ids' should have at least 2 items")
+		return errs.Err()
+	}
+
+	stops := make(map[int64]bool, len(busRoute.LocationIDS))
+	for _, id := range busRoute.LocationIDS {
+		if stops[id] {
+			errs.Add("location_ids", "duplicate", fmt.Sprintf("location %d is a stop more than once", id))
+		}
+		stops[id] = true
+	}
+	locations, err := b.locationRepo.SelectByIDArray(ctx, busRoute.LocationIDS)
+	if err != nil {
+		return err
+	}
+	for _, l := range locations {
+		delete(stops, l.ID)
+	}
+	for _, id := range busRoute.LocationIDS {
+		if _, missing := stops[id]; missing {
+			errs.Add("location_ids", "not_found", fmt.Sprintf("location %d does not exist", id))
+			delete(stops, id)
+		}
+	}
+	return errs.Err()
+}
+
 func (b *BusRouteService) Delete(ctx context.Context, id int64) error {
 	return b.repo.Delete(ctx, id)
 }
@@ -10,9 +10,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/undefinedlabs/go-mpatch"
+	"gopkg.in/guregu/null.v4"
 
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/bitsb"
 	mocks2 "github.com/sainak/bitsb/mocks"
+	"github.com/sainak/bitsb/pkg/repo"
 )
 
 type BusRouteServiceTestSuite struct {
@@ -20,6 +23,7 @@ type BusRouteServiceTestSuite struct {
 	service      bitsb.BusRouteServiceProvider
 	repo         *mocks2.BusRouteStorer
 	locationRepo *mocks2.LocationStorer
+	calendarRepo *mocks2.ServiceCalendarStorer
 }
 
 func TestBusRouteServiceTestSuite(t *testing.T) {
@@ -41,7 +45,8 @@ func TestBusRouteServiceTestSuite(t *testing.T) {
 func (s *BusRouteServiceTestSuite) SetupTest() {
 	s.repo = mocks2.NewBusRouteStorer(s.T())
 	s.locationRepo = mocks2.NewLocationStorer(s.T())
-	s.service = NewBusRouteService(s.repo, s.locationRepo)
+	s.calendarRepo = mocks2.NewServiceCalendarStorer(s.T())
+	s.service = NewBusRouteService(s.repo, s.locationRepo, s.calendarRepo)
 }
 
 func (s *BusRouteServiceTestSuite) TestListAll() {
@@ -60,24 +65,40 @@ func (s *BusRouteServiceTestSuite) TestListAll() {
 
 	t.Run("when list all routes is successful", func(t *testing.T) {
 		s.repo.
-			On("SelectAll", mock.Anything, "", int64(10), []int64{}).
-			Return(busRoutes, "", nil)
+			On("SelectAll", mock.Anything, "", int64(10), bitsb.BusRouteFilters{}, repo.Sort{}).
+			Return(busRoutes, repo.Page{}, nil)
 
-		routes, cursor, err := s.service.ListAll(context.Background(), "", int64(10), []int64{})
+		routes, page, err := s.service.ListAll(context.Background(), "", int64(10), bitsb.BusRouteFilters{}, repo.Sort{})
 		require.NoError(t, err)
 		require.Equal(t, busRoutes, routes)
-		require.Equal(t, "", cursor)
+		require.Equal(t, repo.Page{}, page)
 	})
 
 	t.Run("when list all routes is unsuccessful", func(t *testing.T) {
 		s.repo.
-			On("SelectAll", mock.Anything, "awd342", int64(10), []int64{}).
-			Return([]*bitsb.BusRoute{}, "", fmt.Errorf("error"))
+			On("SelectAll", mock.Anything, "awd342", int64(10), bitsb.BusRouteFilters{}, repo.Sort{}).
+			Return([]*bitsb.BusRoute{}, repo.Page{}, fmt.Errorf("error"))
 
-		routes, cursor, err := s.service.ListAll(context.Background(), "awd342", int64(10), []int64{})
+		routes, page, err := s.service.ListAll(context.Background(), "awd342", int64(10), bitsb.BusRouteFilters{}, repo.Sort{})
 		require.Error(t, err)
 		require.Empty(t, routes)
-		require.Equal(t, "", cursor)
+		require.Equal(t, repo.Page{}, page)
+	})
+
+	t.Run("when list all routes is in the order of the given locations", func(t *testing.T) {
+		filters := bitsb.BusRouteFilters{Locations: []int64{1, 2}, Ordered: true}
+		orderedRoutes := []*bitsb.BusRoute{
+			{ID: 3, Name: "Test Route 3", LocationIDS: []int64{4, 1, 6, 2}},
+		}
+		s.repo.
+			On("SelectAll", mock.Anything, "", int64(10), filters, repo.Sort{}).
+			Return(orderedRoutes, repo.Page{}, nil)
+
+		routes, _, err := s.service.ListAll(context.Background(), "", int64(10), filters, repo.Sort{})
+		require.NoError(t, err)
+		require.Len(t, routes, 1)
+		require.Equal(t, 1, *routes[0].BoardingIndex)
+		require.Equal(t, 3, *routes[0].AlightingIndex)
 	})
 }
 
@@ -85,11 +106,12 @@ func (s *BusRouteServiceTestSuite) TestGetByID() {
 	t := s.T()
 
 	busRoute := &bitsb.BusRoute{
-		ID:          1,
-		Name:        "Test Route 1",
-		MinPrice:    3,
-		MaxPrice:    10,
-		LocationIDS: []int64{1, 2},
+		ID:               1,
+		Name:             "Test Route 1",
+		MinPrice:         3,
+		MaxPrice:         10,
+		LocationIDS:      []int64{1, 2},
+		SegmentDurations: []int64{120},
 	}
 
 	locationDetails := []*bitsb.Location{
@@ -98,17 +120,23 @@ func (s *BusRouteServiceTestSuite) TestGetByID() {
 	}
 
 	locations := []*bitsb.LocationForm{
-		{"location 1"},
-		{"location 2"},
+		{Name: "location 1"},
+		{Name: "location 2"},
+	}
+
+	segments := []*bitsb.Segment{
+		{FromLocationID: 1, ToLocationID: 2, Duration: null.IntFrom(120)},
 	}
 
 	routeWithLoc := &bitsb.BusRoute{
-		ID:          1,
-		Name:        "Test Route 1",
-		MinPrice:    3,
-		MaxPrice:    10,
-		LocationIDS: []int64{1, 2},
-		Locations:   locations,
+		ID:               1,
+		Name:             "Test Route 1",
+		MinPrice:         3,
+		MaxPrice:         10,
+		LocationIDS:      []int64{1, 2},
+		SegmentDurations: []int64{120},
+		Locations:        locations,
+		Segments:         segments,
 	}
 
 	t.Run("when get route by id is successful", func(t *testing.T) {
@@ -175,7 +203,7 @@ func (s *BusRouteServiceTestSuite) TestCalculateTicketPrice() {
 
 		price, err := s.service.CalculateTicketPrice(context.Background(), busRoute.ID, start, end)
 		require.NoError(t, err)
-		require.Equal(t, int64(10), price)
+		require.Equal(t, int64(10), price.TicketPrice)
 	})
 
 	t.Run("when calculate ticket price is successful for 1 stop", func(t *testing.T) {
@@ -186,7 +214,7 @@ func (s *BusRouteServiceTestSuite) TestCalculateTicketPrice() {
 
 		price, err := s.service.CalculateTicketPrice(context.Background(), busRoute.ID, start, end)
 		require.NoError(t, err)
-		require.Equal(t, int64(3), price)
+		require.Equal(t, int64(3), price.TicketPrice)
 	})
 
 	t.Run("claculete ticket price for invalid route", func(t *testing.T) {
@@ -197,7 +225,7 @@ func (s *BusRouteServiceTestSuite) TestCalculateTicketPrice() {
 
 		price, err := s.service.CalculateTicketPrice(context.Background(), int64(3), start, end)
 		require.Error(t, err)
-		require.Equal(t, int64(0), price)
+		require.Equal(t, int64(0), price.TicketPrice)
 	})
 
 	t.Run("claculete ticket price for invalid start location", func(t *testing.T) {
@@ -205,36 +233,324 @@ func (s *BusRouteServiceTestSuite) TestCalculateTicketPrice() {
 
 		price, err := s.service.CalculateTicketPrice(context.Background(), busRoute.ID, start, end)
 		require.Error(t, err)
-		require.Equal(t, int64(0), price)
+		require.Equal(t, int64(0), price.TicketPrice)
 	})
 }
 
-func (s *BusRouteServiceTestSuite) TestCreate() {
+func (s *BusRouteServiceTestSuite) TestTimetable() {
 	t := s.T()
 
+	startTime, _ := time.Parse("15:04", "08:00")
+	endTime, _ := time.Parse("15:04", "09:00")
 	busRoute := &bitsb.BusRoute{
+		ID:               5,
+		Name:             "Test Route 5",
+		Number:           "5A",
+		StartTime:        startTime,
+		EndTime:          endTime,
+		Interval:         30,
+		LocationIDS:      []int64{1, 2, 3},
+		SegmentDurations: []int64{240, 180},
+	}
+
+	locationDetails := []*bitsb.Location{
+		{ID: 1, Name: "location 1"},
+		{ID: 2, Name: "location 2"},
+		{ID: 3, Name: "location 3"},
+	}
+
+	t.Run("when timetable is successfully generated", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, busRoute.ID).
+			Return(busRoute, nil).
+			Once()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(locationDetails, nil).
+			Once()
+
+		timetable, err := s.service.Timetable(context.Background(), busRoute.ID, bitsb.Date{})
+		require.NoError(t, err)
+		require.Equal(t, busRoute.ID, timetable.BusRouteID)
+		require.Len(t, timetable.Trips, 3)
+
+		lastTrip := timetable.Trips[2]
+		require.Equal(t, "09:00", lastTrip.Departure.Format("15:04"))
+		require.Len(t, lastTrip.Stops, 3)
+		require.Equal(t, "location 3", lastTrip.Stops[2].Name)
+		require.Equal(t, "09:04", lastTrip.Stops[1].Time.Format("15:04"))
+		require.Equal(t, "09:07", lastTrip.Stops[2].Time.Format("15:04"))
+	})
+
+	t.Run("when timetable is generated for a route running past midnight", func(t *testing.T) {
+		lateStart, _ := time.Parse("15:04", "23:00")
+		lateEnd, _ := time.Parse("15:04", "01:00")
+		lateRoute := &bitsb.BusRoute{
+			ID:          6,
+			StartTime:   lateStart,
+			EndTime:     lateEnd,
+			Interval:    60,
+			LocationIDS: []int64{1, 2},
+		}
+		s.repo.
+			On("SelectByID", mock.Anything, lateRoute.ID).
+			Return(lateRoute, nil).
+			Once()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, lateRoute.LocationIDS).
+			Return(locationDetails[:2], nil).
+			Once()
+
+		timetable, err := s.service.Timetable(context.Background(), lateRoute.ID, bitsb.Date{})
+		require.NoError(t, err)
+		require.Len(t, timetable.Trips, 3)
+		require.Equal(t, "01:00", timetable.Trips[2].Departure.Format("15:04"))
+	})
+
+	t.Run("when timetable is for a day the route does not run", func(t *testing.T) {
+		weekendRoute := &bitsb.BusRoute{
+			ID:          10,
+			StartTime:   startTime,
+			EndTime:     endTime,
+			Interval:    30,
+			LocationIDS: []int64{1, 2},
+			CalendarID:  null.IntFrom(1),
+		}
+		monday, _ := bitsb.ParseDate("2020-11-02")
+		holiday, _ := bitsb.ParseDate("2020-11-14")
+		calendar := &bitsb.ServiceCalendar{
+			ID:           1,
+			Weekdays:     []int64{0, 6},
+			RemovedDates: []bitsb.Date{holiday},
+		}
+		s.repo.
+			On("SelectByID", mock.Anything, weekendRoute.ID).
+			Return(weekendRoute, nil).
+			Twice()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, weekendRoute.LocationIDS).
+			Return(locationDetails[:2], nil).
+			Twice()
+		s.calendarRepo.
+			On("SelectByID", mock.Anything, int64(1)).
+			Return(calendar, nil).
+			Twice()
+
+		timetable, err := s.service.Timetable(context.Background(), weekendRoute.ID, monday)
+		require.NoError(t, err)
+		require.Equal(t, monday, timetable.Date)
+		require.Empty(t, timetable.Trips)
+
+		timetable, err = s.service.Timetable(context.Background(), weekendRoute.ID, holiday)
+		require.NoError(t, err)
+		require.Empty(t, timetable.Trips)
+	})
+
+	t.Run("when timetable route is not found", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(7)).
+			Return(nil, fmt.Errorf("error")).
+			Once()
+
+		timetable, err := s.service.Timetable(context.Background(), int64(7), bitsb.Date{})
+		require.Error(t, err)
+		require.Empty(t, timetable)
+	})
+}
+
+func (s *BusRouteServiceTestSuite) TestDepartures() {
+	t := s.T()
+
+	startTime, _ := time.Parse("15:04", "08:00")
+	endTime, _ := time.Parse("15:04", "09:00")
+	busRoutes := []*bitsb.BusRoute{
+		{
+			ID:               8,
+			Number:           "8A",
+			StartTime:        startTime,
+			EndTime:          endTime,
+			Interval:         20,
+			LocationIDS:      []int64{1, 2, 3},
+			SegmentDurations: []int64{600, 600},
+		},
+		{
+			ID:          9,
+			Number:      "9B",
+			StartTime:   startTime,
+			EndTime:     endTime,
+			Interval:    30,
+			LocationIDS: []int64{4, 2},
+		},
+	}
+	after, _ := time.Parse("15:04", "08:15")
+	date, _ := bitsb.ParseDate("2020-11-02")
+
+	t.Run("when departures are merged across routes", func(t *testing.T) {
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{2}).
+			Return(busRoutes, nil).
+			Once()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, []int64{3}).
+			Return([]*bitsb.Location{{ID: 3, Name: "location 3"}}, nil).
+			Once()
+
+		departures, err := s.service.Departures(context.Background(), 2, date, after, 2)
+		require.NoError(t, err)
+		require.Len(t, departures, 2)
+		require.Equal(t, "8A", departures[0].Number)
+		require.Equal(t, "08:30", departures[0].Time.Format("15:04"))
+		require.Equal(t, "location 3", departures[0].Direction)
+		require.Equal(t, "08:50", departures[1].Time.Format("15:04"))
+	})
+
+	t.Run("when a trip of the day before runs past midnight", func(t *testing.T) {
+		lateStart, _ := time.Parse("15:04", "23:00")
+		lateEnd, _ := time.Parse("15:04", "00:30")
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{6}).
+			Return([]*bitsb.BusRoute{{
+				ID:          10,
+				Number:      "N1",
+				StartTime:   lateStart,
+				EndTime:     lateEnd,
+				Interval:    30,
+				LocationIDS: []int64{6, 7},
+				// runs on sundays only, the date is a monday
+				CalendarID: null.IntFrom(2),
+			}}, nil).
+			Once()
+		s.calendarRepo.
+			On("SelectByID", mock.Anything, int64(2)).
+			Return(&bitsb.ServiceCalendar{ID: 2, Weekdays: []int64{0}}, nil).
+			Once()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, []int64{7}).
+			Return([]*bitsb.Location{{ID: 7, Name: "location 7"}}, nil).
+			Once()
+
+		midnight, _ := time.Parse("15:04", "00:00")
+		departures, err := s.service.Departures(context.Background(), 6, date, midnight, 10)
+		require.NoError(t, err)
+		require.Len(t, departures, 2)
+		require.Equal(t, "00:00", departures[0].Time.Format("15:04"))
+		require.Equal(t, "00:30", departures[1].Time.Format("15:04"))
+		require.Equal(t, "location 7", departures[1].Direction)
+	})
+
+	t.Run("when the routes cannot be fetched", func(t *testing.T) {
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{5}).
+			Return(nil, fmt.Errorf("error")).
+			Once()
+
+		departures, err := s.service.Departures(context.Background(), 5, date, after, 10)
+		require.Error(t, err)
+		require.Empty(t, departures)
+	})
+}
+
+func newRouteFixture() *bitsb.BusRoute {
+	startTime, _ := time.Parse("15:04", "06:00")
+	endTime, _ := time.Parse("15:04", "22:00")
+	return &bitsb.BusRoute{
 		ID:          1,
 		Name:        "Test Route 1",
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Interval:    15,
 		MinPrice:    3,
 		MaxPrice:    10,
 		LocationIDS: []int64{1, 2, 3, 5, 7, 8},
 	}
+}
+
+func stops(ids ...int64) []*bitsb.Location {
+	locations := make([]*bitsb.Location, 0, len(ids))
+	for _, id := range ids {
+		locations = append(locations, &bitsb.Location{ID: id})
+	}
+	return locations
+}
+
+func (s *BusRouteServiceTestSuite) TestCreate() {
+	t := s.T()
 
 	t.Run("when create route is successful", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(stops(1, 2, 3, 5, 7, 8), nil).
+			Once()
 		s.repo.
 			On("Insert", mock.Anything, busRoute).
-			Return(nil)
+			Return(nil).
+			Once()
 
 		err := s.service.Create(context.Background(), busRoute)
 		require.NoError(t, err)
 	})
 
 	t.Run("when create route is unsuccessful", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		busRoute.LocationIDS = []int64{1, 2}
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(stops(1, 2), nil).
+			Once()
 		s.repo.
-			On("Insert", mock.Anything, &bitsb.BusRoute{}).
-			Return(fmt.Errorf("error"))
+			On("Insert", mock.Anything, busRoute).
+			Return(fmt.Errorf("error")).
+			Once()
 
-		err := s.service.Create(context.Background(), &bitsb.BusRoute{})
+		err := s.service.Create(context.Background(), busRoute)
+		require.Error(t, err)
+	})
+
+	t.Run("when the route stops at unknown or repeated locations", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		busRoute.LocationIDS = []int64{1, 2, 1, 9}
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(stops(1, 2), nil).
+			Once()
+
+		err := s.service.Create(context.Background(), busRoute)
+		var v *apperrors.ValidationError
+		require.ErrorAs(t, err, &v)
+		require.Equal(t, []apperrors.FieldError{
+			{Field: "location_ids", Code: "duplicate", Detail: "location 1 is a stop more than once"},
+			{Field: "location_ids", Code: "not_found", Detail: "location 9 does not exist"},
+		}, v.Errors)
+	})
+
+	t.Run("when the route cannot be run", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		busRoute.EndTime = busRoute.StartTime
+		busRoute.Interval = 0
+		busRoute.MinPrice = 20
+		busRoute.LocationIDS = []int64{1}
+
+		err := s.service.Create(context.Background(), busRoute)
+		var v *apperrors.ValidationError
+		require.ErrorAs(t, err, &v)
+		require.True(t, v.Has("interval"))
+		require.True(t, v.Has("end_time"))
+		require.True(t, v.Has("min_price"))
+		require.True(t, v.Has("location_ids"))
+	})
+
+	t.Run("when the fare config does not match the strategy", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		busRoute.LocationIDS = []int64{1, 2, 3}
+		busRoute.FareStrategy = bitsb.FareZone
+		busRoute.FareConfig = bitsb.FareConfig{Zones: []int64{1, 2}}
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(stops(1, 2, 3), nil).
+			Once()
+
+		err := s.service.Create(context.Background(), busRoute)
 		require.Error(t, err)
 	})
 }
@@ -242,29 +558,44 @@ func (s *BusRouteServiceTestSuite) TestCreate() {
 func (s *BusRouteServiceTestSuite) TestUpdate() {
 	t := s.T()
 
-	busRoute := &bitsb.BusRoute{
-		ID:          1,
-		Name:        "Test Route 1",
-		MinPrice:    3,
-		MaxPrice:    10,
-		LocationIDS: []int64{1, 2, 3, 5, 7, 8},
-	}
-
 	t.Run("when update route is successful", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(stops(1, 2, 3, 5, 7, 8), nil).
+			Once()
 		s.repo.
 			On("Update", mock.Anything, busRoute).
-			Return(nil)
+			Return(nil).
+			Once()
 
 		err := s.service.Update(context.Background(), busRoute)
 		require.NoError(t, err)
 	})
 
 	t.Run("when update route is unsuccessful", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(stops(1, 2, 3, 5, 7, 8), nil).
+			Once()
 		s.repo.
-			On("Update", mock.Anything, &bitsb.BusRoute{}).
-			Return(fmt.Errorf("error"))
+			On("Update", mock.Anything, busRoute).
+			Return(fmt.Errorf("error")).
+			Once()
 
-		err := s.service.Update(context.Background(), &bitsb.BusRoute{})
+		err := s.service.Update(context.Background(), busRoute)
+		require.Error(t, err)
+	})
+
+	t.Run("when the locations cannot be fetched", func(t *testing.T) {
+		busRoute := newRouteFixture()
+		s.locationRepo.
+			On("SelectByIDArray", mock.Anything, busRoute.LocationIDS).
+			Return(nil, fmt.Errorf("error")).
+			Once()
+
+		err := s.service.Update(context.Background(), busRoute)
 		require.Error(t, err)
 	})
 }
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/bitsb/fare"
+	"github.com/sainak/bitsb/pkg/utils"
+)
+
+const (
+	// maxTransfers is the maximum number of bus changes in a planned journey
+	maxTransfers = 2
+	// minTransferTime is the time a passenger needs to change buses at a stop
+	minTransferTime = 2 * time.Minute
+)
+
+type JourneyService struct {
+	repo bitsb.BusRouteStorer
+}
+
+func NewJourneyService(r bitsb.BusRouteStorer) bitsb.JourneyServiceProvider {
+	return &JourneyService{
+		repo: r,
+	}
+}
+
+// Plan finds the earliest arriving journeys between two locations leaving at or after departAt.
+// Each round of the search adds one more bus ride, so a journey with more transfers
+// is only returned when it arrives earlier than every journey with fewer transfers.
+func (j *JourneyService) Plan(ctx context.Context, from, to int64, departAt time.Time) ([]*bitsb.Journey, error) {
+	journeys := make([]*bitsb.Journey, 0)
+	if from == to {
+		return journeys, apperrors.ErrInvalidLocation
+	}
+
+	reached := map[int64]*bitsb.Journey{
+		from: {Departure: departAt, Arrival: departAt},
+	}
+	marked := []int64{from}
+
+	for round := 0; round <= maxTransfers && len(marked) > 0; round++ {
+		busRoutes, err := j.repo.SelectByLocations(ctx, marked)
+		if err != nil {
+			return []*bitsb.Journey{}, err
+		}
+
+		improved := make(map[int64]*bitsb.Journey)
+		for _, busRoute := range busRoutes {
+			offsets := busRoute.StopOffsets()
+			for _, stop := range marked {
+				boardIndex := utils.IndexOf(busRoute.LocationIDS, stop)
+				if boardIndex == -1 {
+					continue
+				}
+				current := reached[stop]
+				readyAt := current.Arrival
+				if len(current.Legs) > 0 {
+					if current.Legs[len(current.Legs)-1].BusRouteID == busRoute.ID {
+						continue
+					}
+					readyAt = readyAt.Add(minTransferTime)
+				}
+
+				departure, ok := nextDeparture(busRoute, offsets[boardIndex], readyAt)
+				if !ok {
+					continue
+				}
+
+				for alightIndex := boardIndex + 1; alightIndex < len(busRoute.LocationIDS); alightIndex++ {
+					alight := busRoute.LocationIDS[alightIndex]
+					arrival := departure.Add(offsets[alightIndex] - offsets[boardIndex])
+					if best, ok := improved[alight]; ok && !arrival.Before(best.Arrival) {
+						continue
+					}
+					if best, ok := reached[alight]; ok && !arrival.Before(best.Arrival) {
+						continue
+					}
+
+					legFare, err := fare.Calculate(busRoute, stop, alight)
+					if err != nil {
+						return []*bitsb.Journey{}, err
+					}
+					improved[alight] = extendJourney(current, &bitsb.JourneyLeg{
+						BusRouteID:     busRoute.ID,
+						Number:         busRoute.Number,
+						Name:           busRoute.Name,
+						FromLocationID: stop,
+						ToLocationID:   alight,
+						Stops:          alightIndex - boardIndex,
+						Departure:      departure,
+						Arrival:        arrival,
+						Fare:           legFare.TicketPrice,
+					})
+				}
+			}
+		}
+
+		marked = make([]int64, 0, len(improved))
+		for stop, journey := range improved {
+			reached[stop] = journey
+			if stop != to {
+				marked = append(marked, stop)
+			}
+		}
+		sort.Slice(marked, func(i, j int) bool { return marked[i] < marked[j] })
+
+		if journey, ok := improved[to]; ok {
+			journeys = append(journeys, journey)
+		}
+	}
+	return journeys, nil
+}
+
+// nextDeparture returns the time the first trip of the route reaches
+// the stop offset from the first stop at or after the given time. The trips
+// of the day before running past midnight are taken too, 24 hours earlier.
+func nextDeparture(busRoute *bitsb.BusRoute, offset time.Duration, after time.Time) (time.Time, bool) {
+	for days := -1; days <= 0; days++ {
+		for _, departure := range busRoute.DepartureTimes() {
+			if t := departure.Add(offset).AddDate(0, 0, days); !t.Before(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// extendJourney returns a copy of the journey with one more leg added to it
+func extendJourney(journey *bitsb.Journey, leg *bitsb.JourneyLeg) *bitsb.Journey {
+	extended := &bitsb.Journey{
+		Legs:                make([]*bitsb.JourneyLeg, 0, len(journey.Legs)+1),
+		TransferLocationIDS: make([]int64, 0, len(journey.Legs)),
+		Arrival:             leg.Arrival,
+	}
+	extended.Legs = append(extended.Legs, journey.Legs...)
+	extended.Legs = append(extended.Legs, leg)
+	for i, l := range extended.Legs {
+		extended.Fare += l.Fare
+		if i > 0 {
+			extended.TransferLocationIDS = append(extended.TransferLocationIDS, l.FromLocationID)
+		}
+	}
+	extended.Departure = extended.Legs[0].Departure
+	extended.Duration = int64(extended.Arrival.Sub(extended.Departure) / time.Second)
+	return extended
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/mocks"
+)
+
+type JourneyServiceTestSuite struct {
+	suite.Suite
+	service bitsb.JourneyServiceProvider
+	repo    *mocks.BusRouteStorer
+}
+
+func TestJourneyServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(JourneyServiceTestSuite))
+}
+
+func (s *JourneyServiceTestSuite) SetupTest() {
+	s.repo = mocks.NewBusRouteStorer(s.T())
+	s.service = NewJourneyService(s.repo)
+}
+
+func clock(t *testing.T, value string) time.Time {
+	c, err := time.Parse("15:04", value)
+	require.NoError(t, err)
+	return c
+}
+
+func (s *JourneyServiceTestSuite) TestPlan() {
+	t := s.T()
+
+	firstRoute := &bitsb.BusRoute{
+		ID:               1,
+		Number:           "1A",
+		StartTime:        clock(t, "08:00"),
+		EndTime:          clock(t, "09:00"),
+		Interval:         30,
+		LocationIDS:      []int64{1, 2, 3},
+		SegmentDurations: []int64{300, 300},
+		MinPrice:         5,
+		MaxPrice:         20,
+	}
+	secondRoute := &bitsb.BusRoute{
+		ID:               2,
+		Number:           "2B",
+		StartTime:        clock(t, "08:00"),
+		EndTime:          clock(t, "10:00"),
+		Interval:         15,
+		LocationIDS:      []int64{3, 4},
+		SegmentDurations: []int64{600},
+		MinPrice:         5,
+		MaxPrice:         20,
+	}
+
+	t.Run("when the journey needs a transfer", func(t *testing.T) {
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{1}).
+			Return([]*bitsb.BusRoute{firstRoute}, nil).
+			Once()
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{2, 3}).
+			Return([]*bitsb.BusRoute{firstRoute, secondRoute}, nil).
+			Once()
+
+		journeys, err := s.service.Plan(context.Background(), 1, 4, clock(t, "08:00"))
+		require.NoError(t, err)
+		require.Len(t, journeys, 1)
+
+		journey := journeys[0]
+		require.Len(t, journey.Legs, 2)
+		require.Equal(t, []int64{3}, journey.TransferLocationIDS)
+		require.Equal(t, "08:00", journey.Departure.Format("15:04"))
+		require.Equal(t, "08:25", journey.Arrival.Format("15:04"))
+		require.Equal(t, int64(25*60), journey.Duration)
+		require.Equal(t, int64(15), journey.Fare)
+		s.repo.AssertExpectations(t)
+	})
+
+	t.Run("when the journey is on a single route", func(t *testing.T) {
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{1}).
+			Return([]*bitsb.BusRoute{firstRoute}, nil).
+			Once()
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{2}).
+			Return([]*bitsb.BusRoute{firstRoute}, nil).
+			Once()
+
+		journeys, err := s.service.Plan(context.Background(), 1, 3, clock(t, "08:10"))
+		require.NoError(t, err)
+		require.Len(t, journeys, 1)
+		require.Len(t, journeys[0].Legs, 1)
+		require.Empty(t, journeys[0].TransferLocationIDS)
+		require.Equal(t, "08:30", journeys[0].Departure.Format("15:04"))
+		require.Equal(t, int64(10), journeys[0].Fare)
+		s.repo.AssertExpectations(t)
+	})
+
+	t.Run("when there are no more buses", func(t *testing.T) {
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{1}).
+			Return([]*bitsb.BusRoute{firstRoute}, nil).
+			Once()
+
+		journeys, err := s.service.Plan(context.Background(), 1, 3, clock(t, "22:00"))
+		require.NoError(t, err)
+		require.Empty(t, journeys)
+	})
+
+	t.Run("when a trip of the day before runs past midnight", func(t *testing.T) {
+		nightRoute := &bitsb.BusRoute{
+			ID:               3,
+			Number:           "N1",
+			StartTime:        clock(t, "23:00"),
+			EndTime:          clock(t, "00:30"),
+			Interval:         30,
+			LocationIDS:      []int64{6, 7},
+			SegmentDurations: []int64{600},
+		}
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{6}).
+			Return([]*bitsb.BusRoute{nightRoute}, nil).
+			Once()
+
+		journeys, err := s.service.Plan(context.Background(), 6, 7, clock(t, "00:20"))
+		require.NoError(t, err)
+		require.Len(t, journeys, 1)
+		require.Equal(t, clock(t, "00:30"), journeys[0].Departure)
+		require.Equal(t, clock(t, "00:40"), journeys[0].Arrival)
+	})
+
+	t.Run("when the origin and destination are the same", func(t *testing.T) {
+		journeys, err := s.service.Plan(context.Background(), 1, 1, clock(t, "08:00"))
+		require.ErrorIs(t, err, apperrors.ErrInvalidLocation)
+		require.Empty(t, journeys)
+	})
+
+	t.Run("when the routes cannot be fetched", func(t *testing.T) {
+		s.repo.
+			On("SelectByLocations", mock.Anything, []int64{5}).
+			Return(nil, fmt.Errorf("error")).
+			Once()
+
+		journeys, err := s.service.Plan(context.Background(), 5, 1, clock(t, "08:00"))
+		require.Error(t, err)
+		require.Empty(t, journeys)
+	})
+}
@@ -3,17 +3,20 @@ package service
 import (
 	"context"
 
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/bitsb"
 	"github.com/sainak/bitsb/pkg/repo"
 )
 
 type LocationService struct {
 	repo bitsb.LocationStorer
+	uow  repo.UnitOfWork
 }
 
-func NewLocationService(r bitsb.LocationStorer) bitsb.LocationServiceProvider {
+func NewLocationService(r bitsb.LocationStorer, uow repo.UnitOfWork) bitsb.LocationServiceProvider {
 	return &LocationService{
 		repo: r,
+		uow:  uow,
 	}
 }
 
@@ -22,7 +25,7 @@ func (l LocationService) ListAll(
 	cursor string,
 	limit int64,
 	filters repo.Filters,
-) ([]*bitsb.Location, string, error) {
+) ([]*bitsb.Location, repo.Page, error) {
 	return l.repo.SelectAll(ctx, cursor, limit, filters)
 }
 
@@ -30,6 +33,14 @@ func (l LocationService) GetByID(ctx context.Context, id int64) (*bitsb.Location
 	return l.repo.SelectByID(ctx, id)
 }
 
+func (l LocationService) ListNearby(
+	ctx context.Context,
+	lat, lng, radius float64,
+	limit int64,
+) ([]*bitsb.NearbyLocation, error) {
+	return l.repo.SelectNearby(ctx, lat, lng, radius, limit)
+}
+
 func (l LocationService) Create(ctx context.Context, location *bitsb.Location) error {
 	return l.repo.Insert(ctx, location)
 }
@@ -38,6 +49,21 @@ func (l LocationService) Update(ctx context.Context, location *bitsb.Location) e
 	return l.repo.Update(ctx, location)
 }
 
-func (l LocationService) Delete(ctx context.Context, id int64) error {
-	return l.repo.Delete(ctx, id)
+// Delete deletes a location no bus route, user or ticket depends on, when detach is set
+// the location is removed from the routes and users instead of failing with a conflict.
+// SelectDependents locks the location so no route can add it as a stop before it is deleted.
+func (l LocationService) Delete(ctx context.Context, id int64, detach bool) error {
+	if detach {
+		return l.repo.DeleteDetached(ctx, id)
+	}
+	return l.uow.Do(ctx, func(ctx context.Context) error {
+		dependents, err := l.repo.SelectDependents(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !dependents.Empty() {
+			return apperrors.ErrLocationInUse.WithDetails(dependents)
+		}
+		return l.repo.Delete(ctx, id)
+	})
 }
@@ -21,6 +21,7 @@ type LocationServiceTestSuite struct {
 	suite.Suite
 	service bitsb.LocationServiceProvider
 	repo    *mocks.LocationStorer
+	uow     *mocks.UnitOfWork
 }
 
 func TestLocationServiceTestSuite(t *testing.T) {
@@ -41,40 +42,44 @@ func TestLocationServiceTestSuite(t *testing.T) {
 
 func (s *LocationServiceTestSuite) SetupTest() {
 	s.repo = mocks.NewLocationStorer(s.T())
-	s.service = NewLocationService(s.repo)
+	s.uow = mocks.NewUnitOfWork(s.T())
+	s.uow.On("Do", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) }).
+		Maybe()
+	s.service = NewLocationService(s.repo, s.uow)
 }
 
 func (s *LocationServiceTestSuite) TestListAll() {
 	t := s.T()
 
 	locations := []*bitsb.Location{
-		{1, "abc", time.Now(), time.Now()},
-		{2, "def", time.Now(), time.Now()},
-		{3, "abd", time.Now(), time.Now()},
-		{4, "def", time.Now(), time.Now()},
+		{ID: 1, Name: "abc", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 2, Name: "def", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 3, Name: "abd", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 4, Name: "def", CreatedAt: time.Now(), UpdatedAt: time.Now()},
 	}
 
 	t.Run("when location list is successfully retrieved", func(t *testing.T) {
 		s.repo.
 			On("SelectAll", mock.Anything, "", int64(10), repo.Filters{}).
-			Return(locations, "", nil).
+			Return(locations, repo.Page{}, nil).
 			Once()
-		list, nextCursor, err := s.service.ListAll(context.Background(), "", int64(10), repo.Filters{})
+		list, page, err := s.service.ListAll(context.Background(), "", int64(10), repo.Filters{})
 		require.NoError(t, err)
 		require.Equal(t, locations, list)
-		require.Equal(t, "", nextCursor)
+		require.Equal(t, repo.Page{}, page)
 		s.repo.AssertExpectations(t)
 	})
 
 	t.Run("whenlist loction is unsuccessful", func(t *testing.T) {
 		s.repo.
 			On("SelectAll", mock.Anything, "", int64(10), repo.Filters{}).
-			Return([]*bitsb.Location{}, "", fmt.Errorf("error")).
+			Return([]*bitsb.Location{}, repo.Page{}, fmt.Errorf("error")).
 			Once()
-		list, nextCursor, err := s.service.ListAll(context.Background(), "", int64(10), repo.Filters{})
+		list, page, err := s.service.ListAll(context.Background(), "", int64(10), repo.Filters{})
 		require.Error(t, err)
 		require.Empty(t, list)
-		require.Equal(t, "", nextCursor)
+		require.Equal(t, repo.Page{}, page)
 		s.repo.AssertExpectations(t)
 	})
 }
@@ -118,6 +123,37 @@ func (s *LocationServiceTestSuite) TestGetByID() {
 	})
 }
 
+func (s *LocationServiceTestSuite) TestListNearby() {
+	t := s.T()
+
+	locations := []*bitsb.NearbyLocation{
+		{Location: bitsb.Location{ID: 1, Name: "abc"}, Distance: 10},
+		{Location: bitsb.Location{ID: 2, Name: "def"}, Distance: 250},
+	}
+
+	t.Run("when nearby locations are successfully retrieved", func(t *testing.T) {
+		s.repo.
+			On("SelectNearby", mock.Anything, 12.97, 77.59, float64(1000), int64(10)).
+			Return(locations, nil).
+			Once()
+		list, err := s.service.ListNearby(context.Background(), 12.97, 77.59, 1000, int64(10))
+		require.NoError(t, err)
+		require.Equal(t, locations, list)
+		s.repo.AssertExpectations(t)
+	})
+
+	t.Run("when nearby locations are unsuccessful", func(t *testing.T) {
+		s.repo.
+			On("SelectNearby", mock.Anything, 12.97, 77.59, float64(1000), int64(10)).
+			Return([]*bitsb.NearbyLocation{}, fmt.Errorf("error")).
+			Once()
+		list, err := s.service.ListNearby(context.Background(), 12.97, 77.59, 1000, int64(10))
+		require.Error(t, err)
+		require.Empty(t, list)
+		s.repo.AssertExpectations(t)
+	})
+}
+
 func (s *LocationServiceTestSuite) TestCreate() {
 	t := s.T()
 
@@ -169,23 +205,74 @@ func (s *LocationServiceTestSuite) TestUpdate() {
 func (s *LocationServiceTestSuite) TestDelete() {
 	t := s.T()
 
+	noDependents := &bitsb.LocationDependents{}
+
 	t.Run("when location delete is successfully deleted", func(t *testing.T) {
+		s.repo.
+			On("SelectDependents", mock.Anything, int64(1)).
+			Return(noDependents, nil).
+			Once()
 		s.repo.
 			On("Delete", mock.Anything, int64(1)).
 			Return(nil).
 			Once()
-		err := s.service.Delete(context.Background(), int64(1))
+		err := s.service.Delete(context.Background(), int64(1), false)
 		require.NoError(t, err)
 		s.repo.AssertExpectations(t)
 	})
 
 	t.Run("when location delete is unsuccessful", func(t *testing.T) {
+		s.repo.
+			On("SelectDependents", mock.Anything, int64(1)).
+			Return(noDependents, nil).
+			Once()
 		s.repo.
 			On("Delete", mock.Anything, int64(1)).
 			Return(fmt.Errorf("error")).
 			Once()
-		err := s.service.Delete(context.Background(), int64(1))
+		err := s.service.Delete(context.Background(), int64(1), false)
 		require.Error(t, err)
 		s.repo.AssertExpectations(t)
 	})
+
+	t.Run("when the location is in use", func(t *testing.T) {
+		dependents := &bitsb.LocationDependents{
+			BusRoutes: []*bitsb.BusRouteSummary{{ID: 2, Name: "City Loop", Number: "2B"}},
+			UserIDs:   []int64{},
+		}
+		s.repo.
+			On("SelectDependents", mock.Anything, int64(2)).
+			Return(dependents, nil).
+			Once()
+		err := s.service.Delete(context.Background(), int64(2), false)
+		require.ErrorIs(t, err, apperrors.ErrLocationInUse)
+
+		var e *apperrors.Error
+		require.ErrorAs(t, err, &e)
+		require.Equal(t, dependents, e.Details)
+	})
+
+	t.Run("when tickets are from or to the location", func(t *testing.T) {
+		dependents := &bitsb.LocationDependents{
+			BusRoutes: []*bitsb.BusRouteSummary{},
+			UserIDs:   []int64{},
+			TicketIDs: []int64{5},
+		}
+		s.repo.
+			On("SelectDependents", mock.Anything, int64(3)).
+			Return(dependents, nil).
+			Once()
+		err := s.service.Delete(context.Background(), int64(3), false)
+		require.ErrorIs(t, err, apperrors.ErrLocationInUse)
+		s.repo.AssertNotCalled(t, "Delete", mock.Anything, int64(3))
+	})
+
+	t.Run("when the location is detached from its dependents", func(t *testing.T) {
+		s.repo.
+			On("DeleteDetached", mock.Anything, int64(2)).
+			Return(nil).
+			Once()
+		err := s.service.Delete(context.Background(), int64(2), true)
+		require.NoError(t, err)
+	})
 }
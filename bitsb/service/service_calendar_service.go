@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sainak/bitsb/bitsb"
+)
+
+type ServiceCalendarService struct {
+	repo bitsb.ServiceCalendarStorer
+}
+
+func NewServiceCalendarService(r bitsb.ServiceCalendarStorer) bitsb.ServiceCalendarServiceProvider {
+	return &ServiceCalendarService{
+		repo: r,
+	}
+}
+
+func (c ServiceCalendarService) ListAll(ctx context.Context) ([]*bitsb.ServiceCalendar, error) {
+	return c.repo.SelectAll(ctx)
+}
+
+func (c ServiceCalendarService) GetByID(ctx context.Context, id int64) (*bitsb.ServiceCalendar, error) {
+	return c.repo.SelectByID(ctx, id)
+}
+
+func (c ServiceCalendarService) Create(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	return c.repo.Insert(ctx, calendar)
+}
+
+func (c ServiceCalendarService) Update(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	return c.repo.Update(ctx, calendar)
+}
+
+func (c ServiceCalendarService) Delete(ctx context.Context, id int64) error {
+	return c.repo.Delete(ctx, id)
+}
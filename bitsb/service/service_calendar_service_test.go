@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/mocks"
+)
+
+type ServiceCalendarServiceTestSuite struct {
+	suite.Suite
+	service bitsb.ServiceCalendarServiceProvider
+	repo    *mocks.ServiceCalendarStorer
+}
+
+func TestServiceCalendarServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ServiceCalendarServiceTestSuite))
+}
+
+func (s *ServiceCalendarServiceTestSuite) SetupTest() {
+	s.repo = mocks.NewServiceCalendarStorer(s.T())
+	s.service = NewServiceCalendarService(s.repo)
+}
+
+func (s *ServiceCalendarServiceTestSuite) TestListAll() {
+	t := s.T()
+
+	calendars := []*bitsb.ServiceCalendar{
+		{ID: 1, Name: "weekdays", Weekdays: []int64{1, 2, 3, 4, 5}},
+		{ID: 2, Name: "weekends", Weekdays: []int64{0, 6}},
+	}
+
+	t.Run("when calendars are successfully retrieved", func(t *testing.T) {
+		s.repo.
+			On("SelectAll", mock.Anything).
+			Return(calendars, nil).
+			Once()
+		list, err := s.service.ListAll(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, calendars, list)
+	})
+
+	t.Run("when calendars are not retrieved", func(t *testing.T) {
+		s.repo.
+			On("SelectAll", mock.Anything).
+			Return([]*bitsb.ServiceCalendar{}, fmt.Errorf("error")).
+			Once()
+		list, err := s.service.ListAll(context.Background())
+		require.Error(t, err)
+		require.Empty(t, list)
+	})
+}
+
+func (s *ServiceCalendarServiceTestSuite) TestGetByID() {
+	t := s.T()
+
+	t.Run("when calendar is successfully retrieved", func(t *testing.T) {
+		calendar := &bitsb.ServiceCalendar{ID: 1, Name: "weekdays"}
+		s.repo.
+			On("SelectByID", mock.Anything, int64(1)).
+			Return(calendar, nil).
+			Once()
+		got, err := s.service.GetByID(context.Background(), int64(1))
+		require.NoError(t, err)
+		require.Equal(t, calendar, got)
+	})
+
+	t.Run("when calendar is not found", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(2)).
+			Return(&bitsb.ServiceCalendar{}, apperrors.ErrNotFound).
+			Once()
+		got, err := s.service.GetByID(context.Background(), int64(2))
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+		require.Empty(t, got)
+	})
+}
+
+func (s *ServiceCalendarServiceTestSuite) TestCreate() {
+	t := s.T()
+
+	t.Run("when calendar is successfully created", func(t *testing.T) {
+		s.repo.
+			On("Insert", mock.Anything, mock.Anything).
+			Return(nil).
+			Once()
+		err := s.service.Create(context.Background(), &bitsb.ServiceCalendar{Name: "weekdays"})
+		require.NoError(t, err)
+	})
+}
+
+func (s *ServiceCalendarServiceTestSuite) TestUpdate() {
+	t := s.T()
+
+	t.Run("when calendar is successfully updated", func(t *testing.T) {
+		s.repo.
+			On("Update", mock.Anything, mock.Anything).
+			Return(nil).
+			Once()
+		err := s.service.Update(context.Background(), &bitsb.ServiceCalendar{ID: 1, Name: "weekdays"})
+		require.NoError(t, err)
+	})
+}
+
+func (s *ServiceCalendarServiceTestSuite) TestDelete() {
+	t := s.T()
+
+	t.Run("when calendar is successfully deleted", func(t *testing.T) {
+		s.repo.
+			On("Delete", mock.Anything, int64(1)).
+			Return(nil).
+			Once()
+		err := s.service.Delete(context.Background(), int64(1))
+		require.NoError(t, err)
+	})
+
+	t.Run("when calendar delete is unsuccessful", func(t *testing.T) {
+		s.repo.
+			On("Delete", mock.Anything, int64(2)).
+			Return(apperrors.ErrNotFound).
+			Once()
+		err := s.service.Delete(context.Background(), int64(2))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,298 @@
+package inmemory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/pkg/utils"
+)
+
+// busRouteColumns are the columns bus routes can be filtered on
+var busRouteColumns = repo.Columns{
+	"name":          "name",
+	"number":        "number",
+	"interval":      "interval",
+	"min_price":     "min_price",
+	"max_price":     "max_price",
+	"fare_strategy": "fare_strategy",
+	"calendar_id":   "calendar_id",
+}
+
+// busRouteSorts are the columns bus routes can be sorted on
+var busRouteSorts = repo.SortColumns{
+	"created_at": {Column: "created_at", Type: "timestamp"},
+	"name":       {Column: "name", Type: "varchar"},
+	"number":     {Column: "number", Type: "varchar"},
+	"min_price":  {Column: "min_price", Type: "integer"},
+	"max_price":  {Column: "max_price", Type: "integer"},
+	"start_time": {Column: "start_time", Type: "time"},
+	"end_time":   {Column: "end_time", Type: "time"},
+}
+
+var defaultBusRouteSort = repo.Sort{Key: "created_at", Desc: true}
+
+type BusRouteRepository struct {
+	store *Store
+}
+
+func NewBusRouteRepository(store *Store) bitsb.BusRouteStorer {
+	return &BusRouteRepository{store}
+}
+
+// cloneBusRoute copies the stored columns of the route
+func cloneBusRoute(busRoute bitsb.BusRoute) bitsb.BusRoute {
+	return bitsb.BusRoute{
+		ID:               busRoute.ID,
+		Name:             busRoute.Name,
+		Number:           busRoute.Number,
+		StartTime:        busRoute.StartTime,
+		EndTime:          busRoute.EndTime,
+		Interval:         busRoute.Interval,
+		LocationIDS:      cloneSlice(busRoute.LocationIDS),
+		CalendarID:       busRoute.CalendarID,
+		SegmentDurations: cloneSlice(busRoute.SegmentDurations),
+		SegmentDistances: cloneSlice(busRoute.SegmentDistances),
+		MinPrice:         busRoute.MinPrice,
+		MaxPrice:         busRoute.MaxPrice,
+		FareStrategy:     busRoute.FareStrategy,
+		FareConfig: bitsb.FareConfig{
+			FlatPrice:  busRoute.FareConfig.FlatPrice,
+			Zones:      append([]int64(nil), busRoute.FareConfig.Zones...),
+			ZonePrices: append([]int64(nil), busRoute.FareConfig.ZonePrices...),
+			BasePrice:  busRoute.FareConfig.BasePrice,
+			PricePerKM: busRoute.FareConfig.PricePerKM,
+		},
+		CreatedAt: busRoute.CreatedAt,
+		UpdatedAt: busRoute.UpdatedAt,
+	}
+}
+
+func busRouteField(b *bitsb.BusRoute) func(column string) interface{} {
+	return func(column string) interface{} {
+		switch column {
+		case "name":
+			return b.Name
+		case "number":
+			return b.Number
+		case "interval":
+			return b.Interval
+		case "min_price":
+			return b.MinPrice
+		case "max_price":
+			return b.MaxPrice
+		case "fare_strategy":
+			return b.FareStrategy
+		case "calendar_id":
+			if b.CalendarID.Valid {
+				return b.CalendarID.Int64
+			}
+		}
+		return nil
+	}
+}
+
+func (b *BusRouteRepository) SelectAll(
+	ctx context.Context,
+	cursor string,
+	limit int64,
+	filters bitsb.BusRouteFilters,
+	sort repo.Sort,
+) ([]*bitsb.BusRoute, repo.Page, error) {
+	if sort.Key == "" {
+		sort = defaultBusRouteSort
+	}
+	paginator, err := repo.NewPaginator(cursor, limit, sort, busRouteSorts, filters)
+	if err != nil {
+		return []*bitsb.BusRoute{}, repo.Page{}, err
+	}
+
+	t, unlock := b.store.lock(ctx)
+	defer unlock()
+
+	busRoutes := make([]*bitsb.BusRoute, 0)
+	for _, row := range t.busRoutes {
+		busRoute := cloneBusRoute(row)
+		match, err := matchBusRoute(t, &busRoute, filters)
+		if err != nil {
+			return []*bitsb.BusRoute{}, repo.Page{}, err
+		}
+		if match {
+			busRoutes = append(busRoutes, &busRoute)
+		}
+	}
+
+	busRoutes, page := repo.PaginateSlice(paginator, busRoutes, func(busRoute *bitsb.BusRoute) (string, int64) {
+		return busRouteSortValue(busRoute, sort.Key), busRoute.ID
+	})
+	return busRoutes, page, nil
+}
+
+// matchBusRoute reports whether the route matches the filters
+func matchBusRoute(t *tables, busRoute *bitsb.BusRoute, filters bitsb.BusRouteFilters) (bool, error) {
+	previous := -1
+	for _, locationID := range filters.Locations {
+		i := utils.IndexOf(busRoute.LocationIDS, locationID)
+		if i == -1 {
+			return false, nil
+		}
+		// only matches routes that reach the stops in the given order
+		if filters.Ordered && i <= previous {
+			return false, nil
+		}
+		previous = i
+	}
+	if !filters.Date.IsZero() && busRoute.CalendarID.Valid {
+		calendar, ok := t.calendars[busRoute.CalendarID.Int64]
+		if !ok || !calendar.RunsOn(filters.Date) {
+			return false, nil
+		}
+	}
+	if filters.Query != "" {
+		query := strings.ToLower(filters.Query)
+		if !strings.Contains(strings.ToLower(busRoute.Name), query) && !strings.Contains(strings.ToLower(busRoute.Number), query) {
+			return false, nil
+		}
+	}
+	// routes with any fare in the price range
+	if filters.PriceFrom.Valid && busRoute.MaxPrice < filters.PriceFrom.Int64 {
+		return false, nil
+	}
+	if filters.PriceTo.Valid && busRoute.MinPrice > filters.PriceTo.Int64 {
+		return false, nil
+	}
+	if filters.RunningAt.Valid {
+		at := filters.RunningAt.Time.Format("15:04:05")
+		start, end := busRoute.StartTime.Format("15:04:05"), busRoute.EndTime.Format("15:04:05")
+		// routes with an end time before their start time run past midnight
+		if start <= end && (at < start || at > end) || start > end && at < start && at > end {
+			return false, nil
+		}
+	}
+	return filters.Filters.Match(busRouteColumns, busRouteField(busRoute))
+}
+
+// busRouteSortValue returns the value of the sort key of the route as it is stored in the cursor
+func busRouteSortValue(busRoute *bitsb.BusRoute, key string) string {
+	switch key {
+	case "name":
+		return busRoute.Name
+	case "number":
+		return busRoute.Number
+	case "min_price":
+		return strconv.FormatInt(busRoute.MinPrice, 10)
+	case "max_price":
+		return strconv.FormatInt(busRoute.MaxPrice, 10)
+	case "start_time":
+		return busRoute.StartTime.Format("15:04:05")
+	case "end_time":
+		return busRoute.EndTime.Format("15:04:05")
+	default:
+		return busRoute.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// SelectByLocations returns every route that stops at any of the given locations
+func (b *BusRouteRepository) SelectByLocations(ctx context.Context, locations []int64) ([]*bitsb.BusRoute, error) {
+	t, unlock := b.store.lock(ctx)
+	defer unlock()
+
+	busRoutes := make([]*bitsb.BusRoute, 0)
+	for _, row := range t.busRoutes {
+		for _, locationID := range locations {
+			if utils.IndexOf(row.LocationIDS, locationID) != -1 {
+				busRoute := cloneBusRoute(row)
+				busRoutes = append(busRoutes, &busRoute)
+				break
+			}
+		}
+	}
+	sort.Slice(busRoutes, func(i, j int) bool {
+		return busRoutes[i].ID < busRoutes[j].ID
+	})
+	return busRoutes, nil
+}
+
+func (b *BusRouteRepository) SelectByID(ctx context.Context, id int64) (*bitsb.BusRoute, error) {
+	t, unlock := b.store.lock(ctx)
+	defer unlock()
+
+	row, ok := t.busRoutes[id]
+	if !ok {
+		return &bitsb.BusRoute{}, apperrors.ErrRouteNotFound.Wrap(sql.ErrNoRows)
+	}
+	busRoute := cloneBusRoute(row)
+	return &busRoute, nil
+}
+
+func (b *BusRouteRepository) Insert(ctx context.Context, busRoute *bitsb.BusRoute) error {
+	t, unlock := b.store.lock(ctx)
+	defer unlock()
+
+	if err := checkBusRoute(t, busRoute); err != nil {
+		return err
+	}
+
+	currentTime := time.Now()
+	busRoute.CreatedAt = currentTime
+	busRoute.UpdatedAt = currentTime
+	busRoute.ID = b.store.nextID("bus_routes")
+	t.busRoutes[busRoute.ID] = cloneBusRoute(*busRoute)
+	return nil
+}
+
+func (b *BusRouteRepository) Update(ctx context.Context, busRoute *bitsb.BusRoute) error {
+	t, unlock := b.store.lock(ctx)
+	defer unlock()
+
+	busRoute.UpdatedAt = time.Now()
+
+	row, ok := t.busRoutes[busRoute.ID]
+	if !ok {
+		return apperrors.ErrRouteNotFound
+	}
+	if err := checkBusRoute(t, busRoute); err != nil {
+		return err
+	}
+	updated := cloneBusRoute(*busRoute)
+	updated.CreatedAt = row.CreatedAt
+	t.busRoutes[busRoute.ID] = updated
+	return nil
+}
+
+// checkBusRoute checks the constraints of the bus_routes table
+func checkBusRoute(t *tables, busRoute *bitsb.BusRoute) error {
+	for _, other := range t.busRoutes {
+		if other.Number == busRoute.Number && other.ID != busRoute.ID {
+			return apperrors.ErrEntityAlreadyExist
+		}
+	}
+	if busRoute.CalendarID.Valid {
+		if _, ok := t.calendars[busRoute.CalendarID.Int64]; !ok {
+			return errForeignKey
+		}
+	}
+	return nil
+}
+
+func (b *BusRouteRepository) Delete(ctx context.Context, id int64) error {
+	t, unlock := b.store.lock(ctx)
+	defer unlock()
+
+	if _, ok := t.busRoutes[id]; !ok {
+		return apperrors.ErrRouteNotFound
+	}
+	for _, ticket := range t.tickets {
+		if ticket.BusRouteID == id {
+			return errForeignKey
+		}
+	}
+	delete(t.busRoutes, id)
+	return nil
+}
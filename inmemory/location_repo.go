@@ -0,0 +1,328 @@
+package inmemory
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/pkg/utils"
+	"github.com/sainak/bitsb/tickets"
+)
+
+// locationColumns are the columns locations can be filtered on
+var locationColumns = repo.Columns{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+}
+
+// locationSorts are the columns locations can be sorted on
+var locationSorts = repo.SortColumns{
+	"created_at": {Column: "created_at", Type: "timestamptz"},
+}
+
+var defaultLocationSort = repo.Sort{Key: "created_at", Desc: true}
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000
+
+type LocationRepository struct {
+	store *Store
+}
+
+func NewLocationRepository(store *Store) bitsb.LocationStorer {
+	return &LocationRepository{store}
+}
+
+func locationField(l *bitsb.Location) func(column string) interface{} {
+	return func(column string) interface{} {
+		switch column {
+		case "id":
+			return l.ID
+		case "name":
+			return l.Name
+		case "created_at":
+			return l.CreatedAt
+		}
+		return nil
+	}
+}
+
+func (l LocationRepository) SelectAll(
+	ctx context.Context,
+	cursor string,
+	limit int64,
+	filters repo.Filters,
+) ([]*bitsb.Location, repo.Page, error) {
+	paginator, err := repo.NewPaginator(cursor, limit, defaultLocationSort, locationSorts, filters)
+	if err != nil {
+		return []*bitsb.Location{}, repo.Page{}, err
+	}
+
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	locations := make([]*bitsb.Location, 0)
+	for _, row := range t.locations {
+		location := row
+		match, err := filters.Match(locationColumns, locationField(&location))
+		if err != nil {
+			return []*bitsb.Location{}, repo.Page{}, err
+		}
+		if match {
+			locations = append(locations, &location)
+		}
+	}
+
+	locations, page := repo.PaginateSlice(paginator, locations, func(l *bitsb.Location) (string, int64) {
+		return l.CreatedAt.Format(time.RFC3339Nano), l.ID
+	})
+	return locations, page, nil
+}
+
+func (l LocationRepository) SelectByID(ctx context.Context, id int64) (*bitsb.Location, error) {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	location, ok := t.locations[id]
+	if !ok {
+		return &bitsb.Location{}, apperrors.ErrLocationNotFound.Wrap(sql.ErrNoRows)
+	}
+	return &location, nil
+}
+
+func (l LocationRepository) SelectByIDArray(ctx context.Context, ids []int64) ([]*bitsb.Location, error) {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	locations := make([]*bitsb.Location, 0, len(ids))
+	for _, id := range ids {
+		if location, ok := t.locations[id]; ok {
+			locations = append(locations, &location)
+		}
+	}
+	return locations, nil
+}
+
+// SelectNearby returns the locations within radius meters of the given point,
+// nearest first. Distances are calculated with the haversine formula.
+func (l LocationRepository) SelectNearby(
+	ctx context.Context,
+	lat, lng, radius float64,
+	limit int64,
+) ([]*bitsb.NearbyLocation, error) {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	locations := make([]*bitsb.NearbyLocation, 0)
+	for _, location := range t.locations {
+		if !location.Latitude.Valid || !location.Longitude.Valid {
+			continue
+		}
+		distance := haversine(lat, lng, location.Latitude.Float64, location.Longitude.Float64)
+		if distance <= radius {
+			locations = append(locations, &bitsb.NearbyLocation{Location: location, Distance: distance})
+		}
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Distance != locations[j].Distance {
+			return locations[i].Distance < locations[j].Distance
+		}
+		return locations[i].ID < locations[j].ID
+	})
+	if int64(len(locations)) > limit {
+		locations = locations[:limit]
+	}
+	return locations, nil
+}
+
+// haversine returns the distance in meters between two points
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	radians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	a := math.Pow(math.Sin(radians(lat2-lat1)/2), 2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Pow(math.Sin(radians(lng2-lng1)/2), 2)
+	return earthRadius * 2 * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func (l LocationRepository) SelectDependents(ctx context.Context, id int64) (*bitsb.LocationDependents, error) {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	dependents := &bitsb.LocationDependents{
+		BusRoutes: make([]*bitsb.BusRouteSummary, 0),
+		UserIDs:   make([]int64, 0),
+		TicketIDs: make([]int64, 0),
+	}
+	for _, busRoute := range t.busRoutes {
+		if utils.IndexOf(busRoute.LocationIDS, id) != -1 {
+			dependents.BusRoutes = append(dependents.BusRoutes, &bitsb.BusRouteSummary{
+				ID:     busRoute.ID,
+				Name:   busRoute.Name,
+				Number: busRoute.Number,
+			})
+		}
+	}
+	sort.Slice(dependents.BusRoutes, func(i, j int) bool {
+		return dependents.BusRoutes[i].ID < dependents.BusRoutes[j].ID
+	})
+	for _, user := range t.users {
+		if user.HomeLocationID.ValueOrZero() == id || user.WorkLocationID.ValueOrZero() == id {
+			dependents.UserIDs = append(dependents.UserIDs, user.ID)
+		}
+	}
+	sort.Slice(dependents.UserIDs, func(i, j int) bool {
+		return dependents.UserIDs[i] < dependents.UserIDs[j]
+	})
+	for _, ticket := range t.tickets {
+		if ticket.FromLocationID == id || ticket.ToLocationID == id {
+			dependents.TicketIDs = append(dependents.TicketIDs, ticket.ID)
+		}
+	}
+	sort.Slice(dependents.TicketIDs, func(i, j int) bool {
+		return dependents.TicketIDs[i] < dependents.TicketIDs[j]
+	})
+	return dependents, nil
+}
+
+func (l LocationRepository) Insert(ctx context.Context, location *bitsb.Location) error {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	if nameTaken(t, location) {
+		return apperrors.ErrEntityAlreadyExist
+	}
+
+	currentTime := time.Now()
+	location.CreatedAt = currentTime
+	location.UpdatedAt = currentTime
+	location.ID = l.store.nextID("locations")
+	t.locations[location.ID] = *location
+	return nil
+}
+
+func (l LocationRepository) Update(ctx context.Context, location *bitsb.Location) error {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	row, ok := t.locations[location.ID]
+	if !ok {
+		return apperrors.ErrLocationNotFound
+	}
+	if nameTaken(t, location) {
+		return apperrors.ErrEntityAlreadyExist
+	}
+	row.Name = location.Name
+	row.Latitude = location.Latitude
+	row.Longitude = location.Longitude
+	row.UpdatedAt = location.UpdatedAt
+	t.locations[location.ID] = row
+	return nil
+}
+
+// nameTaken reports whether another location has the name of the location
+func nameTaken(t *tables, location *bitsb.Location) bool {
+	for _, other := range t.locations {
+		if other.Name == location.Name && other.ID != location.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func (l LocationRepository) Delete(ctx context.Context, id int64) error {
+	t, unlock := l.store.lock(ctx)
+	defer unlock()
+
+	if _, ok := t.locations[id]; !ok {
+		return apperrors.ErrLocationNotFound
+	}
+	if locationReferenced(t, id) {
+		return errForeignKey
+	}
+	delete(t.locations, id)
+	return nil
+}
+
+// locationReferenced reports whether a user or a ticket references the location
+func locationReferenced(t *tables, id int64) bool {
+	for _, user := range t.users {
+		if user.HomeLocationID.ValueOrZero() == id || user.WorkLocationID.ValueOrZero() == id {
+			return true
+		}
+	}
+	for _, ticket := range t.tickets {
+		if ticket.FromLocationID == id || ticket.ToLocationID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (l LocationRepository) DeleteDetached(ctx context.Context, id int64) error {
+	return l.store.Do(ctx, func(ctx context.Context) error {
+		t, unlock := l.store.lock(ctx)
+		defer unlock()
+
+		ids := make([]int64, 0)
+		for routeID, busRoute := range t.busRoutes {
+			if utils.IndexOf(busRoute.LocationIDS, id) != -1 {
+				ids = append(ids, routeID)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+		// booked tickets are timed and priced on the stops of their route, it is not changed under them
+		ticketIDs := make([]int64, 0)
+		for _, ticket := range t.tickets {
+			booked := ticket.Status == tickets.StatusBooked && utils.IndexOf(ids, ticket.BusRouteID) != -1
+			if ticket.FromLocationID == id || ticket.ToLocationID == id || booked {
+				ticketIDs = append(ticketIDs, ticket.ID)
+			}
+		}
+		if len(ticketIDs) > 0 {
+			sort.Slice(ticketIDs, func(i, j int) bool { return ticketIDs[i] < ticketIDs[j] })
+			return apperrors.ErrLocationTicketed.WithDetails(ticketIDs)
+		}
+
+		busRoutes := make([]bitsb.BusRoute, 0, len(ids))
+		tooShort := make([]*bitsb.BusRouteSummary, 0)
+		for _, routeID := range ids {
+			busRoute := cloneBusRoute(t.busRoutes[routeID])
+			busRoute.RemoveStop(id)
+			if len(busRoute.LocationIDS) < 2 {
+				tooShort = append(tooShort, &bitsb.BusRouteSummary{ID: busRoute.ID, Name: busRoute.Name, Number: busRoute.Number})
+			}
+			busRoutes = append(busRoutes, busRoute)
+		}
+		if len(tooShort) > 0 {
+			return apperrors.ErrTooFewStops.WithDetails(tooShort)
+		}
+		for _, busRoute := range busRoutes {
+			busRoute.UpdatedAt = time.Now()
+			t.busRoutes[busRoute.ID] = busRoute
+		}
+
+		for userID, user := range t.users {
+			if user.HomeLocationID.ValueOrZero() == id {
+				user.HomeLocationID = null.Int{}
+			}
+			if user.WorkLocationID.ValueOrZero() == id {
+				user.WorkLocationID = null.Int{}
+			}
+			t.users[userID] = user
+		}
+
+		if _, ok := t.locations[id]; !ok {
+			return apperrors.ErrLocationNotFound
+		}
+		delete(t.locations, id)
+		return nil
+	})
+}
@@ -0,0 +1,63 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/users"
+)
+
+type PasswordResetRepository struct {
+	store *Store
+}
+
+func NewPasswordResetRepository(store *Store) users.PasswordResetStorer {
+	return &PasswordResetRepository{store}
+}
+
+func (p PasswordResetRepository) Insert(ctx context.Context, reset *users.PasswordReset) error {
+	t, unlock := p.store.lock(ctx)
+	defer unlock()
+
+	if _, ok := t.users[reset.UserID]; !ok {
+		return errForeignKey
+	}
+	for _, other := range t.passwordResets {
+		if other.TokenHash == reset.TokenHash {
+			return apperrors.ErrEntityAlreadyExist
+		}
+	}
+
+	reset.CreatedAt = time.Now()
+	reset.ID = p.store.nextID("password_resets")
+	row := *reset
+	row.UsedAt = null.Time{}
+	t.passwordResets[reset.ID] = row
+	return nil
+}
+
+func (p PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (int64, error) {
+	t, unlock := p.store.lock(ctx)
+	defer unlock()
+
+	now := time.Now()
+	userID := int64(0)
+	for _, row := range t.passwordResets {
+		if row.TokenHash == tokenHash && !row.UsedAt.Valid && row.ExpiresAt.After(now) {
+			userID = row.UserID
+		}
+	}
+	if userID == 0 {
+		return 0, apperrors.ErrConflict
+	}
+	for id, row := range t.passwordResets {
+		if row.UserID == userID && !row.UsedAt.Valid {
+			row.UsedAt = null.TimeFrom(now)
+			t.passwordResets[id] = row
+		}
+	}
+	return userID, nil
+}
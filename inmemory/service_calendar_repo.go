@@ -0,0 +1,98 @@
+package inmemory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+)
+
+type ServiceCalendarRepository struct {
+	store *Store
+}
+
+func NewServiceCalendarRepository(store *Store) bitsb.ServiceCalendarStorer {
+	return &ServiceCalendarRepository{store}
+}
+
+// cloneCalendar copies the stored columns of the calendar
+func cloneCalendar(calendar bitsb.ServiceCalendar) bitsb.ServiceCalendar {
+	calendar.Weekdays = cloneSlice(calendar.Weekdays)
+	calendar.AddedDates = cloneSlice(calendar.AddedDates)
+	calendar.RemovedDates = cloneSlice(calendar.RemovedDates)
+	return calendar
+}
+
+func (c ServiceCalendarRepository) SelectAll(ctx context.Context) ([]*bitsb.ServiceCalendar, error) {
+	t, unlock := c.store.lock(ctx)
+	defer unlock()
+
+	calendars := make([]*bitsb.ServiceCalendar, 0, len(t.calendars))
+	for _, row := range t.calendars {
+		calendar := cloneCalendar(row)
+		calendars = append(calendars, &calendar)
+	}
+	sort.Slice(calendars, func(i, j int) bool {
+		return calendars[i].ID < calendars[j].ID
+	})
+	return calendars, nil
+}
+
+func (c ServiceCalendarRepository) SelectByID(ctx context.Context, id int64) (*bitsb.ServiceCalendar, error) {
+	t, unlock := c.store.lock(ctx)
+	defer unlock()
+
+	row, ok := t.calendars[id]
+	if !ok {
+		return &bitsb.ServiceCalendar{}, apperrors.ErrCalendarNotFound.Wrap(sql.ErrNoRows)
+	}
+	calendar := cloneCalendar(row)
+	return &calendar, nil
+}
+
+func (c ServiceCalendarRepository) Insert(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	t, unlock := c.store.lock(ctx)
+	defer unlock()
+
+	currentTime := time.Now()
+	calendar.CreatedAt = currentTime
+	calendar.UpdatedAt = currentTime
+	calendar.ID = c.store.nextID("service_calendars")
+	t.calendars[calendar.ID] = cloneCalendar(*calendar)
+	return nil
+}
+
+func (c ServiceCalendarRepository) Update(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	t, unlock := c.store.lock(ctx)
+	defer unlock()
+
+	calendar.UpdatedAt = time.Now()
+
+	row, ok := t.calendars[calendar.ID]
+	if !ok {
+		return apperrors.ErrCalendarNotFound
+	}
+	updated := cloneCalendar(*calendar)
+	updated.CreatedAt = row.CreatedAt
+	t.calendars[calendar.ID] = updated
+	return nil
+}
+
+func (c ServiceCalendarRepository) Delete(ctx context.Context, id int64) error {
+	t, unlock := c.store.lock(ctx)
+	defer unlock()
+
+	if _, ok := t.calendars[id]; !ok {
+		return apperrors.ErrCalendarNotFound
+	}
+	for _, busRoute := range t.busRoutes {
+		if busRoute.CalendarID.Valid && busRoute.CalendarID.Int64 == id {
+			return errForeignKey
+		}
+	}
+	delete(t.calendars, id)
+	return nil
+}
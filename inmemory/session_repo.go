@@ -0,0 +1,113 @@
+package inmemory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/users"
+)
+
+type SessionRepository struct {
+	store *Store
+}
+
+func NewSessionRepository(store *Store) users.SessionStorer {
+	return &SessionRepository{store}
+}
+
+func (s SessionRepository) SelectByID(ctx context.Context, id string) (*users.Session, error) {
+	t, unlock := s.store.lock(ctx)
+	defer unlock()
+
+	session, ok := t.sessions[id]
+	if !ok {
+		return &users.Session{}, apperrors.ErrSessionNotFound.Wrap(sql.ErrNoRows)
+	}
+	return &session, nil
+}
+
+func (s SessionRepository) SelectActiveForUser(ctx context.Context, userID int64) ([]*users.Session, error) {
+	t, unlock := s.store.lock(ctx)
+	defer unlock()
+
+	now := time.Now()
+	sessions := make([]*users.Session, 0)
+	for _, row := range t.sessions {
+		session := row
+		if session.UserID == userID && session.Active(now) {
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (s SessionRepository) Insert(ctx context.Context, session *users.Session) error {
+	t, unlock := s.store.lock(ctx)
+	defer unlock()
+
+	if _, ok := t.users[session.UserID]; !ok {
+		return errForeignKey
+	}
+	if _, ok := t.sessions[session.ID]; ok {
+		return apperrors.ErrEntityAlreadyExist
+	}
+	row := *session
+	row.RevokedAt = null.Time{}
+	t.sessions[session.ID] = row
+	return nil
+}
+
+func (s SessionRepository) Rotate(ctx context.Context, session *users.Session, tokenID string) error {
+	t, unlock := s.store.lock(ctx)
+	defer unlock()
+
+	row, ok := t.sessions[session.ID]
+	if !ok || row.TokenID != tokenID || row.RevokedAt.Valid {
+		return apperrors.ErrConflict
+	}
+	row.TokenID = session.TokenID
+	row.LastUsedAt = session.LastUsedAt
+	row.ExpiresAt = session.ExpiresAt
+	t.sessions[session.ID] = row
+	return nil
+}
+
+func (s SessionRepository) Revoke(ctx context.Context, id string) error {
+	t, unlock := s.store.lock(ctx)
+	defer unlock()
+
+	row, ok := t.sessions[id]
+	if !ok {
+		return apperrors.ErrSessionNotFound
+	}
+	if !row.RevokedAt.Valid {
+		row.RevokedAt = null.TimeFrom(time.Now())
+		t.sessions[id] = row
+	}
+	return nil
+}
+
+func (s SessionRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	t, unlock := s.store.lock(ctx)
+	defer unlock()
+
+	now := null.TimeFrom(time.Now())
+	for id, row := range t.sessions {
+		if row.UserID == userID && !row.RevokedAt.Valid {
+			row.RevokedAt = now
+			t.sessions[id] = row
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package inmemory implements the storers on maps held in memory, for running
+// the API and tests without Postgres. The storers keep the semantics of the
+// Postgres ones: ids are assigned from sequences, unique and foreign key
+// constraints are checked and fail with the errors they are parsed into,
+// and lists are filtered and paginated with the same cursors.
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/middleware"
+	"github.com/sainak/bitsb/tickets"
+	"github.com/sainak/bitsb/users"
+)
+
+var txCtxKey = &middleware.ContextKey{Name: "inmemory tx"}
+
+// errForeignKey is what a foreign key violation is parsed into
+var errForeignKey = apperrors.ErrEntityInUse
+
+// Store holds the tables of the storers, storers made from one store see the rows of
+// each other like the tables of one database. It is also the unit of work of its
+// storers, units of work run one at a time and their changes are undone when they fail.
+type Store struct {
+	mu sync.Mutex
+	t  *tables
+	// sequences are not rolled back, like the sequences of Postgres
+	sequences map[string]int64
+}
+
+type tables struct {
+	locations      map[int64]bitsb.Location
+	busRoutes      map[int64]bitsb.BusRoute
+	calendars      map[int64]bitsb.ServiceCalendar
+	users          map[int64]users.User
+	sessions       map[string]users.Session
+	tickets        map[int64]tickets.Ticket
+	passwordResets map[int64]users.PasswordReset
+}
+
+func NewStore() *Store {
+	return &Store{
+		t: &tables{
+			locations:      make(map[int64]bitsb.Location),
+			busRoutes:      make(map[int64]bitsb.BusRoute),
+			calendars:      make(map[int64]bitsb.ServiceCalendar),
+			users:          make(map[int64]users.User),
+			sessions:       make(map[string]users.Session),
+			tickets:        make(map[int64]tickets.Ticket),
+			passwordResets: make(map[int64]users.PasswordReset),
+		},
+		sequences: make(map[string]int64),
+	}
+}
+
+// Do runs fn as a unit of work of the store, see repo.UnitOfWork
+func (s *Store) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(txCtxKey) == s {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.t.clone()
+	defer func() {
+		if p := recover(); p != nil {
+			s.t = snapshot
+			panic(p)
+		}
+		if err != nil {
+			s.t = snapshot
+		}
+	}()
+	return fn(context.WithValue(ctx, txCtxKey, s))
+}
+
+// lock locks the store for a storer call, unless the call is part of a unit of work
+// that holds the lock already. It returns the tables and the function unlocking them.
+func (s *Store) lock(ctx context.Context) (*tables, func()) {
+	if ctx.Value(txCtxKey) == s {
+		return s.t, func() {}
+	}
+	s.mu.Lock()
+	return s.t, s.mu.Unlock
+}
+
+// nextID returns the next value of the sequence of the table
+func (s *Store) nextID(table string) int64 {
+	s.sequences[table]++
+	return s.sequences[table]
+}
+
+// clone copies the maps of the tables, rows are never changed in place
+// so the copy is not affected by later changes to the tables
+func (t *tables) clone() *tables {
+	return &tables{
+		locations:      cloneMap(t.locations),
+		busRoutes:      cloneMap(t.busRoutes),
+		calendars:      cloneMap(t.calendars),
+		users:          cloneMap(t.users),
+		sessions:       cloneMap(t.sessions),
+		tickets:        cloneMap(t.tickets),
+		passwordResets: cloneMap(t.passwordResets),
+	}
+}
+
+func cloneMap[K comparable, T any](m map[K]T) map[K]T {
+	c := make(map[K]T, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// cloneSlice copies values, nil is copied as an empty slice like an empty array column is read
+func cloneSlice[T any](values []T) []T {
+	return append(make([]T, 0, len(values)), values...)
+}
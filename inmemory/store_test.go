@@ -0,0 +1,24 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/sainak/bitsb/inmemory"
+	"github.com/sainak/bitsb/storertest"
+)
+
+func TestConformance(t *testing.T) {
+	storertest.Run(t, func(t *testing.T) *storertest.Storers {
+		store := inmemory.NewStore()
+		return &storertest.Storers{
+			Locations:  inmemory.NewLocationRepository(store),
+			BusRoutes:  inmemory.NewBusRouteRepository(store),
+			Calendars:  inmemory.NewServiceCalendarRepository(store),
+			Tickets:    inmemory.NewTicketRepository(store),
+			Users:      inmemory.NewUserRepository(store),
+			Sessions:   inmemory.NewSessionRepository(store),
+			Resets:     inmemory.NewPasswordResetRepository(store),
+			UnitOfWork: store,
+		}
+	})
+}
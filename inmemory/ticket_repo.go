@@ -0,0 +1,167 @@
+package inmemory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/tickets"
+)
+
+// ticketColumns are the columns tickets can be filtered on
+var ticketColumns = repo.Columns{
+	"status":       "status",
+	"bus_route_id": "bus_route_id",
+	"travel_date":  "travel_date",
+}
+
+// ticketSorts are the columns tickets can be sorted on
+var ticketSorts = repo.SortColumns{
+	"created_at": {Column: "created_at", Type: "timestamptz"},
+}
+
+var defaultTicketSort = repo.Sort{Key: "created_at", Desc: true}
+
+type TicketRepository struct {
+	store *Store
+}
+
+func NewTicketRepository(store *Store) tickets.TicketStorer {
+	return &TicketRepository{store}
+}
+
+func ticketField(ticket *tickets.Ticket) func(column string) interface{} {
+	return func(column string) interface{} {
+		switch column {
+		case "status":
+			return ticket.Status
+		case "bus_route_id":
+			return ticket.BusRouteID
+		case "travel_date":
+			return ticket.TravelDate.Time
+		}
+		return nil
+	}
+}
+
+func (t TicketRepository) SelectAllForUser(
+	ctx context.Context,
+	userID int64,
+	cursor string,
+	limit int64,
+	filters repo.Filters,
+) ([]*tickets.Ticket, repo.Page, error) {
+	// the user is part of the filters so a cursor only pages through the tickets of its user
+	paginator, err := repo.NewPaginator(cursor, limit, defaultTicketSort, ticketSorts, []interface{}{userID, filters})
+	if err != nil {
+		return []*tickets.Ticket{}, repo.Page{}, err
+	}
+
+	tables, unlock := t.store.lock(ctx)
+	defer unlock()
+
+	result := make([]*tickets.Ticket, 0)
+	for _, row := range tables.tickets {
+		ticket := row
+		if ticket.UserID != userID {
+			continue
+		}
+		match, err := filters.Match(ticketColumns, ticketField(&ticket))
+		if err != nil {
+			return []*tickets.Ticket{}, repo.Page{}, err
+		}
+		if match {
+			result = append(result, &ticket)
+		}
+	}
+
+	result, page := repo.PaginateSlice(paginator, result, func(ticket *tickets.Ticket) (string, int64) {
+		return ticket.CreatedAt.Format(time.RFC3339Nano), ticket.ID
+	})
+	return result, page, nil
+}
+
+func (t TicketRepository) SelectByID(ctx context.Context, id int64) (*tickets.Ticket, error) {
+	tables, unlock := t.store.lock(ctx)
+	defer unlock()
+
+	ticket, ok := tables.tickets[id]
+	if !ok {
+		return &tickets.Ticket{}, apperrors.ErrTicketNotFound.Wrap(sql.ErrNoRows)
+	}
+	return &ticket, nil
+}
+
+func (t TicketRepository) Insert(ctx context.Context, ticket *tickets.Ticket) error {
+	tables, unlock := t.store.lock(ctx)
+	defer unlock()
+
+	_, userFound := tables.users[ticket.UserID]
+	_, busRouteFound := tables.busRoutes[ticket.BusRouteID]
+	_, fromFound := tables.locations[ticket.FromLocationID]
+	_, toFound := tables.locations[ticket.ToLocationID]
+	if !userFound || !busRouteFound || !fromFound || !toFound {
+		return errForeignKey
+	}
+
+	currentTime := time.Now()
+	ticket.CreatedAt = currentTime
+	ticket.UpdatedAt = currentTime
+	ticket.ID = t.store.nextID("tickets")
+	row := *ticket
+	row.CancelledAt = null.Time{}
+	row.UsedAt = null.Time{}
+	row.Pass = ""
+	tables.tickets[ticket.ID] = row
+	return nil
+}
+
+// MarkCancelled marks a booked ticket as cancelled, it fails with apperrors.ErrConflict
+// when the ticket is no longer booked so a used ticket cannot be cancelled
+func (t TicketRepository) MarkCancelled(ctx context.Context, ticket *tickets.Ticket) error {
+	tables, unlock := t.store.lock(ctx)
+	defer unlock()
+
+	row, ok := tables.tickets[ticket.ID]
+	if !ok || row.Status != tickets.StatusBooked {
+		return apperrors.ErrConflict
+	}
+
+	currentTime := time.Now()
+	row.Status = tickets.StatusCancelled
+	row.CancelledAt = null.TimeFrom(currentTime)
+	row.UpdatedAt = currentTime
+	tables.tickets[ticket.ID] = row
+
+	ticket.Status = row.Status
+	ticket.CancelledAt = row.CancelledAt
+	ticket.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+// MarkUsed marks a booked ticket as used, it fails with apperrors.ErrConflict
+// when the ticket is no longer booked so a pass can only be used once
+func (t TicketRepository) MarkUsed(ctx context.Context, ticket *tickets.Ticket) error {
+	tables, unlock := t.store.lock(ctx)
+	defer unlock()
+
+	row, ok := tables.tickets[ticket.ID]
+	if !ok || row.Status != tickets.StatusBooked {
+		return apperrors.ErrConflict
+	}
+
+	currentTime := time.Now()
+	row.Status = tickets.StatusUsed
+	row.UsedAt = null.TimeFrom(currentTime)
+	row.UpdatedAt = currentTime
+	tables.tickets[ticket.ID] = row
+
+	ticket.Status = row.Status
+	ticket.UsedAt = row.UsedAt
+	ticket.UpdatedAt = row.UpdatedAt
+	return nil
+}
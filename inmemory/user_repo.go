@@ -0,0 +1,144 @@
+package inmemory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/users"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) users.UserStorer {
+	return &UserRepository{store}
+}
+
+func (u UserRepository) SelectByID(ctx context.Context, id int64) (users.User, error) {
+	t, unlock := u.store.lock(ctx)
+	defer unlock()
+
+	user, ok := t.users[id]
+	if !ok {
+		return users.User{}, apperrors.ErrUserNotFound.Wrap(sql.ErrNoRows)
+	}
+	return user, nil
+}
+
+func (u UserRepository) SelectByEmail(ctx context.Context, email string) (users.User, error) {
+	t, unlock := u.store.lock(ctx)
+	defer unlock()
+
+	for _, user := range t.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return users.User{}, apperrors.ErrUserNotFound.Wrap(sql.ErrNoRows)
+}
+
+// Insert stores the columns the Postgres storer inserts, the verification and last login are not set
+func (u UserRepository) Insert(ctx context.Context, user *users.User) error {
+	t, unlock := u.store.lock(ctx)
+	defer unlock()
+
+	if emailTaken(t, user) {
+		return apperrors.ErrEntityAlreadyExist
+	}
+	if !commuteLocationsFound(t, user) {
+		return errForeignKey
+	}
+
+	currentTime := time.Now()
+	user.CreatedAt = currentTime
+	user.UpdatedAt = currentTime
+	user.ID = u.store.nextID("users")
+	t.users[user.ID] = users.User{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Access:    user.Access,
+		Password:  user.Password,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+
+		HomeLocationID: user.HomeLocationID,
+		WorkLocationID: user.WorkLocationID,
+	}
+	return nil
+}
+
+// Update changes the columns the Postgres storer updates, the access level is kept
+func (u UserRepository) Update(ctx context.Context, user *users.User) error {
+	t, unlock := u.store.lock(ctx)
+	defer unlock()
+
+	user.UpdatedAt = time.Now()
+
+	row, ok := t.users[user.ID]
+	if !ok {
+		return apperrors.ErrUserNotFound.Wrap(sql.ErrNoRows)
+	}
+	if emailTaken(t, user) {
+		return apperrors.ErrEntityAlreadyExist
+	}
+	if !commuteLocationsFound(t, user) {
+		return errForeignKey
+	}
+	row.Email = user.Email
+	row.FirstName = user.FirstName
+	row.LastName = user.LastName
+	row.Password = user.Password
+	row.LastLogin = user.LastLogin
+	row.UpdatedAt = user.UpdatedAt
+	row.HomeLocationID = user.HomeLocationID
+	row.WorkLocationID = user.WorkLocationID
+	t.users[user.ID] = row
+	return nil
+}
+
+func (u UserRepository) MarkVerified(ctx context.Context, user *users.User) error {
+	t, unlock := u.store.lock(ctx)
+	defer unlock()
+
+	row, ok := t.users[user.ID]
+	if !ok {
+		return apperrors.ErrUserNotFound.Wrap(sql.ErrNoRows)
+	}
+	currentTime := time.Now()
+	if !row.VerifiedAt.Valid {
+		row.VerifiedAt = null.TimeFrom(currentTime)
+	}
+	row.UpdatedAt = currentTime
+	t.users[user.ID] = row
+
+	user.VerifiedAt = row.VerifiedAt
+	user.UpdatedAt = row.UpdatedAt
+	return nil
+}
+
+// emailTaken reports whether another user has the email of the user
+func emailTaken(t *tables, user *users.User) bool {
+	for _, other := range t.users {
+		if other.Email == user.Email && other.ID != user.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// commuteLocationsFound reports whether the home and work locations of the user exist
+func commuteLocationsFound(t *tables, user *users.User) bool {
+	for _, id := range []null.Int{user.HomeLocationID, user.WorkLocationID} {
+		if _, ok := t.locations[id.Int64]; id.Valid && !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,3 @@
+ALTER TABLE locations
+    DROP COLUMN IF EXISTS latitude,
+    DROP COLUMN IF EXISTS longitude;
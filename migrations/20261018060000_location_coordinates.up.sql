@@ -0,0 +1,3 @@
+ALTER TABLE locations
+    ADD COLUMN latitude  DOUBLE PRECISION NULL CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN longitude DOUBLE PRECISION NULL CHECK (longitude BETWEEN -180 AND 180);
@@ -0,0 +1,3 @@
+ALTER TABLE bus_routes
+    DROP COLUMN IF EXISTS segment_durations,
+    DROP COLUMN IF EXISTS segment_distances;
@@ -0,0 +1,3 @@
+ALTER TABLE bus_routes
+    ADD COLUMN segment_durations INTEGER[] DEFAULT '{}' NOT NULL,
+    ADD COLUMN segment_distances INTEGER[] DEFAULT '{}' NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "idx_bus_routes_calendar_id";
+
+ALTER TABLE bus_routes
+    DROP COLUMN IF EXISTS calendar_id;
+
+DROP TABLE IF EXISTS "service_calendars";
@@ -0,0 +1,16 @@
+CREATE TABLE service_calendars
+(
+    id            SERIAL PRIMARY KEY     NOT NULL,
+    name          VARCHAR(100)           NOT NULL,
+    weekdays      INTEGER[] DEFAULT '{}' NOT NULL,
+    start_date    DATE                   NULL,
+    end_date      DATE                   NULL,
+    added_dates   DATE[]    DEFAULT '{}' NOT NULL,
+    removed_dates DATE[]    DEFAULT '{}' NOT NULL,
+    created_at    TIMESTAMPTZ            NOT NULL,
+    updated_at    TIMESTAMPTZ            NOT NULL
+);
+
+ALTER TABLE bus_routes
+    ADD COLUMN calendar_id INTEGER REFERENCES service_calendars (id) NULL;
+CREATE INDEX idx_bus_routes_calendar_id ON bus_routes (calendar_id);
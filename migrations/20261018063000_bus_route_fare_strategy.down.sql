@@ -0,0 +1,3 @@
+ALTER TABLE bus_routes
+    DROP COLUMN IF EXISTS fare_strategy,
+    DROP COLUMN IF EXISTS fare_config;
@@ -0,0 +1,3 @@
+ALTER TABLE bus_routes
+    ADD COLUMN fare_strategy VARCHAR(20) DEFAULT 'per_stop' NOT NULL,
+    ADD COLUMN fare_config JSONB DEFAULT '{}' NOT NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE tickets
+(
+    id               SERIAL PRIMARY KEY                 NOT NULL,
+    user_id          INTEGER REFERENCES users (id)      NOT NULL,
+    bus_route_id     INTEGER REFERENCES bus_routes (id) NOT NULL,
+    from_location_id INTEGER REFERENCES locations (id)  NOT NULL,
+    to_location_id   INTEGER REFERENCES locations (id)  NOT NULL,
+    travel_date      DATE                               NOT NULL,
+    departure_time   TIME                               NOT NULL,
+    arrival_time     TIME                               NOT NULL,
+    price            INTEGER                            NOT NULL,
+    status           VARCHAR(20) DEFAULT 'booked'       NOT NULL,
+    cancelled_at     TIMESTAMPTZ                        NULL,
+    created_at       TIMESTAMPTZ                        NOT NULL,
+    updated_at       TIMESTAMPTZ                        NOT NULL
+);
+CREATE INDEX idx_tickets_user_id_created_at ON tickets (user_id, created_at);
+CREATE INDEX idx_tickets_bus_route_id ON tickets (bus_route_id);
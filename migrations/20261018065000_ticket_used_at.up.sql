@@ -0,0 +1,2 @@
+ALTER TABLE tickets
+    ADD COLUMN used_at TIMESTAMPTZ NULL;
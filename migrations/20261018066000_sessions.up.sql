@@ -0,0 +1,11 @@
+CREATE TABLE sessions
+(
+    id           VARCHAR(32) PRIMARY KEY                           NOT NULL,
+    user_id      INTEGER REFERENCES users (id) ON DELETE CASCADE   NOT NULL,
+    token_id     VARCHAR(32)                                       NOT NULL,
+    created_at   TIMESTAMPTZ                                       NOT NULL,
+    last_used_at TIMESTAMPTZ                                       NOT NULL,
+    expires_at   TIMESTAMPTZ                                       NOT NULL,
+    revoked_at   TIMESTAMPTZ                                       NULL
+);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
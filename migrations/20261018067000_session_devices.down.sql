@@ -0,0 +1,5 @@
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+DROP INDEX IF EXISTS idx_sessions_user_id_last_used_at;
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip;
@@ -0,0 +1,5 @@
+ALTER TABLE sessions
+    ADD COLUMN user_agent TEXT        DEFAULT '' NOT NULL,
+    ADD COLUMN ip         VARCHAR(45) DEFAULT '' NOT NULL;
+CREATE INDEX idx_sessions_user_id_last_used_at ON sessions (user_id, last_used_at);
+DROP INDEX IF EXISTS idx_sessions_user_id;
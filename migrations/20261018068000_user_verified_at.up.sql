@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN verified_at TIMESTAMPTZ NULL;
+-- accounts made before verification existed are trusted
+UPDATE users
+SET verified_at = created_at;
@@ -0,0 +1,10 @@
+CREATE TABLE password_resets
+(
+    id         SERIAL PRIMARY KEY                              NOT NULL,
+    user_id    INTEGER REFERENCES users (id) ON DELETE CASCADE NOT NULL,
+    token_hash CHAR(64) UNIQUE                                 NOT NULL,
+    created_at TIMESTAMPTZ                                     NOT NULL,
+    expires_at TIMESTAMPTZ                                     NOT NULL,
+    used_at    TIMESTAMPTZ                                     NULL
+);
+CREATE INDEX idx_password_resets_user_id ON password_resets (user_id);
@@ -0,0 +1,8 @@
+-- stop times of trips running past midnight fall on the days after the travel date
+ALTER TABLE tickets
+    ADD COLUMN departure_day_offset SMALLINT DEFAULT 0 NOT NULL,
+    ADD COLUMN arrival_day_offset   SMALLINT DEFAULT 0 NOT NULL;
+-- tickets of trips arriving past midnight
+UPDATE tickets
+SET arrival_day_offset = 1
+WHERE arrival_time < departure_time;
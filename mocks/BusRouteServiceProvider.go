@@ -8,6 +8,10 @@ import (
 	bitsb "github.com/sainak/bitsb/bitsb"
 
 	mock "github.com/stretchr/testify/mock"
+
+	repo "github.com/sainak/bitsb/pkg/repo"
+
+	time "time"
 )
 
 // BusRouteServiceProvider is an autogenerated mock type for the BusRouteServiceProvider type
@@ -16,14 +20,16 @@ type BusRouteServiceProvider struct {
 }
 
 // CalculateTicketPrice provides a mock function with given fields: ctx, id, start, end
-func (_m *BusRouteServiceProvider) CalculateTicketPrice(ctx context.Context, id int64, start int64, end int64) (int64, error) {
+func (_m *BusRouteServiceProvider) CalculateTicketPrice(ctx context.Context, id int64, start int64, end int64) (*bitsb.Fare, error) {
 	ret := _m.Called(ctx, id, start, end)
 
-	var r0 int64
-	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64) int64); ok {
+	var r0 *bitsb.Fare
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, int64) *bitsb.Fare); ok {
 		r0 = rf(ctx, id, start, end)
 	} else {
-		r0 = ret.Get(0).(int64)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitsb.Fare)
+		}
 	}
 
 	var r1 error
@@ -64,6 +70,29 @@ func (_m *BusRouteServiceProvider) Delete(ctx context.Context, id int64) error {
 	return r0
 }
 
+// Departures provides a mock function with given fields: ctx, locationID, date, after, limit
+func (_m *BusRouteServiceProvider) Departures(ctx context.Context, locationID int64, date bitsb.Date, after time.Time, limit int64) ([]*bitsb.Departure, error) {
+	ret := _m.Called(ctx, locationID, date, after, limit)
+
+	var r0 []*bitsb.Departure
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bitsb.Date, time.Time, int64) []*bitsb.Departure); ok {
+		r0 = rf(ctx, locationID, date, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bitsb.Departure)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, bitsb.Date, time.Time, int64) error); ok {
+		r1 = rf(ctx, locationID, date, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByID provides a mock function with given fields: ctx, id
 func (_m *BusRouteServiceProvider) GetByID(ctx context.Context, id int64) (*bitsb.BusRoute, error) {
 	ret := _m.Called(ctx, id)
@@ -87,29 +116,29 @@ func (_m *BusRouteServiceProvider) GetByID(ctx context.Context, id int64) (*bits
 	return r0, r1
 }
 
-// ListAll provides a mock function with given fields: ctx, cursor, limit, locations
-func (_m *BusRouteServiceProvider) ListAll(ctx context.Context, cursor string, limit int64, locations []int64) ([]*bitsb.BusRoute, string, error) {
-	ret := _m.Called(ctx, cursor, limit, locations)
+// ListAll provides a mock function with given fields: ctx, cursor, limit, filters, sort
+func (_m *BusRouteServiceProvider) ListAll(ctx context.Context, cursor string, limit int64, filters bitsb.BusRouteFilters, sort repo.Sort) ([]*bitsb.BusRoute, repo.Page, error) {
+	ret := _m.Called(ctx, cursor, limit, filters, sort)
 
 	var r0 []*bitsb.BusRoute
-	if rf, ok := ret.Get(0).(func(context.Context, string, int64, []int64) []*bitsb.BusRoute); ok {
-		r0 = rf(ctx, cursor, limit, locations)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, bitsb.BusRouteFilters, repo.Sort) []*bitsb.BusRoute); ok {
+		r0 = rf(ctx, cursor, limit, filters, sort)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*bitsb.BusRoute)
 		}
 	}
 
-	var r1 string
-	if rf, ok := ret.Get(1).(func(context.Context, string, int64, []int64) string); ok {
-		r1 = rf(ctx, cursor, limit, locations)
+	var r1 repo.Page
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, bitsb.BusRouteFilters, repo.Sort) repo.Page); ok {
+		r1 = rf(ctx, cursor, limit, filters, sort)
 	} else {
-		r1 = ret.Get(1).(string)
+		r1 = ret.Get(1).(repo.Page)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(context.Context, string, int64, []int64) error); ok {
-		r2 = rf(ctx, cursor, limit, locations)
+	if rf, ok := ret.Get(2).(func(context.Context, string, int64, bitsb.BusRouteFilters, repo.Sort) error); ok {
+		r2 = rf(ctx, cursor, limit, filters, sort)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -117,6 +146,29 @@ func (_m *BusRouteServiceProvider) ListAll(ctx context.Context, cursor string, l
 	return r0, r1, r2
 }
 
+// Timetable provides a mock function with given fields: ctx, id, date
+func (_m *BusRouteServiceProvider) Timetable(ctx context.Context, id int64, date bitsb.Date) (*bitsb.Timetable, error) {
+	ret := _m.Called(ctx, id, date)
+
+	var r0 *bitsb.Timetable
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bitsb.Date) *bitsb.Timetable); ok {
+		r0 = rf(ctx, id, date)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitsb.Timetable)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, bitsb.Date) error); ok {
+		r1 = rf(ctx, id, date)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, busRoute
 func (_m *BusRouteServiceProvider) Update(ctx context.Context, busRoute *bitsb.BusRoute) error {
 	ret := _m.Called(ctx, busRoute)
@@ -8,6 +8,8 @@ import (
 	bitsb "github.com/sainak/bitsb/bitsb"
 
 	mock "github.com/stretchr/testify/mock"
+
+	repo "github.com/sainak/bitsb/pkg/repo"
 )
 
 // BusRouteStorer is an autogenerated mock type for the BusRouteStorer type
@@ -43,29 +45,29 @@ func (_m *BusRouteStorer) Insert(ctx context.Context, busRoute *bitsb.BusRoute)
 	return r0
 }
 
-// SelectAll provides a mock function with given fields: ctx, cursor, limit, locations
-func (_m *BusRouteStorer) SelectAll(ctx context.Context, cursor string, limit int64, locations []int64) ([]*bitsb.BusRoute, string, error) {
-	ret := _m.Called(ctx, cursor, limit, locations)
+// SelectAll provides a mock function with given fields: ctx, cursor, limit, filters, sort
+func (_m *BusRouteStorer) SelectAll(ctx context.Context, cursor string, limit int64, filters bitsb.BusRouteFilters, sort repo.Sort) ([]*bitsb.BusRoute, repo.Page, error) {
+	ret := _m.Called(ctx, cursor, limit, filters, sort)
 
 	var r0 []*bitsb.BusRoute
-	if rf, ok := ret.Get(0).(func(context.Context, string, int64, []int64) []*bitsb.BusRoute); ok {
-		r0 = rf(ctx, cursor, limit, locations)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, bitsb.BusRouteFilters, repo.Sort) []*bitsb.BusRoute); ok {
+		r0 = rf(ctx, cursor, limit, filters, sort)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*bitsb.BusRoute)
 		}
 	}
 
-	var r1 string
-	if rf, ok := ret.Get(1).(func(context.Context, string, int64, []int64) string); ok {
-		r1 = rf(ctx, cursor, limit, locations)
+	var r1 repo.Page
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, bitsb.BusRouteFilters, repo.Sort) repo.Page); ok {
+		r1 = rf(ctx, cursor, limit, filters, sort)
 	} else {
-		r1 = ret.Get(1).(string)
+		r1 = ret.Get(1).(repo.Page)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(context.Context, string, int64, []int64) error); ok {
-		r2 = rf(ctx, cursor, limit, locations)
+	if rf, ok := ret.Get(2).(func(context.Context, string, int64, bitsb.BusRouteFilters, repo.Sort) error); ok {
+		r2 = rf(ctx, cursor, limit, filters, sort)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -96,6 +98,29 @@ func (_m *BusRouteStorer) SelectByID(ctx context.Context, id int64) (*bitsb.BusR
 	return r0, r1
 }
 
+// SelectByLocations provides a mock function with given fields: ctx, locations
+func (_m *BusRouteStorer) SelectByLocations(ctx context.Context, locations []int64) ([]*bitsb.BusRoute, error) {
+	ret := _m.Called(ctx, locations)
+
+	var r0 []*bitsb.BusRoute
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []*bitsb.BusRoute); ok {
+		r0 = rf(ctx, locations)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bitsb.BusRoute)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) error); ok {
+		r1 = rf(ctx, locations)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, busRoute
 func (_m *BusRouteStorer) Update(ctx context.Context, busRoute *bitsb.BusRoute) error {
 	ret := _m.Called(ctx, busRoute)
@@ -0,0 +1,65 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bitsb "github.com/sainak/bitsb/bitsb"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// FareStrategy is an autogenerated mock type for the FareStrategy type
+type FareStrategy struct {
+	mock.Mock
+}
+
+// Calculate provides a mock function with given fields: busRoute, start, end
+func (_m *FareStrategy) Calculate(busRoute *bitsb.BusRoute, start int, end int) (*bitsb.Fare, error) {
+	ret := _m.Called(busRoute, start, end)
+
+	var r0 *bitsb.Fare
+	if rf, ok := ret.Get(0).(func(*bitsb.BusRoute, int, int) *bitsb.Fare); ok {
+		r0 = rf(busRoute, start, end)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitsb.Fare)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*bitsb.BusRoute, int, int) error); ok {
+		r1 = rf(busRoute, start, end)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Validate provides a mock function with given fields: busRoute
+func (_m *FareStrategy) Validate(busRoute *bitsb.BusRoute) error {
+	ret := _m.Called(busRoute)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*bitsb.BusRoute) error); ok {
+		r0 = rf(busRoute)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewFareStrategy interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewFareStrategy creates a new instance of FareStrategy. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewFareStrategy(t mockConstructorTestingTNewFareStrategy) *FareStrategy {
+	mock := &FareStrategy{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,56 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	bitsb "github.com/sainak/bitsb/bitsb"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// JourneyServiceProvider is an autogenerated mock type for the JourneyServiceProvider type
+type JourneyServiceProvider struct {
+	mock.Mock
+}
+
+// Plan provides a mock function with given fields: ctx, from, to, departAt
+func (_m *JourneyServiceProvider) Plan(ctx context.Context, from int64, to int64, departAt time.Time) ([]*bitsb.Journey, error) {
+	ret := _m.Called(ctx, from, to, departAt)
+
+	var r0 []*bitsb.Journey
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, time.Time) []*bitsb.Journey); ok {
+		r0 = rf(ctx, from, to, departAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bitsb.Journey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, time.Time) error); ok {
+		r1 = rf(ctx, from, to, departAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewJourneyServiceProvider interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewJourneyServiceProvider creates a new instance of JourneyServiceProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewJourneyServiceProvider(t mockConstructorTestingTNewJourneyServiceProvider) *JourneyServiceProvider {
+	mock := &JourneyServiceProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
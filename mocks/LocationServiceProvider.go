@@ -31,13 +31,13 @@ func (_m *LocationServiceProvider) Create(ctx context.Context, location *bitsb.L
 	return r0
 }
 
-// Delete provides a mock function with given fields: ctx, id
-func (_m *LocationServiceProvider) Delete(ctx context.Context, id int64) error {
-	ret := _m.Called(ctx, id)
+// Delete provides a mock function with given fields: ctx, id, detach
+func (_m *LocationServiceProvider) Delete(ctx context.Context, id int64, detach bool) error {
+	ret := _m.Called(ctx, id, detach)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
-		r0 = rf(ctx, id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) error); ok {
+		r0 = rf(ctx, id, detach)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -69,7 +69,7 @@ func (_m *LocationServiceProvider) GetByID(ctx context.Context, id int64) (*bits
 }
 
 // ListAll provides a mock function with given fields: ctx, cursor, limit, filters
-func (_m *LocationServiceProvider) ListAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*bitsb.Location, string, error) {
+func (_m *LocationServiceProvider) ListAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*bitsb.Location, repo.Page, error) {
 	ret := _m.Called(ctx, cursor, limit, filters)
 
 	var r0 []*bitsb.Location
@@ -81,11 +81,11 @@ func (_m *LocationServiceProvider) ListAll(ctx context.Context, cursor string, l
 		}
 	}
 
-	var r1 string
-	if rf, ok := ret.Get(1).(func(context.Context, string, int64, repo.Filters) string); ok {
+	var r1 repo.Page
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, repo.Filters) repo.Page); ok {
 		r1 = rf(ctx, cursor, limit, filters)
 	} else {
-		r1 = ret.Get(1).(string)
+		r1 = ret.Get(1).(repo.Page)
 	}
 
 	var r2 error
@@ -98,6 +98,29 @@ func (_m *LocationServiceProvider) ListAll(ctx context.Context, cursor string, l
 	return r0, r1, r2
 }
 
+// ListNearby provides a mock function with given fields: ctx, lat, lng, radius, limit
+func (_m *LocationServiceProvider) ListNearby(ctx context.Context, lat float64, lng float64, radius float64, limit int64) ([]*bitsb.NearbyLocation, error) {
+	ret := _m.Called(ctx, lat, lng, radius, limit)
+
+	var r0 []*bitsb.NearbyLocation
+	if rf, ok := ret.Get(0).(func(context.Context, float64, float64, float64, int64) []*bitsb.NearbyLocation); ok {
+		r0 = rf(ctx, lat, lng, radius, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bitsb.NearbyLocation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, float64, float64, float64, int64) error); ok {
+		r1 = rf(ctx, lat, lng, radius, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, location
 func (_m *LocationServiceProvider) Update(ctx context.Context, location *bitsb.Location) error {
 	ret := _m.Called(ctx, location)
@@ -31,6 +31,20 @@ func (_m *LocationStorer) Delete(ctx context.Context, id int64) error {
 	return r0
 }
 
+// DeleteDetached provides a mock function with given fields: ctx, id
+func (_m *LocationStorer) DeleteDetached(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Insert provides a mock function with given fields: ctx, location
 func (_m *LocationStorer) Insert(ctx context.Context, location *bitsb.Location) error {
 	ret := _m.Called(ctx, location)
@@ -46,7 +60,7 @@ func (_m *LocationStorer) Insert(ctx context.Context, location *bitsb.Location)
 }
 
 // SelectAll provides a mock function with given fields: ctx, cursor, limit, filters
-func (_m *LocationStorer) SelectAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*bitsb.Location, string, error) {
+func (_m *LocationStorer) SelectAll(ctx context.Context, cursor string, limit int64, filters repo.Filters) ([]*bitsb.Location, repo.Page, error) {
 	ret := _m.Called(ctx, cursor, limit, filters)
 
 	var r0 []*bitsb.Location
@@ -58,11 +72,11 @@ func (_m *LocationStorer) SelectAll(ctx context.Context, cursor string, limit in
 		}
 	}
 
-	var r1 string
-	if rf, ok := ret.Get(1).(func(context.Context, string, int64, repo.Filters) string); ok {
+	var r1 repo.Page
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, repo.Filters) repo.Page); ok {
 		r1 = rf(ctx, cursor, limit, filters)
 	} else {
-		r1 = ret.Get(1).(string)
+		r1 = ret.Get(1).(repo.Page)
 	}
 
 	var r2 error
@@ -121,6 +135,52 @@ func (_m *LocationStorer) SelectByIDArray(ctx context.Context, ids []int64) ([]*
 	return r0, r1
 }
 
+// SelectDependents provides a mock function with given fields: ctx, id
+func (_m *LocationStorer) SelectDependents(ctx context.Context, id int64) (*bitsb.LocationDependents, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *bitsb.LocationDependents
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *bitsb.LocationDependents); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitsb.LocationDependents)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SelectNearby provides a mock function with given fields: ctx, lat, lng, radius, limit
+func (_m *LocationStorer) SelectNearby(ctx context.Context, lat float64, lng float64, radius float64, limit int64) ([]*bitsb.NearbyLocation, error) {
+	ret := _m.Called(ctx, lat, lng, radius, limit)
+
+	var r0 []*bitsb.NearbyLocation
+	if rf, ok := ret.Get(0).(func(context.Context, float64, float64, float64, int64) []*bitsb.NearbyLocation); ok {
+		r0 = rf(ctx, lat, lng, radius, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bitsb.NearbyLocation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, float64, float64, float64, int64) error); ok {
+		r1 = rf(ctx, lat, lng, radius, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, location
 func (_m *LocationStorer) Update(ctx context.Context, location *bitsb.Location) error {
 	ret := _m.Called(ctx, location)
@@ -0,0 +1,65 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	users "github.com/sainak/bitsb/users"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// PasswordResetStorer is an autogenerated mock type for the PasswordResetStorer type
+type PasswordResetStorer struct {
+	mock.Mock
+}
+
+// Consume provides a mock function with given fields: ctx, tokenHash
+func (_m *PasswordResetStorer) Consume(ctx context.Context, tokenHash string) (int64, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Insert provides a mock function with given fields: ctx, reset
+func (_m *PasswordResetStorer) Insert(ctx context.Context, reset *users.PasswordReset) error {
+	ret := _m.Called(ctx, reset)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *users.PasswordReset) error); ok {
+		r0 = rf(ctx, reset)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewPasswordResetStorer interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewPasswordResetStorer creates a new instance of PasswordResetStorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewPasswordResetStorer(t mockConstructorTestingTNewPasswordResetStorer) *PasswordResetStorer {
+	mock := &PasswordResetStorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
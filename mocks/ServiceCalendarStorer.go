@@ -0,0 +1,119 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	bitsb "github.com/sainak/bitsb/bitsb"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ServiceCalendarStorer is an autogenerated mock type for the ServiceCalendarStorer type
+type ServiceCalendarStorer struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *ServiceCalendarStorer) Delete(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Insert provides a mock function with given fields: ctx, calendar
+func (_m *ServiceCalendarStorer) Insert(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	ret := _m.Called(ctx, calendar)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *bitsb.ServiceCalendar) error); ok {
+		r0 = rf(ctx, calendar)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SelectAll provides a mock function with given fields: ctx
+func (_m *ServiceCalendarStorer) SelectAll(ctx context.Context) ([]*bitsb.ServiceCalendar, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*bitsb.ServiceCalendar
+	if rf, ok := ret.Get(0).(func(context.Context) []*bitsb.ServiceCalendar); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bitsb.ServiceCalendar)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SelectByID provides a mock function with given fields: ctx, id
+func (_m *ServiceCalendarStorer) SelectByID(ctx context.Context, id int64) (*bitsb.ServiceCalendar, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *bitsb.ServiceCalendar
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *bitsb.ServiceCalendar); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bitsb.ServiceCalendar)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, calendar
+func (_m *ServiceCalendarStorer) Update(ctx context.Context, calendar *bitsb.ServiceCalendar) error {
+	ret := _m.Called(ctx, calendar)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *bitsb.ServiceCalendar) error); ok {
+		r0 = rf(ctx, calendar)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewServiceCalendarStorer interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewServiceCalendarStorer creates a new instance of ServiceCalendarStorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewServiceCalendarStorer(t mockConstructorTestingTNewServiceCalendarStorer) *ServiceCalendarStorer {
+	mock := &ServiceCalendarStorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
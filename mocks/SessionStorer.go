@@ -0,0 +1,132 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	users "github.com/sainak/bitsb/users"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// SessionStorer is an autogenerated mock type for the SessionStorer type
+type SessionStorer struct {
+	mock.Mock
+}
+
+// Insert provides a mock function with given fields: ctx, session
+func (_m *SessionStorer) Insert(ctx context.Context, session *users.Session) error {
+	ret := _m.Called(ctx, session)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *users.Session) error); ok {
+		r0 = rf(ctx, session)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Revoke provides a mock function with given fields: ctx, id
+func (_m *SessionStorer) Revoke(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeAllForUser provides a mock function with given fields: ctx, userID
+func (_m *SessionStorer) RevokeAllForUser(ctx context.Context, userID int64) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rotate provides a mock function with given fields: ctx, session, tokenID
+func (_m *SessionStorer) Rotate(ctx context.Context, session *users.Session, tokenID string) error {
+	ret := _m.Called(ctx, session, tokenID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *users.Session, string) error); ok {
+		r0 = rf(ctx, session, tokenID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SelectActiveForUser provides a mock function with given fields: ctx, userID
+func (_m *SessionStorer) SelectActiveForUser(ctx context.Context, userID int64) ([]*users.Session, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*users.Session
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*users.Session); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*users.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SelectByID provides a mock function with given fields: ctx, id
+func (_m *SessionStorer) SelectByID(ctx context.Context, id string) (*users.Session, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *users.Session
+	if rf, ok := ret.Get(0).(func(context.Context, string) *users.Session); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*users.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewSessionStorer interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewSessionStorer creates a new instance of SessionStorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewSessionStorer(t mockConstructorTestingTNewSessionStorer) *SessionStorer {
+	mock := &SessionStorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
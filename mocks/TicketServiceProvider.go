@@ -0,0 +1,161 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	repo "github.com/sainak/bitsb/pkg/repo"
+	mock "github.com/stretchr/testify/mock"
+
+	tickets "github.com/sainak/bitsb/tickets"
+)
+
+// TicketServiceProvider is an autogenerated mock type for the TicketServiceProvider type
+type TicketServiceProvider struct {
+	mock.Mock
+}
+
+// Book provides a mock function with given fields: ctx, ticket
+func (_m *TicketServiceProvider) Book(ctx context.Context, ticket *tickets.Ticket) error {
+	ret := _m.Called(ctx, ticket)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *tickets.Ticket) error); ok {
+		r0 = rf(ctx, ticket)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Cancel provides a mock function with given fields: ctx, userID, id
+func (_m *TicketServiceProvider) Cancel(ctx context.Context, userID int64, id int64) (*tickets.Ticket, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 *tickets.Ticket
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *tickets.Ticket); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tickets.Ticket)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetForUser provides a mock function with given fields: ctx, userID, id
+func (_m *TicketServiceProvider) GetForUser(ctx context.Context, userID int64, id int64) (*tickets.Ticket, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 *tickets.Ticket
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) *tickets.Ticket); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tickets.Ticket)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListForUser provides a mock function with given fields: ctx, userID, cursor, limit, filters
+func (_m *TicketServiceProvider) ListForUser(ctx context.Context, userID int64, cursor string, limit int64, filters repo.Filters) ([]*tickets.Ticket, repo.Page, error) {
+	ret := _m.Called(ctx, userID, cursor, limit, filters)
+
+	var r0 []*tickets.Ticket
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, repo.Filters) []*tickets.Ticket); ok {
+		r0 = rf(ctx, userID, cursor, limit, filters)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*tickets.Ticket)
+		}
+	}
+
+	var r1 repo.Page
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int64, repo.Filters) repo.Page); ok {
+		r1 = rf(ctx, userID, cursor, limit, filters)
+	} else {
+		r1 = ret.Get(1).(repo.Page)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, int64, string, int64, repo.Filters) error); ok {
+		r2 = rf(ctx, userID, cursor, limit, filters)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// PassKey provides a mock function with given fields:
+func (_m *TicketServiceProvider) PassKey() *tickets.PassKey {
+	ret := _m.Called()
+
+	var r0 *tickets.PassKey
+	if rf, ok := ret.Get(0).(func() *tickets.PassKey); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tickets.PassKey)
+		}
+	}
+
+	return r0
+}
+
+// Validate provides a mock function with given fields: ctx, pass, busRouteID
+func (_m *TicketServiceProvider) Validate(ctx context.Context, pass string, busRouteID int64) (*tickets.Ticket, error) {
+	ret := _m.Called(ctx, pass, busRouteID)
+
+	var r0 *tickets.Ticket
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *tickets.Ticket); ok {
+		r0 = rf(ctx, pass, busRouteID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tickets.Ticket)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, pass, busRouteID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewTicketServiceProvider interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewTicketServiceProvider creates a new instance of TicketServiceProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewTicketServiceProvider(t mockConstructorTestingTNewTicketServiceProvider) *TicketServiceProvider {
+	mock := &TicketServiceProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,127 @@
+// Code generated by mockery v2.18.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	repo "github.com/sainak/bitsb/pkg/repo"
+	mock "github.com/stretchr/testify/mock"
+
+	tickets "github.com/sainak/bitsb/tickets"
+)
+
+// TicketStorer is an autogenerated mock type for the TicketStorer type
+type TicketStorer struct {
+	mock.Mock
+}
+
+// Insert provides a mock function with given fields: ctx, ticket
+func (_m *TicketStorer) Insert(ctx context.Context, ticket *tickets.Ticket) error {
+	ret := _m.Called(ctx, ticket)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *tickets.Ticket) error); ok {
+		r0 = rf(ctx, ticket)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkCancelled provides a mock function with given fields: ctx, ticket
+func (_m *TicketStorer) MarkCancelled(ctx context.Context, ticket *tickets.Ticket) error {
+	ret := _m.Called(ctx, ticket)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *tickets.Ticket) error); ok {
+		r0 = rf(ctx, ticket)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkUsed provides a mock function with given fields: ctx, ticket
+func (_m *TicketStorer) MarkUsed(ctx context.Context, ticket *tickets.Ticket) error {
+	ret := _m.Called(ctx, ticket)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *tickets.Ticket) error); ok {
+		r0 = rf(ctx, ticket)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SelectAllForUser provides a mock function with given fields: ctx, userID, cursor, limit, filters
+func (_m *TicketStorer) SelectAllForUser(ctx context.Context, userID int64, cursor string, limit int64, filters repo.Filters) ([]*tickets.Ticket, repo.Page, error) {
+	ret := _m.Called(ctx, userID, cursor, limit, filters)
+
+	var r0 []*tickets.Ticket
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, int64, repo.Filters) []*tickets.Ticket); ok {
+		r0 = rf(ctx, userID, cursor, limit, filters)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*tickets.Ticket)
+		}
+	}
+
+	var r1 repo.Page
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, int64, repo.Filters) repo.Page); ok {
+		r1 = rf(ctx, userID, cursor, limit, filters)
+	} else {
+		r1 = ret.Get(1).(repo.Page)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, int64, string, int64, repo.Filters) error); ok {
+		r2 = rf(ctx, userID, cursor, limit, filters)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SelectByID provides a mock function with given fields: ctx, id
+func (_m *TicketStorer) SelectByID(ctx context.Context, id int64) (*tickets.Ticket, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 *tickets.Ticket
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *tickets.Ticket); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*tickets.Ticket)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewTicketStorer interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewTicketStorer creates a new instance of TicketStorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewTicketStorer(t mockConstructorTestingTNewTicketStorer) *TicketStorer {
+	mock := &TicketStorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
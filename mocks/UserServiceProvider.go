@@ -14,6 +14,34 @@ type UserServiceProvider struct {
 	mock.Mock
 }
 
+// Close provides a mock function with given fields: ctx
+func (_m *UserServiceProvider) Close(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ForgotPassword provides a mock function with given fields: ctx, email
+func (_m *UserServiceProvider) ForgotPassword(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetByID provides a mock function with given fields: ctx, id
 func (_m *UserServiceProvider) GetByID(ctx context.Context, id int64) (users.User, error) {
 	ret := _m.Called(ctx, id)
@@ -35,20 +63,43 @@ func (_m *UserServiceProvider) GetByID(ctx context.Context, id int64) (users.Use
 	return r0, r1
 }
 
-// Login provides a mock function with given fields: ctx, creds
-func (_m *UserServiceProvider) Login(ctx context.Context, creds *users.UserLoginForm) (users.Token, error) {
-	ret := _m.Called(ctx, creds)
+// ListSessions provides a mock function with given fields: ctx, userID
+func (_m *UserServiceProvider) ListSessions(ctx context.Context, userID int64) ([]*users.Session, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*users.Session
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*users.Session); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*users.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: ctx, creds, device
+func (_m *UserServiceProvider) Login(ctx context.Context, creds *users.UserLoginForm, device users.Device) (users.Token, error) {
+	ret := _m.Called(ctx, creds, device)
 
 	var r0 users.Token
-	if rf, ok := ret.Get(0).(func(context.Context, *users.UserLoginForm) users.Token); ok {
-		r0 = rf(ctx, creds)
+	if rf, ok := ret.Get(0).(func(context.Context, *users.UserLoginForm, users.Device) users.Token); ok {
+		r0 = rf(ctx, creds, device)
 	} else {
 		r0 = ret.Get(0).(users.Token)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *users.UserLoginForm) error); ok {
-		r1 = rf(ctx, creds)
+	if rf, ok := ret.Get(1).(func(context.Context, *users.UserLoginForm, users.Device) error); ok {
+		r1 = rf(ctx, creds, device)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -56,20 +107,48 @@ func (_m *UserServiceProvider) Login(ctx context.Context, creds *users.UserLogin
 	return r0, r1
 }
 
-// RefreshToken provides a mock function with given fields: token
-func (_m *UserServiceProvider) RefreshToken(token string) (users.Token, error) {
-	ret := _m.Called(token)
+// Logout provides a mock function with given fields: ctx, token
+func (_m *UserServiceProvider) Logout(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LogoutAll provides a mock function with given fields: ctx, userID
+func (_m *UserServiceProvider) LogoutAll(ctx context.Context, userID int64) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshToken provides a mock function with given fields: ctx, token
+func (_m *UserServiceProvider) RefreshToken(ctx context.Context, token string) (users.Token, error) {
+	ret := _m.Called(ctx, token)
 
 	var r0 users.Token
-	if rf, ok := ret.Get(0).(func(string) users.Token); ok {
-		r0 = rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, string) users.Token); ok {
+		r0 = rf(ctx, token)
 	} else {
 		r0 = ret.Get(0).(users.Token)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(token)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -77,6 +156,48 @@ func (_m *UserServiceProvider) RefreshToken(token string) (users.Token, error) {
 	return r0, r1
 }
 
+// ResendVerification provides a mock function with given fields: ctx, userID
+func (_m *UserServiceProvider) ResendVerification(ctx context.Context, userID int64) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password
+func (_m *UserServiceProvider) ResetPassword(ctx context.Context, token string, password string) error {
+	ret := _m.Called(ctx, token, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSession provides a mock function with given fields: ctx, userID, id
+func (_m *UserServiceProvider) RevokeSession(ctx context.Context, userID int64, id string) error {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Signup provides a mock function with given fields: ctx, user
 func (_m *UserServiceProvider) Signup(ctx context.Context, user *users.User) error {
 	ret := _m.Called(ctx, user)
@@ -105,6 +226,27 @@ func (_m *UserServiceProvider) Update(ctx context.Context, user *users.User) err
 	return r0
 }
 
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *UserServiceProvider) VerifyEmail(ctx context.Context, token string) (users.User, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 users.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) users.User); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Get(0).(users.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewUserServiceProvider interface {
 	mock.TestingT
 	Cleanup(func())
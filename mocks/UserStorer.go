@@ -28,6 +28,20 @@ func (_m *UserStorer) Insert(ctx context.Context, user *users.User) error {
 	return r0
 }
 
+// MarkVerified provides a mock function with given fields: ctx, user
+func (_m *UserStorer) MarkVerified(ctx context.Context, user *users.User) error {
+	ret := _m.Called(ctx, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *users.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SelectByEmail provides a mock function with given fields: ctx, email
 func (_m *UserStorer) SelectByEmail(ctx context.Context, email string) (users.User, error) {
 	ret := _m.Called(ctx, email)
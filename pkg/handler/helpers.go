@@ -3,6 +3,9 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/sainak/bitsb/pkg/repo"
 )
 
 const maxLimit = 50
@@ -19,3 +22,29 @@ func GetLimit(r *http.Request) int64 {
 	}
 	return limit
 }
+
+// GetSort returns the `sort` of the request, a key like
+// "name" or "-name" for descending order
+func GetSort(r *http.Request) repo.Sort {
+	return repo.ParseSort(r.URL.Query().Get("sort"))
+}
+
+// GetFilters returns the column filters of the request, given as
+// `filter[column:operator]=value` with comma separated values
+// for the "in" and "between" operators
+func GetFilters(r *http.Request) repo.Filters {
+	filters := make(repo.Filters)
+	for k, v := range r.URL.Query() {
+		if !strings.HasPrefix(k, "filter[") || !strings.HasSuffix(k, "]") || len(v) == 0 {
+			continue
+		}
+		key := k[len("filter[") : len(k)-1]
+		switch _, op, _ := strings.Cut(key, ":"); strings.ToLower(op) {
+		case "in", "between":
+			filters[key] = strings.Split(v[0], ",")
+		default:
+			filters[key] = v[0]
+		}
+	}
+	return filters
+}
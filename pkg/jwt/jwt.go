@@ -1,17 +1,33 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/sainak/bitsb/apperrors"
+)
+
+const (
+	UserID    = "user_id"
+	SessionID = "sid"
 )
 
-const UserID = "user_id"
+// EmailTokenLifespan is how long email verification tokens are valid
+const EmailTokenLifespan = 48 * time.Hour
 
+// JWT signs tokens with Keys, tokens are signed with HS256 and Secret when Keys is nil.
+// When both are set tokens without a kid are still verified with Secret, so the tokens
+// issued before switching to Keys stay valid until they expire.
 type JWT struct {
 	Secret                    string
+	Keys                      *KeySet
 	RefreshTokenLifespanHours time.Duration
 	AuthTokenLifespanMinutes  time.Duration
 }
@@ -35,41 +51,48 @@ func New(secret, refreshTokenLifespanHours, authTokenLifespanMinutes string) *JW
 	}
 }
 
-// CreateRefreshToken generates new jwt refresh token with the given user id
-func (j *JWT) CreateRefreshToken(userID int64) (string, error) {
+// CreateRefreshToken generates new jwt refresh token of the session of the given user,
+// tokenID is the jti of the token
+func (j *JWT) CreateRefreshToken(userID int64, sessionID, tokenID string) (string, error) {
 	claims := gojwt.MapClaims{}
 	claims[UserID] = userID
+	claims[SessionID] = sessionID
+	claims["jti"] = tokenID
 	claims["exp"] = time.Now().Add(j.RefreshTokenLifespanHours).Unix()
 	claims["type"] = "refresh"
 
-	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims)
-
-	signedToken, err := token.SignedString([]byte(j.Secret))
-	if err != nil {
-		return "", err
-	}
-	return signedToken, nil
+	return j.sign(claims)
 }
 
-// CreateToken generates new auth token with the given user id
-func (j *JWT) CreateToken(userID int64) (string, error) {
+// CreateToken generates new auth token of the session of the given user
+func (j *JWT) CreateToken(userID int64, sessionID string) (string, error) {
 	claims := gojwt.MapClaims{}
 	claims[UserID] = userID
+	claims[SessionID] = sessionID
 	claims["exp"] = time.Now().Add(j.AuthTokenLifespanMinutes).Unix()
 	claims["type"] = "auth"
 
-	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims)
+	return j.sign(claims)
+}
 
-	signedToken, err := token.SignedString([]byte(j.Secret))
-	if err != nil {
-		return "", err
+func (j *JWT) sign(claims gojwt.MapClaims) (string, error) {
+	if j.Keys != nil {
+		return j.Keys.sign(claims)
 	}
-	return signedToken, nil
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(j.Secret))
 }
 
-// ParseToken validates and decodes a given token and returns a Token object
+// ParseToken validates and decodes a given token and returns a Token object,
+// the key verifying the token is picked by its kid header
 func (j *JWT) ParseToken(tokenString string) (*gojwt.Token, error) {
 	token, err := gojwt.Parse(tokenString, func(token *gojwt.Token) (interface{}, error) {
+		if _, ok := token.Header["kid"]; ok && j.Keys != nil {
+			return j.Keys.verificationKey(token)
+		}
+		if j.Keys != nil && j.Secret == "" {
+			return nil, fmt.Errorf("token has no key id")
+		}
 		// validate the signing method
 		if _, ok := token.Method.(*gojwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -82,39 +105,112 @@ func (j *JWT) ParseToken(tokenString string) (*gojwt.Token, error) {
 	return token, nil
 }
 
-func (j *JWT) GetUserID(tokenString string) (int64, error) {
+// JWKS returns the public keys tokens can be verified with,
+// it has no keys when tokens are signed with the shared secret
+func (j *JWT) JWKS() JWKS {
+	if j.Keys == nil {
+		return JWKS{Keys: []JSONWebKey{}}
+	}
+	return j.Keys.JWKS()
+}
+
+// parseClaims validates a token of the given type and returns its claims and user id
+func (j *JWT) parseClaims(tokenString, tokenType string) (gojwt.MapClaims, int64, error) {
 	token, err := j.ParseToken(tokenString)
+	if errors.Is(err, gojwt.ErrTokenExpired) {
+		return nil, 0, apperrors.ErrExpiredToken.Wrap(err)
+	}
 	if err != nil || !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+		return nil, 0, apperrors.ErrInvalidToken.Wrap(err)
 	}
 
 	claims := token.Claims.(gojwt.MapClaims)
-
+	if claims["type"] != tokenType {
+		return nil, 0, apperrors.ErrInvalidToken
+	}
 	id, err := strconv.ParseInt(fmt.Sprintf("%v", claims[UserID]), 10, 64)
 	if err != nil {
-		return 0, err
+		return nil, 0, apperrors.ErrInvalidToken.Wrap(err)
 	}
-	return id, nil
+	return claims, id, nil
+}
+
+// AuthClaims are the claims of an auth token
+type AuthClaims struct {
+	UserID    int64
+	SessionID string
 }
 
-// RefreshToken generates a new token based on the refresh token
-func (j *JWT) RefreshToken(refreshTokenString string) (string, error) {
-	token, err := j.ParseToken(refreshTokenString)
+// ParseAuthToken validates an auth token and returns its claims
+func (j *JWT) ParseAuthToken(tokenString string) (*AuthClaims, error) {
+	claims, id, err := j.parseClaims(tokenString, "auth")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if !token.Valid {
-		return "", fmt.Errorf("invalid token")
+	sessionID, _ := claims[SessionID].(string)
+	if sessionID == "" {
+		return nil, apperrors.ErrInvalidToken
 	}
+	return &AuthClaims{UserID: id, SessionID: sessionID}, nil
+}
 
-	claims := token.Claims.(gojwt.MapClaims)
-	if claims["type"] != "refresh" {
-		return "", fmt.Errorf("invalid token")
+// RefreshClaims are the claims of a refresh token
+type RefreshClaims struct {
+	UserID    int64
+	SessionID string
+	TokenID   string
+}
+
+// ParseRefreshToken validates a refresh token and returns its claims
+func (j *JWT) ParseRefreshToken(refreshTokenString string) (*RefreshClaims, error) {
+	claims, id, err := j.parseClaims(refreshTokenString, "refresh")
+	if err != nil {
+		return nil, err
+	}
+	sessionID, _ := claims[SessionID].(string)
+	tokenID, _ := claims["jti"].(string)
+	if sessionID == "" || tokenID == "" {
+		return nil, apperrors.ErrInvalidToken
 	}
+	return &RefreshClaims{UserID: id, SessionID: sessionID, TokenID: tokenID}, nil
+}
 
-	id, err := strconv.ParseInt(fmt.Sprintf("%v", claims[UserID]), 10, 64)
+// EmailClaims are the claims of an email verification token
+type EmailClaims struct {
+	UserID int64
+	Email  string
+}
+
+// CreateEmailToken generates a token verifying the email of the given user,
+// it is only valid while the email of the user is unchanged
+func (j *JWT) CreateEmailToken(userID int64, email string) (string, error) {
+	claims := gojwt.MapClaims{}
+	claims[UserID] = userID
+	claims["email"] = email
+	claims["exp"] = time.Now().Add(EmailTokenLifespan).Unix()
+	claims["type"] = "verify_email"
+
+	return j.sign(claims)
+}
+
+// ParseEmailToken validates an email verification token and returns its claims
+func (j *JWT) ParseEmailToken(tokenString string) (*EmailClaims, error) {
+	claims, id, err := j.parseClaims(tokenString, "verify_email")
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	email, _ := claims["email"].(string)
+	if email == "" {
+		return nil, apperrors.ErrInvalidToken
+	}
+	return &EmailClaims{UserID: id, Email: email}, nil
+}
+
+// NewID returns a random id for sessions and tokens
+func NewID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		logrus.Fatal(err)
 	}
-	return j.CreateToken(id)
+	return base64.RawURLEncoding.EncodeToString(id)
 }
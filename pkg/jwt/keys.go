@@ -0,0 +1,186 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	gojwt "github.com/golang-jwt/jwt/v4"
+)
+
+// minRSAKeyBits is the smallest RSA key accepted for signing tokens
+const minRSAKeyBits = 2048
+
+// Key is a key tokens are signed or verified with, identified by the kid header of the tokens
+type Key struct {
+	ID     string
+	Method gojwt.SigningMethod
+	// private is nil for keys that only verify tokens
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet holds the keys of the tokens, new tokens are signed with its first key
+// and tokens signed with any of its keys are valid. A key is rotated by adding a
+// new key first and keeping the old key until the tokens it signed have expired.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	// ordered are the keys in the order they were listed
+	ordered []*Key
+}
+
+// NewKeySet returns a key set signing with the first of keys
+func NewKeySet(keys ...*Key) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("a key set needs at least one key")
+	}
+	if keys[0].private == nil {
+		return nil, fmt.Errorf("key %q has no private key to sign tokens with", keys[0].ID)
+	}
+	set := &KeySet{signing: keys[0], keys: make(map[string]*Key, len(keys)), ordered: keys}
+	for _, key := range keys {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("key %q is listed twice", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+// LoadKeys loads the keys listed in spec as comma separated kid=path entries,
+// the files hold PEM encoded RSA or Ed25519 keys. The first key signs tokens
+// and has to be a private key, the others may be public keys only verifying tokens.
+func LoadKeys(spec string) (*KeySet, error) {
+	keys := make([]*Key, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		id, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("key %q should be listed as kid=path", entry)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseKey(id, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeySet(keys...)
+}
+
+// ParseKey parses a PEM encoded RSA or Ed25519 key, private keys can be
+// PKCS #8 or PKCS #1 encoded and public keys PKIX encoded
+func ParseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q has unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	key := &Key{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.private, key.public = gojwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.public = gojwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.private, key.public = gojwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.public = gojwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("key %q is neither an RSA nor an Ed25519 key", id)
+	}
+	if public, ok := key.public.(*rsa.PublicKey); ok && public.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("key %q should have at least %d bits", id, minRSAKeyBits)
+	}
+	return key, nil
+}
+
+// sign signs the claims with the signing key and sets its kid header
+func (k *KeySet) sign(claims gojwt.Claims) (string, error) {
+	token := gojwt.NewWithClaims(k.signing.Method, claims)
+	token.Header["kid"] = k.signing.ID
+	return token.SignedString(k.signing.private)
+}
+
+// verificationKey returns the public key of the kid header of the token
+func (k *KeySet) verificationKey(token *gojwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+	// validate the signing method
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// JSONWebKey is the public part of a key as published in a JWKS
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// N and E are the modulus and exponent of RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and X are the curve and public key of Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, see RFC 7517
+type JWKS struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, the signing key first
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JSONWebKey, 0, len(k.ordered))}
+	for _, key := range k.ordered {
+		jwks.Keys = append(jwks.Keys, key.jwk())
+	}
+	return jwks
+}
+
+func (k *Key) jwk() JSONWebKey {
+	jwk := JSONWebKey{KeyID: k.ID, Use: "sig", Algorithm: k.Method.Alg()}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sainak/bitsb/apperrors"
+)
+
+func pemKey(t *testing.T, blockType string, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func newEd25519Key(t *testing.T, id string) (*Key, []byte) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	key, err := ParseKey(id, pemKey(t, "PRIVATE KEY", der))
+	require.NoError(t, err)
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	return key, pemKey(t, "PUBLIC KEY", publicDER)
+}
+
+func newRSAKey(t *testing.T, id string, bits int) (*Key, error) {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+	return ParseKey(id, pemKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)))
+}
+
+func newJWT(t *testing.T, keys ...*Key) *JWT {
+	t.Helper()
+	set, err := NewKeySet(keys...)
+	require.NoError(t, err)
+	j := New("", "24", "5")
+	j.Keys = set
+	return j
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := newRSAKey(t, "rsa-1", 2048)
+	require.NoError(t, err)
+	edKey, edPublic := newEd25519Key(t, "ed-1")
+
+	t.Run("tokens are signed with the first key", func(t *testing.T) {
+		for _, key := range []*Key{rsaKey, edKey} {
+			j := newJWT(t, key)
+			token, err := j.CreateToken(1, "session-1")
+			require.NoError(t, err)
+
+			parsed, err := j.ParseToken(token)
+			require.NoError(t, err)
+			require.Equal(t, key.ID, parsed.Header["kid"])
+			require.Equal(t, key.Method.Alg(), parsed.Method.Alg())
+			claims, err := j.ParseAuthToken(token)
+			require.NoError(t, err)
+			require.Equal(t, "session-1", claims.SessionID)
+		}
+	})
+
+	t.Run("tokens of rotated keys stay valid", func(t *testing.T) {
+		old := newJWT(t, rsaKey)
+		token, err := old.CreateRefreshToken(1, "session-1", "token-1")
+		require.NoError(t, err)
+
+		rotated := newJWT(t, edKey, rsaKey)
+		_, err = rotated.ParseRefreshToken(token)
+		require.NoError(t, err)
+	})
+
+	t.Run("public keys only verify tokens", func(t *testing.T) {
+		edPublicKey, err := ParseKey(edKey.ID, edPublic)
+		require.NoError(t, err)
+		_, err = NewKeySet(edPublicKey)
+		require.Error(t, err)
+
+		token, err := newJWT(t, edKey).CreateToken(1, "session-1")
+		require.NoError(t, err)
+		_, err = newJWT(t, rsaKey, edPublicKey).ParseAuthToken(token)
+		require.NoError(t, err)
+	})
+
+	t.Run("tokens of removed keys are rejected", func(t *testing.T) {
+		token, err := newJWT(t, rsaKey).CreateToken(1, "session-1")
+		require.NoError(t, err)
+
+		_, err = newJWT(t, edKey).ParseAuthToken(token)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+
+	t.Run("the algorithm has to match the key", func(t *testing.T) {
+		// an HMAC token using the public key as secret
+		token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{UserID: 1, SessionID: "s", "type": "auth"})
+		token.Header["kid"] = edKey.ID
+		signed, err := token.SignedString([]byte(edKey.public.(ed25519.PublicKey)))
+		require.NoError(t, err)
+
+		_, err = newJWT(t, edKey).ParseAuthToken(signed)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+
+	t.Run("tokens of the secret are valid while it is set", func(t *testing.T) {
+		token, err := New("secret", "24", "5").CreateToken(1, "session-1")
+		require.NoError(t, err)
+
+		j := newJWT(t, edKey)
+		_, err = j.ParseAuthToken(token)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+
+		j.Secret = "secret"
+		_, err = j.ParseAuthToken(token)
+		require.NoError(t, err)
+	})
+
+	t.Run("small RSA keys are refused", func(t *testing.T) {
+		_, err := newRSAKey(t, "rsa-small", 1024)
+		require.Error(t, err)
+	})
+
+	t.Run("keys are loaded from files", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "ed-2.pem")
+		require.NoError(t, os.WriteFile(path, edPublic, 0o600))
+
+		_, err := LoadKeys("ed-2=" + path)
+		require.Error(t, err, "the signing key has to be private")
+		_, err = LoadKeys(path)
+		require.Error(t, err)
+		_, err = LoadKeys("ed-2=" + filepath.Join(dir, "missing.pem"))
+		require.Error(t, err)
+	})
+
+	t.Run("the JWKS lists the public keys", func(t *testing.T) {
+		jwks := newJWT(t, edKey, rsaKey).JWKS()
+		require.Len(t, jwks.Keys, 2)
+		require.Equal(t, JSONWebKey{
+			KeyType:   "OKP",
+			KeyID:     "ed-1",
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         jwks.Keys[0].X,
+		}, jwks.Keys[0])
+		require.Len(t, jwks.Keys[0].X, 43)
+		require.Equal(t, "RSA", jwks.Keys[1].KeyType)
+		require.Equal(t, "RS256", jwks.Keys[1].Algorithm)
+		require.Equal(t, "AQAB", jwks.Keys[1].E)
+
+		require.Empty(t, New("secret", "24", "5").JWKS().Keys)
+	})
+}
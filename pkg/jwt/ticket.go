@@ -0,0 +1,82 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// TicketClaims are the claims of a ticket pass, the short names keep
+// the pass small enough to be shown as a QR code
+type TicketClaims struct {
+	TicketID       int64  `json:"tid"`
+	BusRouteID     int64  `json:"rid"`
+	FromLocationID int64  `json:"frm"`
+	ToLocationID   int64  `json:"to"`
+	TravelDate     string `json:"dt"`
+	Departure      string `json:"dep"`
+	Arrival        string `json:"arr"`
+	gojwt.RegisteredClaims
+}
+
+// TicketSigner signs ticket passes with an Ed25519 key,
+// so they can be verified offline with only the public key
+type TicketSigner struct {
+	privateKey ed25519.PrivateKey
+}
+
+// NewTicketSigner returns a TicketSigner using the base64 encoded Ed25519 seed,
+// when seed is empty a random key is generated and passes are only valid until a restart
+func NewTicketSigner(seed string) (*TicketSigner, error) {
+	if seed == "" {
+		logrus.Warn("ticket signing key is not set, using a random key")
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &TicketSigner{privateKey}, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ticket signing key should be %d bytes", ed25519.SeedSize)
+	}
+	return &TicketSigner{ed25519.NewKeyFromSeed(b)}, nil
+}
+
+// PublicKey returns the key used to verify ticket passes
+func (t *TicketSigner) PublicKey() ed25519.PublicKey {
+	return t.privateKey.Public().(ed25519.PublicKey)
+}
+
+// Sign returns a signed ticket pass with the given claims
+func (t *TicketSigner) Sign(claims *TicketClaims) (string, error) {
+	token := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, claims)
+	return token.SignedString(t.privateKey)
+}
+
+// Parse verifies the signature of a ticket pass and returns its claims
+func (t *TicketSigner) Parse(pass string) (*TicketClaims, error) {
+	claims := &TicketClaims{}
+	token, err := gojwt.ParseWithClaims(pass, claims, func(token *gojwt.Token) (interface{}, error) {
+		// validate the signing method
+		if _, ok := token.Method.(*gojwt.SigningMethodEd25519); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return t.PublicKey(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterMailer(t *testing.T) {
+	var b bytes.Buffer
+	mailer := NewWriterMailer(&b, "BitsB <no-reply@localhost>")
+
+	err := mailer.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hello", Body: "line 1\nline 2"})
+	require.NoError(t, err)
+	out := b.String()
+	require.True(t, strings.HasPrefix(out, "From: BitsB <no-reply@localhost>\r\nTo: ada@example.com\r\nSubject: Hello\r\n"))
+	require.Contains(t, out, "\r\n\r\nline 1\r\nline 2")
+}
+
+func TestHeaderInjection(t *testing.T) {
+	mailer := NewMemoryMailer()
+
+	err := mailer.Send(context.Background(), Message{To: "ada@example.com\r\nBcc: eve@example.com", Subject: "Hello"})
+	require.Error(t, err)
+	err = mailer.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hello\nBcc: eve@example.com"})
+	require.Error(t, err)
+	require.Empty(t, mailer.Messages())
+
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hello"}))
+	require.Len(t, mailer.Messages(), 1)
+}
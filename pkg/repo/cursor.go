@@ -1,32 +1,103 @@
 package repo
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
-	"strconv"
-	"time"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sainak/bitsb/apperrors"
 )
 
-// DecodeCursor will decode cursor from user for db
-func DecodeCursor(encodedTime string) (time.Time, error) {
-	byt, err := base64.StdEncoding.DecodeString(encodedTime)
-	if err != nil {
-		return time.Unix(1<<31-1, 0), err
-	} else if len(byt) == 0 {
-		return time.Unix(1<<31-1, 0), nil
+var cursorSecret = randomSecret()
+
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logrus.Fatal(err)
+	}
+	return secret
+}
+
+// SetCursorSecret sets the secret cursors are signed with, until it
+// is set a random secret is used and cursors do not survive a restart
+func SetCursorSecret(secret string) {
+	if secret == "" {
+		logrus.Warn("cursor secret is not set, using a random secret")
+		return
 	}
+	cursorSecret = []byte(secret)
+}
+
+// Cursor points at a row of a list by its sort value and id, the next page has
+// the rows after it, or the rows before it when Before is set. A cursor only
+// works for the list it was made for, with the same sort and filters.
+type Cursor struct {
+	Sort    string `json:"s"`
+	Value   string `json:"v"`
+	ID      int64  `json:"id"`
+	Before  bool   `json:"b,omitempty"`
+	Filters string `json:"f"`
+}
 
-	timeString := string(byt)
-	millis, err := strconv.ParseInt(timeString, 10, 64)
+// Encode returns the cursor signed with the cursor secret
+func (c *Cursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(sign(payload))
+}
+
+// DecodeCursor verifies the signature of an encoded cursor and decodes it
+func DecodeCursor(encoded string) (*Cursor, error) {
+	p, s, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return nil, apperrors.ErrBadCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
 	if err != nil {
-		return time.Unix(1<<31-1, 0), err
+		return nil, apperrors.ErrBadCursor
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || !hmac.Equal(signature, sign(payload)) {
+		return nil, apperrors.ErrBadCursor
+	}
+
+	c := &Cursor{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(c); err != nil {
+		return nil, apperrors.ErrBadCursor
 	}
+	return c, nil
+}
 
-	return time.Unix(0, millis*int64(time.Millisecond)), nil
+func sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
 }
 
-// EncodeCursor will encode cursor from db to user
-func EncodeCursor(t time.Time) string {
-	timeString := strconv.FormatInt(t.UnixMilli(), 10)
+// Fingerprint returns a short hash of the filters of a list
+func Fingerprint(filters interface{}) string {
+	b, err := json.Marshal(filters)
+	if err != nil {
+		b = []byte(fmt.Sprint(filters))
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
 
-	return base64.StdEncoding.EncodeToString([]byte(timeString))
+// Page tells where a page is in its list, Next and Prev are the cursors of the pages
+// around it and are empty at the ends of the list, HasMore is set when there is a next page
+type Page struct {
+	Next    string `json:"next"`
+	Prev    string `json:"prev"`
+	Limit   int64  `json:"limit"`
+	HasMore bool   `json:"has_more"`
 }
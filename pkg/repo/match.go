@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sainak/bitsb/apperrors"
+)
+
+// Match reports whether a row held in memory matches the filters like the conditions
+// of Build do in a query, field returns the value of a column of the row. Filter values
+// given as strings are converted to the type of the column before they are compared,
+// and a nil value is NULL, it never matches.
+func (f Filters) Match(columns Columns, field func(column string) interface{}) (bool, error) {
+	for k, v := range f {
+		column, op, err := parseKey(columns, k, v)
+		if err != nil {
+			return false, err
+		}
+		value := field(column)
+		if value == nil {
+			return false, nil
+		}
+
+		var match bool
+		switch op {
+		case "eq", "ne", "lt", "gt":
+			c, err := compareTo(value, v, k)
+			if err != nil {
+				return false, err
+			}
+			match = op == "eq" && c == 0 || op == "ne" && c != 0 || op == "lt" && c < 0 || op == "gt" && c > 0
+		case "in":
+			list := reflect.ValueOf(v)
+			for i := 0; i < list.Len() && !match; i++ {
+				c, err := compareTo(value, list.Index(i).Interface(), k)
+				if err != nil {
+					return false, err
+				}
+				match = c == 0
+			}
+		case "ilike":
+			match = strings.Contains(strings.ToLower(fmt.Sprint(value)), strings.ToLower(fmt.Sprint(v)))
+		case "between":
+			bounds := reflect.ValueOf(v)
+			lower, err := compareTo(value, bounds.Index(0).Interface(), k)
+			if err != nil {
+				return false, err
+			}
+			upper, err := compareTo(value, bounds.Index(1).Interface(), k)
+			if err != nil {
+				return false, err
+			}
+			match = lower >= 0 && upper <= 0
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// compareTo compares the value of a column with the value of the filter k, values
+// that are a driver.Valuer are compared as the value they are written to a query with
+func compareTo(value, v interface{}, k string) (int, error) {
+	badValue := apperrors.NewWithCode(http.StatusBadRequest, "filter.bad_value", fmt.Sprintf("%v is not a valid value for %q", v, k))
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil || v == nil {
+			return 0, badValue
+		}
+	}
+	if s, ok := v.(string); ok {
+		var err error
+		switch value.(type) {
+		case int64:
+			v, err = strconv.ParseInt(s, 10, 64)
+		case float64:
+			v, err = strconv.ParseFloat(s, 64)
+		case time.Time:
+			if v, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				v, err = time.Parse("2006-01-02", s)
+			}
+		}
+		if err != nil {
+			return 0, badValue
+		}
+	}
+
+	switch a := value.(type) {
+	case int64:
+		b := reflect.ValueOf(v)
+		if !b.CanInt() {
+			return 0, badValue
+		}
+		return compareOrdered(a, b.Int()), nil
+	case float64:
+		b := reflect.ValueOf(v)
+		switch {
+		case b.CanFloat():
+			return compareOrdered(a, b.Float()), nil
+		case b.CanInt():
+			return compareOrdered(a, float64(b.Int())), nil
+		}
+		return 0, badValue
+	case time.Time:
+		b, ok := v.(time.Time)
+		if !ok {
+			return 0, badValue
+		}
+		return a.Compare(b), nil
+	default:
+		return strings.Compare(fmt.Sprint(value), fmt.Sprint(v)), nil
+	}
+}
+
+func compareOrdered[T int64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// PaginateSlice pages through rows held in memory the way a query built with the
+// paginator does, the rows are sorted, the ones past the cursor are kept and
+// they are trimmed to the page. key is the same as for Paginate.
+func PaginateSlice[T any](p *Paginator, rows []T, key func(T) (string, int64)) ([]T, Page) {
+	desc := p.sort.Desc != p.backward()
+	page := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if p.cursor != nil {
+			value, id := key(row)
+			c := p.compare(value, id, p.cursor.Value, p.cursor.ID)
+			if desc && c >= 0 || !desc && c <= 0 {
+				continue
+			}
+		}
+		page = append(page, row)
+	}
+	sort.SliceStable(page, func(i, j int) bool {
+		a, aID := key(page[i])
+		b, bID := key(page[j])
+		c := p.compare(a, aID, b, bID)
+		return desc && c > 0 || !desc && c < 0
+	})
+	if int64(len(page)) > p.Limit() {
+		page = page[:p.Limit()]
+	}
+	return Paginate(p, page, key)
+}
+
+// compare compares two rows by the value of the sort column, as a value of its SQL type, and then by id
+func (p *Paginator) compare(a string, aID int64, b string, bID int64) int {
+	c := 0
+	switch p.column.Type {
+	case "integer":
+		x, _ := strconv.ParseInt(a, 10, 64)
+		y, _ := strconv.ParseInt(b, 10, 64)
+		c = compareOrdered(x, y)
+	case "timestamp", "timestamptz":
+		x, _ := time.Parse(time.RFC3339Nano, a)
+		y, _ := time.Parse(time.RFC3339Nano, b)
+		c = x.Compare(y)
+	default:
+		c = compareOrdered(a, b)
+	}
+	if c == 0 {
+		c = compareOrdered(aID, bID)
+	}
+	return c
+}
@@ -0,0 +1,55 @@
+package repo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+)
+
+func TestFiltersMatch(t *testing.T) {
+	columns := repo.Columns{"travel_date": "travel_date", "price": "price"}
+	travelDate := time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC)
+	field := func(column string) interface{} {
+		switch column {
+		case "travel_date":
+			return travelDate
+		case "price":
+			return int64(20)
+		}
+		return nil
+	}
+	date := func(value string) bitsb.Date {
+		d, _ := bitsb.ParseDate(value)
+		return d
+	}
+
+	for name, tc := range map[string]struct {
+		filters repo.Filters
+		match   bool
+	}{
+		"when a date is compared to a time":     {repo.Filters{"travel_date:eq": date("2020-11-02")}, true},
+		"when a date is before the time":        {repo.Filters{"travel_date:gt": date("2020-11-01")}, true},
+		"when a date is another day":            {repo.Filters{"travel_date:eq": date("2020-11-03")}, false},
+		"when a date is in the list":            {repo.Filters{"travel_date:in": []bitsb.Date{date("2020-11-01"), date("2020-11-02")}}, true},
+		"when a string is compared to a time":   {repo.Filters{"travel_date:eq": "2020-11-02"}, true},
+		"when a string is compared to a number": {repo.Filters{"price:lt": "30"}, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			match, err := tc.filters.Match(columns, field)
+			require.NoError(t, err)
+			require.Equal(t, tc.match, match)
+		})
+	}
+
+	t.Run("when a date is zero", func(t *testing.T) {
+		_, err := repo.Filters{"travel_date:eq": bitsb.Date{}}.Match(columns, field)
+		var appErr *apperrors.Error
+		require.ErrorAs(t, err, &appErr)
+		require.Equal(t, "filter.bad_value", appErr.Code)
+	})
+}
@@ -2,21 +2,105 @@ package repo
 
 import (
 	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
 	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/sainak/bitsb/apperrors"
 )
 
+// Filters are the conditions of a list query,
+// keys are in the format of "column:operator" and default to "column:eq"
 type Filters map[string]interface{}
 
-func (f *Filters) BuildQuery() string {
-	var query []string
-	for k, v := range *f {
-		// keys are in format of "key:operator"
-		s := strings.Split(k, ":")
-		switch strings.ToLower(s[1]) {
-		case "ilike", "like":
-			v = fmt.Sprintf("%%%v%%", v)
+// Columns whitelists the columns of an entity that can be filtered on,
+// it maps the name used in filters to the column in the database
+type Columns map[string]string
+
+var operators = map[string]string{
+	"eq":    "=",
+	"ne":    "<>",
+	"lt":    "<",
+	"gt":    ">",
+	"in":    "= ANY",
+	"ilike": "ILIKE",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes the wildcards of a LIKE pattern so value is matched literally
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// Build returns the filters as conditions joined with AND and the args of the query.
+// The values are never part of the SQL, they are appended to args as positional
+// params, so args should hold the params already used by the rest of the query.
+func (f Filters) Build(columns Columns, args []interface{}) (string, []interface{}, error) {
+	// sort the keys so the same filters always build the same query
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(keys))
+	for _, k := range keys {
+		column, op, err := parseKey(columns, k, f[k])
+		if err != nil {
+			return "", args, err
+		}
+
+		v := f[k]
+		switch op {
+		case "eq", "ne", "lt", "gt":
+			args = append(args, v)
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", column, operators[op], len(args)))
+		case "in":
+			args = append(args, pq.Array(v))
+			conditions = append(conditions, fmt.Sprintf("%s %s($%d)", column, operators[op], len(args)))
+		case "ilike":
+			args = append(args, "%"+EscapeLike(fmt.Sprint(v))+"%")
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", column, operators[op], len(args)))
+		case "between":
+			bounds := reflect.ValueOf(v)
+			args = append(args, bounds.Index(0).Interface(), bounds.Index(1).Interface())
+			conditions = append(conditions, fmt.Sprintf("%s BETWEEN $%d AND $%d", column, len(args)-1, len(args)))
+		}
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// parseKey returns the column and the lower-cased operator of the filter key k,
+// it fails when the column cannot be filtered on, the operator is unknown
+// or v is not a list for the operators taking several values
+func parseKey(columns Columns, k string, v interface{}) (string, string, error) {
+	name, op, _ := strings.Cut(k, ":")
+	if op == "" {
+		op = "eq"
+	}
+	column, ok := columns[name]
+	if !ok {
+		return "", "", apperrors.NewWithCode(http.StatusBadRequest, "filter.bad_column", fmt.Sprintf("cannot filter on %q", name))
+	}
+
+	values := reflect.ValueOf(v)
+	isList := values.Kind() == reflect.Slice || values.Kind() == reflect.Array
+	switch op = strings.ToLower(op); op {
+	case "eq", "ne", "lt", "gt", "ilike":
+	case "in":
+		if !isList {
+			return "", "", apperrors.NewWithCode(http.StatusBadRequest, "filter.bad_value", fmt.Sprintf("%q needs a list of values", k))
+		}
+	case "between":
+		if !isList || values.Len() != 2 {
+			return "", "", apperrors.NewWithCode(http.StatusBadRequest, "filter.bad_value", fmt.Sprintf("%q needs a lower and an upper bound", k))
 		}
-		query = append(query, fmt.Sprintf("%s %s '%v'", s[0], s[1], v))
+	default:
+		return "", "", apperrors.NewWithCode(http.StatusBadRequest, "filter.bad_operator", fmt.Sprintf("unknown filter operator %q", op))
 	}
-	return strings.Join(query, " AND ")
+	return column, op, nil
 }
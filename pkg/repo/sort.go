@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sainak/bitsb/apperrors"
+)
+
+// Sort is the order of a list query, by Key ascending unless Desc is set.
+// The zero Sort uses the default order of the list.
+type Sort struct {
+	Key  string
+	Desc bool
+}
+
+// ParseSort parses a sort key like "name", or "-name" for descending order
+func ParseSort(value string) Sort {
+	if strings.HasPrefix(value, "-") {
+		return Sort{Key: value[1:], Desc: true}
+	}
+	return Sort{Key: value}
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Key
+	}
+	return s.Key
+}
+
+// SortColumns whitelists the columns of an entity that can be sorted on,
+// it maps the sort key to the column and its SQL type
+type SortColumns map[string]SortColumn
+
+type SortColumn struct {
+	Column string
+	Type   string
+}
+
+// Paginator pages through a list query with keyset pagination,
+// rows are ordered by the sort column and then by id to break ties
+type Paginator struct {
+	sort    Sort
+	column  SortColumn
+	limit   int64
+	filters string
+	cursor  *Cursor
+}
+
+// NewPaginator returns a Paginator for the page of the list after the cursor,
+// the cursor has to be made for a list with the same sort and filters
+func NewPaginator(cursor string, limit int64, sort Sort, columns SortColumns, filters interface{}) (*Paginator, error) {
+	column, ok := columns[sort.Key]
+	if !ok {
+		return nil, apperrors.NewWithCode(http.StatusBadRequest, "sort.bad_column", fmt.Sprintf("cannot sort on %q", sort.Key))
+	}
+	p := &Paginator{
+		sort:    sort,
+		column:  column,
+		limit:   limit,
+		filters: Fingerprint(filters),
+	}
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sort.String() || c.Filters != p.filters {
+			return nil, apperrors.ErrBadCursor
+		}
+		p.cursor = c
+	}
+	return p, nil
+}
+
+// backward reports whether the page is before its cursor
+func (p *Paginator) backward() bool {
+	return p.cursor != nil && p.cursor.Before
+}
+
+// Condition returns the condition matching the rows of the page, the values of
+// the cursor are appended to args as positional params. It is empty on the first page.
+func (p *Paginator) Condition(args []interface{}) (string, []interface{}) {
+	if p.cursor == nil {
+		return "", args
+	}
+	op := ">"
+	if p.sort.Desc != p.backward() {
+		op = "<"
+	}
+	args = append(args, p.cursor.Value, p.cursor.ID)
+	return fmt.Sprintf("(%s, id) %s (cast($%d as %s), $%d)", p.column.Column, op, len(args)-1, p.column.Type, len(args)), args
+}
+
+// OrderBy returns the order of the query, it is reversed when paging backward
+func (p *Paginator) OrderBy() string {
+	direction := "ASC"
+	if p.sort.Desc != p.backward() {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%[1]s %[2]s, id %[2]s", p.column.Column, direction)
+}
+
+// Limit returns the limit of the query, one more row than the page
+// is fetched to know if there are more rows after it
+func (p *Paginator) Limit() int64 {
+	return p.limit + 1
+}
+
+func (p *Paginator) cursorAt(value string, id int64, before bool) string {
+	c := &Cursor{
+		Sort:    p.sort.String(),
+		Value:   value,
+		ID:      id,
+		Before:  before,
+		Filters: p.filters,
+	}
+	return c.Encode()
+}
+
+// Paginate trims the rows fetched with the paginator to the page and returns where the
+// page is in the list, key returns the value of the sort column and the id of a row
+func Paginate[T any](p *Paginator, rows []T, key func(T) (string, int64)) ([]T, Page) {
+	page := Page{Limit: p.limit}
+	more := int64(len(rows)) > p.limit
+	if more {
+		rows = rows[:p.limit]
+	}
+	if p.backward() {
+		// rows were fetched in reverse order
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	// going backward there is always a next page, the one the cursor came from,
+	// and going forward from a cursor there is always a previous page
+	hasNext := more || p.backward()
+	hasPrev := p.cursor != nil && (!p.backward() || more)
+	if hasNext {
+		value, id := key(rows[len(rows)-1])
+		page.Next = p.cursorAt(value, id, false)
+		page.HasMore = true
+	}
+	if hasPrev {
+		value, id := key(rows[0])
+		page.Prev = p.cursorAt(value, id, true)
+	}
+	return rows, page
+}
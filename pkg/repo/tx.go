@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sainak/bitsb/pkg/middleware"
+)
+
+var txCtxKey = &middleware.ContextKey{Name: "tx"}
+
+// Querier is implemented by both *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Conn returns the transaction of the context, or db when the context has none,
+// storers run their queries on it so they take part in a unit of work
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txCtxKey).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// UnitOfWork runs storer calls in one transaction, so their writes are committed
+// or rolled back together. The transaction runs at the READ COMMITTED level: rows
+// read in it can still change before it writes, storers lock the rows they read
+// or write conditionally where a check has to hold until the write.
+type UnitOfWork interface {
+	// Do calls fn with a context carrying a transaction, the transaction is
+	// committed when fn returns nil and rolled back when it fails or panics.
+	// When ctx already carries a transaction fn joins it.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type unitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) UnitOfWork {
+	return &unitOfWork{db}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return InTx(ctx, u.db, fn)
+}
+
+// InTx calls fn in a transaction of db, see UnitOfWork.Do
+func InTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txCtxKey).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(tx)
+			panic(p)
+		}
+		if err != nil {
+			rollback(tx)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txCtxKey, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logrus.Error(err)
+	}
+}
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	uow := NewUnitOfWork(db)
+
+	t.Run("when every call succeeds the transaction is committed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE locations").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM locations").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := uow.Do(context.Background(), func(ctx context.Context) error {
+			if _, err := Conn(ctx, db).ExecContext(ctx, "UPDATE locations SET name = 'a'"); err != nil {
+				return err
+			}
+			_, err := Conn(ctx, db).ExecContext(ctx, "DELETE FROM locations")
+			return err
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("when a call fails the transaction is rolled back", func(t *testing.T) {
+		failure := errors.New("failure")
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE locations").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		err := uow.Do(context.Background(), func(ctx context.Context) error {
+			if _, err := Conn(ctx, db).ExecContext(ctx, "UPDATE locations SET name = 'a'"); err != nil {
+				return err
+			}
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+	})
+
+	t.Run("when a call panics the transaction is rolled back", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		require.Panics(t, func() {
+			_ = uow.Do(context.Background(), func(ctx context.Context) error {
+				panic("failure")
+			})
+		})
+	})
+
+	t.Run("when units of work are nested they share the transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM locations").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := uow.Do(context.Background(), func(ctx context.Context) error {
+			return uow.Do(ctx, func(ctx context.Context) error {
+				_, err := Conn(ctx, db).ExecContext(ctx, "DELETE FROM locations")
+				return err
+			})
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("when there is no unit of work the database is used", func(t *testing.T) {
+		require.Equal(t, db, Conn(context.Background(), db))
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,142 @@
+package validate
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sainak/bitsb/apperrors"
+)
+
+// Struct checks the fields of form against the rules in their `binding` tags
+// and collects every rule that is broken, fields are named by their json key.
+// The rules are separated by commas:
+//
+//	required    the field is set, it is not zero, null or empty
+//	email       the field is an email address
+//	min=n       a number is at least n, a string or a list has at least n characters or items
+//	max=n       a number is at most n, a string or a list has at most n characters or items
+//	oneof=a b   the field is one of the space separated values
+//
+// Only required is checked on fields that are not set.
+func Struct(form interface{}) *apperrors.ValidationError {
+	errs := &apperrors.ValidationError{}
+	v := reflect.Indirect(reflect.ValueOf(form))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("binding")
+		if tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+		name := fieldName(field)
+		value, set := resolve(v.Field(i))
+		for _, rule := range strings.Split(tag, ",") {
+			rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			if rule == "required" {
+				if !set {
+					errs.Add(name, rule, fmt.Sprintf("'%s' is required", name))
+				}
+				continue
+			}
+			if !set {
+				continue
+			}
+			if detail := check(rule, param, name, value); detail != "" {
+				errs.Add(name, rule, detail)
+			}
+		}
+	}
+	return errs
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// resolve returns the value of a field and whether it is set,
+// nullable fields are unwrapped to the value they hold
+func resolve(v reflect.Value) (reflect.Value, bool) {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok && z.IsZero() {
+		return v, false
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil || value == nil {
+			return v, false
+		}
+		// a valid null value is set even when it holds the zero value
+		return reflect.ValueOf(value), true
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v, v.Len() > 0
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		return resolve(v.Elem())
+	}
+	return v, !v.IsZero()
+}
+
+// check returns the detail of the broken rule, or "" when value follows it
+func check(rule, param, name string, value reflect.Value) string {
+	switch rule {
+	case "email":
+		address, err := mail.ParseAddress(value.String())
+		if err != nil || address.Address != value.String() {
+			return fmt.Sprintf("'%s' should be a valid email address", name)
+		}
+	case "min", "max":
+		bound, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: bad %s rule on %q: %v", rule, name, err))
+		}
+		size, unit := measure(value)
+		if (rule == "min" && size < bound) || (rule == "max" && size > bound) {
+			limit := map[string]string{"min": "at least", "max": "at most"}[rule]
+			if unit == "" {
+				return fmt.Sprintf("'%s' should be %s %s", name, limit, param)
+			}
+			return fmt.Sprintf("'%s' should have %s %s %s", name, limit, param, unit)
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		for _, option := range options {
+			if fmt.Sprint(value.Interface()) == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("'%s' should be one of %s", name, strings.Join(options, ", "))
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q on %q", rule, name))
+	}
+	return ""
+}
+
+// measure returns the number compared by the min and max rules
+// and the unit it is counted in, numbers have no unit
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "items"
+	}
+	panic(fmt.Sprintf("validate: cannot measure a %s", value.Kind()))
+}
@@ -0,0 +1,91 @@
+package validate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+)
+
+type form struct {
+	Name     string     `json:"name" binding:"required,max=5"`
+	Email    string     `json:"email" binding:"email"`
+	Stops    []int64    `json:"stops" binding:"required,min=2"`
+	Count    int64      `json:"count" binding:"min=1,max=3"`
+	Kind     string     `json:"kind" binding:"oneof=flat zone"`
+	Lat      null.Float `json:"lat" binding:"min=-90,max=90"`
+	Date     time.Time  `json:"date" binding:"required"`
+	Untagged string
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		form form
+		want []apperrors.FieldError
+	}{
+		{
+			name: "when every rule is followed",
+			form: form{
+				Name:  "bus",
+				Email: "user@example.com",
+				Stops: []int64{1, 2},
+				Count: 2,
+				Kind:  "zone",
+				Lat:   null.FloatFrom(0),
+				Date:  time.Now(),
+			},
+		},
+		{
+			name: "when required fields are not set",
+			form: form{},
+			want: []apperrors.FieldError{
+				{Field: "name", Code: "required", Detail: "'name' is required"},
+				{Field: "stops", Code: "required", Detail: "'stops' is required"},
+				{Field: "date", Code: "required", Detail: "'date' is required"},
+			},
+		},
+		{
+			name: "when every broken rule is collected",
+			form: form{
+				Name:  "a long name",
+				Email: "User <user@example.com>",
+				Stops: []int64{1},
+				Count: 4,
+				Kind:  "distance",
+				Lat:   null.FloatFrom(-91),
+				Date:  time.Now(),
+			},
+			want: []apperrors.FieldError{
+				{Field: "name", Code: "max", Detail: "'name' should have at most 5 characters"},
+				{Field: "email", Code: "email", Detail: "'email' should be a valid email address"},
+				{Field: "stops", Code: "min", Detail: "'stops' should have at least 2 items"},
+				{Field: "count", Code: "max", Detail: "'count' should be at most 3"},
+				{Field: "kind", Code: "oneof", Detail: "'kind' should be one of flat, zone"},
+				{Field: "lat", Code: "min", Detail: "'lat' should be at least -90"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Struct(&tt.form)
+			require.Equal(t, tt.want, errs.Errors)
+			if tt.want == nil {
+				require.NoError(t, errs.Err())
+			} else {
+				require.ErrorIs(t, errs.Err(), errs)
+			}
+		})
+	}
+}
+
+func TestStructUnknownRule(t *testing.T) {
+	require.Panics(t, func() {
+		Struct(struct {
+			Name string `binding:"uppercase"`
+		}{Name: "x"})
+	})
+}
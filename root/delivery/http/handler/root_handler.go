@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/go-chi/render"
+
+	"github.com/sainak/bitsb/pkg/jwt"
 )
 
 func Home(w http.ResponseWriter, r *http.Request) {
@@ -20,3 +22,11 @@ func Ping(w http.ResponseWriter, r *http.Request) {
 		"current_time": time.Now(),
 	})
 }
+
+// JWKS publishes the public keys auth tokens are verified with
+func JWKS(j *jwt.JWT) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		render.JSON(w, r, j.JWKS())
+	}
+}
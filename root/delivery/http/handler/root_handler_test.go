@@ -2,7 +2,10 @@ package handler
 
 import (
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/sainak/bitsb/pkg/jwt"
 )
 
 func TestPing(t *testing.T) {
@@ -17,3 +20,17 @@ func TestPing(t *testing.T) {
 		t.Errorf("Expected status code 200, got %d", w.Code)
 	}
 }
+
+func TestJWKS(t *testing.T) {
+	r := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	JWKS(jwt.New("secret", "24", "5"))(w, r)
+
+	if w.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", w.Code)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"keys":[]}` {
+		t.Errorf("Expected no keys, got %s", body)
+	}
+}
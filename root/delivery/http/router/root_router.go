@@ -1,12 +1,26 @@
 package router
 
 import (
+	"net/http"
+	"strings"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/sainak/bitsb/pkg/jwt"
 	"github.com/sainak/bitsb/root/delivery/http/handler"
 )
 
-func RegisterRoutes(router *chi.Mux) {
+// URLFormat is middleware.URLFormat for every path but the well-known ones,
+// their extension is part of the name they are published at
+func URLFormat(next http.Handler) http.Handler {
+	return middleware.Maybe(middleware.URLFormat, func(r *http.Request) bool {
+		return !strings.HasPrefix(r.URL.Path, "/.well-known/")
+	})(next)
+}
+
+func RegisterRoutes(router chi.Router, j *jwt.JWT) {
 	router.Get("/", handler.Home)
 	router.Get("/ping", handler.Ping)
+	router.Get("/.well-known/jwks.json", handler.JWKS(j))
 }
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sainak/bitsb/pkg/jwt"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(URLFormat)
+	RegisterRoutes(r, jwt.New("secret", "24", "5"))
+
+	for path, code := range map[string]int{
+		"/.well-known/jwks.json": http.StatusOK,
+		"/.well-known/jwks":      http.StatusNotFound,
+		"/.well-known/jwks.xml":  http.StatusNotFound,
+		"/ping":                  http.StatusOK,
+		"/ping.json":             http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != code {
+			t.Errorf("Expected status code %d for %s, got %d", code, path, w.Code)
+		}
+	}
+}
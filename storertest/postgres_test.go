@@ -0,0 +1,44 @@
+package storertest
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	_bitsbRepo "github.com/sainak/bitsb/bitsb/repo/postgres"
+	"github.com/sainak/bitsb/pkg/repo"
+	_ticketRepo "github.com/sainak/bitsb/tickets/repo/postgres"
+	_userRepo "github.com/sainak/bitsb/users/repo/postgres"
+)
+
+// TestPostgres runs the conformance tests against the database at TEST_DB_DSN,
+// it has to be migrated and its tables are emptied before every test
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" || testing.Short() {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	Run(t, func(t *testing.T) *Storers {
+		_, err := db.Exec(`TRUNCATE password_resets, sessions, tickets, bus_routes, service_calendars, users, locations RESTART IDENTITY CASCADE;`)
+		require.NoError(t, err)
+		return &Storers{
+			Locations:  _bitsbRepo.NewLocationRepository(db),
+			BusRoutes:  _bitsbRepo.NewBusRouteRepository(db),
+			Calendars:  _bitsbRepo.NewServiceCalendarRepository(db),
+			Tickets:    _ticketRepo.NewTicketRepository(db),
+			Users:      _userRepo.NewUserRepository(db),
+			Sessions:   _userRepo.NewSessionRepository(db),
+			Resets:     _userRepo.NewPasswordResetRepository(db),
+			UnitOfWork: repo.NewUnitOfWork(db),
+		}
+	})
+}
@@ -0,0 +1,1055 @@
+// Package storertest has the conformance tests the implementations of the
+// storers have to pass, so the backends can be used in place of each other.
+package storertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/tickets"
+	"github.com/sainak/bitsb/users"
+)
+
+// Storers are the storers of one backend, they have to share one database
+type Storers struct {
+	Locations  bitsb.LocationStorer
+	BusRoutes  bitsb.BusRouteStorer
+	Calendars  bitsb.ServiceCalendarStorer
+	Tickets    tickets.TicketStorer
+	Users      users.UserStorer
+	Sessions   users.SessionStorer
+	Resets     users.PasswordResetStorer
+	UnitOfWork repo.UnitOfWork
+}
+
+// Run runs the conformance tests, newStorers is called by every test
+// and has to return storers of an empty database
+func Run(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	t.Run("Locations", func(t *testing.T) { testLocations(t, newStorers) })
+	t.Run("BusRoutes", func(t *testing.T) { testBusRoutes(t, newStorers) })
+	t.Run("ServiceCalendars", func(t *testing.T) { testServiceCalendars(t, newStorers) })
+	t.Run("Tickets", func(t *testing.T) { testTickets(t, newStorers) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, newStorers) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newStorers) })
+	t.Run("PasswordResets", func(t *testing.T) { testPasswordResets(t, newStorers) })
+	t.Run("UnitOfWork", func(t *testing.T) { testUnitOfWork(t, newStorers) })
+}
+
+// requireAppError checks that err is parsed into an error with the code of target,
+// storers can fail with the error itself or with the database error it is parsed from
+func requireAppError(t *testing.T, err error, target *apperrors.Error) {
+	t.Helper()
+	require.Error(t, err)
+	require.ErrorIs(t, apperrors.ParseError(err), target)
+}
+
+func insertLocations(t *testing.T, s *Storers, names ...string) []*bitsb.Location {
+	t.Helper()
+	locations := make([]*bitsb.Location, 0, len(names))
+	for _, name := range names {
+		location := &bitsb.Location{Name: name}
+		require.NoError(t, s.Locations.Insert(context.Background(), location))
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+func clock(t *testing.T, value string) time.Time {
+	t.Helper()
+	c, err := time.Parse("15:04", value)
+	require.NoError(t, err)
+	return c
+}
+
+func date(t *testing.T, value string) bitsb.Date {
+	t.Helper()
+	d, err := bitsb.ParseDate(value)
+	require.NoError(t, err)
+	return d
+}
+
+func dates(list []bitsb.Date) []string {
+	result := make([]string, 0, len(list))
+	for _, d := range list {
+		result = append(result, d.String())
+	}
+	return result
+}
+
+func newBusRoute(number string, locationIDs ...int64) *bitsb.BusRoute {
+	return &bitsb.BusRoute{
+		Name:         "Route " + number,
+		Number:       number,
+		StartTime:    time.Date(0, 1, 1, 6, 0, 0, 0, time.UTC),
+		EndTime:      time.Date(0, 1, 1, 22, 0, 0, 0, time.UTC),
+		Interval:     30,
+		LocationIDS:  locationIDs,
+		FareStrategy: bitsb.FarePerStop,
+		MinPrice:     5,
+		MaxPrice:     25,
+	}
+}
+
+func ids(locations []*bitsb.Location) []int64 {
+	result := make([]int64, 0, len(locations))
+	for _, location := range locations {
+		result = append(result, location.ID)
+	}
+	return result
+}
+
+func names(locations []*bitsb.Location) []string {
+	result := make([]string, 0, len(locations))
+	for _, location := range locations {
+		result = append(result, location.Name)
+	}
+	return result
+}
+
+func numbers(busRoutes []*bitsb.BusRoute) []string {
+	result := make([]string, 0, len(busRoutes))
+	for _, busRoute := range busRoutes {
+		result = append(result, busRoute.Number)
+	}
+	return result
+}
+
+func testLocations(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+
+	t.Run("inserted locations can be selected", func(t *testing.T) {
+		s := newStorers(t)
+		location := &bitsb.Location{Name: "Central", Latitude: null.FloatFrom(12.97), Longitude: null.FloatFrom(77.59)}
+		require.NoError(t, s.Locations.Insert(ctx, location))
+		require.NotZero(t, location.ID)
+
+		got, err := s.Locations.SelectByID(ctx, location.ID)
+		require.NoError(t, err)
+		require.Equal(t, location.ID, got.ID)
+		require.Equal(t, "Central", got.Name)
+		require.Equal(t, location.Latitude, got.Latitude)
+		require.Equal(t, location.Longitude, got.Longitude)
+		require.WithinDuration(t, location.CreatedAt, got.CreatedAt, time.Millisecond)
+	})
+
+	t.Run("missing locations are not found", func(t *testing.T) {
+		s := newStorers(t)
+		_, err := s.Locations.SelectByID(ctx, 404)
+		require.ErrorIs(t, err, apperrors.ErrLocationNotFound)
+		require.ErrorIs(t, s.Locations.Update(ctx, &bitsb.Location{ID: 404, Name: "Missing"}), apperrors.ErrLocationNotFound)
+		require.ErrorIs(t, s.Locations.Delete(ctx, 404), apperrors.ErrLocationNotFound)
+	})
+
+	t.Run("location names are unique", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "Central", "Market")
+		requireAppError(t, s.Locations.Insert(ctx, &bitsb.Location{Name: "Central"}), apperrors.ErrEntityAlreadyExist)
+
+		locations[1].Name = "Central"
+		requireAppError(t, s.Locations.Update(ctx, locations[1]), apperrors.ErrEntityAlreadyExist)
+	})
+
+	t.Run("updated locations are stored", func(t *testing.T) {
+		s := newStorers(t)
+		location := insertLocations(t, s, "Central")[0]
+		location.Name = "Central Station"
+		location.Latitude = null.FloatFrom(1.5)
+		require.NoError(t, s.Locations.Update(ctx, location))
+
+		got, err := s.Locations.SelectByID(ctx, location.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Central Station", got.Name)
+		require.Equal(t, null.FloatFrom(1.5), got.Latitude)
+	})
+
+	t.Run("deleted locations are gone", func(t *testing.T) {
+		s := newStorers(t)
+		location := insertLocations(t, s, "Central")[0]
+		require.NoError(t, s.Locations.Delete(ctx, location.ID))
+		_, err := s.Locations.SelectByID(ctx, location.ID)
+		require.ErrorIs(t, err, apperrors.ErrLocationNotFound)
+	})
+
+	t.Run("lists are paged with cursors both ways", func(t *testing.T) {
+		s := newStorers(t)
+		insertLocations(t, s, "A", "B", "C", "D", "E")
+
+		first, page, err := s.Locations.SelectAll(ctx, "", 2, repo.Filters{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"E", "D"}, names(first))
+		require.True(t, page.HasMore)
+		require.Empty(t, page.Prev)
+
+		second, page, err := s.Locations.SelectAll(ctx, page.Next, 2, repo.Filters{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"C", "B"}, names(second))
+		require.NotEmpty(t, page.Prev)
+
+		last, page, err := s.Locations.SelectAll(ctx, page.Next, 2, repo.Filters{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"A"}, names(last))
+		require.False(t, page.HasMore)
+
+		back, _, err := s.Locations.SelectAll(ctx, page.Prev, 2, repo.Filters{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"C", "B"}, names(back))
+
+		_, _, err = s.Locations.SelectAll(ctx, page.Prev, 2, repo.Filters{"name": "A"})
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+	})
+
+	t.Run("lists are filtered", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "Central", "Central Market", "Harbour")
+
+		got, _, err := s.Locations.SelectAll(ctx, "", 10, repo.Filters{"name:ilike": "central"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Central Market", "Central"}, names(got))
+
+		got, _, err = s.Locations.SelectAll(ctx, "", 10, repo.Filters{"name:ne": "Central", "id:gt": locations[0].ID})
+		require.NoError(t, err)
+		require.Equal(t, []string{"Harbour", "Central Market"}, names(got))
+
+		_, _, err = s.Locations.SelectAll(ctx, "", 10, repo.Filters{"latitude": "1"})
+		require.Equal(t, "filter.bad_column", apperrors.ParseError(err).Code)
+	})
+
+	t.Run("locations are selected in the order of their ids", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "A", "B", "C")
+
+		got, err := s.Locations.SelectByIDArray(ctx, []int64{locations[2].ID, locations[0].ID, 404, locations[1].ID})
+		require.NoError(t, err)
+		require.Equal(t, []string{"C", "A", "B"}, names(got))
+	})
+
+	t.Run("nearby locations are nearest first", func(t *testing.T) {
+		s := newStorers(t)
+		for _, location := range []*bitsb.Location{
+			{Name: "Far", Latitude: null.FloatFrom(0.02), Longitude: null.FloatFrom(0)},
+			{Name: "Near", Latitude: null.FloatFrom(0.001), Longitude: null.FloatFrom(0)},
+			{Name: "Nowhere"},
+			{Name: "Away", Latitude: null.FloatFrom(1), Longitude: null.FloatFrom(1)},
+		} {
+			require.NoError(t, s.Locations.Insert(ctx, location))
+		}
+
+		got, err := s.Locations.SelectNearby(ctx, 0, 0, 5000, 10)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, "Near", got[0].Name)
+		require.InDelta(t, 111, got[0].Distance, 1)
+		require.Equal(t, "Far", got[1].Name)
+
+		got, err = s.Locations.SelectNearby(ctx, 0, 0, 5000, 1)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+	})
+
+	t.Run("locations are detached from the routes stopping there", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "A", "B", "C")
+		busRoute := newBusRoute("1A", ids(locations)...)
+		busRoute.SegmentDurations = []int64{60, 120}
+		busRoute.SegmentDistances = []int64{500, 700}
+		require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+
+		dependents, err := s.Locations.SelectDependents(ctx, locations[1].ID)
+		require.NoError(t, err)
+		require.Equal(t, []*bitsb.BusRouteSummary{{ID: busRoute.ID, Name: "Route 1A", Number: "1A"}}, dependents.BusRoutes)
+		require.Empty(t, dependents.UserIDs)
+
+		require.NoError(t, s.Locations.DeleteDetached(ctx, locations[1].ID))
+		_, err = s.Locations.SelectByID(ctx, locations[1].ID)
+		require.ErrorIs(t, err, apperrors.ErrLocationNotFound)
+
+		got, err := s.BusRoutes.SelectByID(ctx, busRoute.ID)
+		require.NoError(t, err)
+		require.Equal(t, []int64{locations[0].ID, locations[2].ID}, got.LocationIDS)
+		require.Equal(t, []int64{180}, got.SegmentDurations)
+		require.Equal(t, []int64{1200}, got.SegmentDistances)
+	})
+
+	t.Run("locations are not detached from tickets", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "A", "B", "C")
+		busRoute := newBusRoute("1A", ids(locations)...)
+		require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		user := &users.User{Email: "ada@example.com", Password: "hash", Access: users.Passenger}
+		require.NoError(t, s.Users.Insert(ctx, user))
+		ticket := &tickets.Ticket{
+			UserID:         user.ID,
+			BusRouteID:     busRoute.ID,
+			FromLocationID: locations[0].ID,
+			ToLocationID:   locations[2].ID,
+			TravelDate:     date(t, "2020-11-02"),
+			Departure:      clock(t, "08:00"),
+			Arrival:        clock(t, "08:20"),
+			Status:         tickets.StatusBooked,
+		}
+		require.NoError(t, s.Tickets.Insert(ctx, ticket))
+
+		dependents, err := s.Locations.SelectDependents(ctx, locations[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, []int64{ticket.ID}, dependents.TicketIDs)
+		dependents, err = s.Locations.SelectDependents(ctx, locations[1].ID)
+		require.NoError(t, err)
+		require.Empty(t, dependents.TicketIDs)
+
+		// the route of the booked ticket stops at B
+		for _, location := range locations {
+			err = s.Locations.DeleteDetached(ctx, location.ID)
+			require.ErrorIs(t, err, apperrors.ErrLocationTicketed)
+			require.Equal(t, []int64{ticket.ID}, apperrors.ParseError(err).Details)
+		}
+		got, err := s.BusRoutes.SelectByID(ctx, busRoute.ID)
+		require.NoError(t, err)
+		require.Equal(t, ids(locations), got.LocationIDS)
+
+		require.NoError(t, s.Tickets.MarkCancelled(ctx, ticket))
+		require.ErrorIs(t, s.Locations.DeleteDetached(ctx, locations[0].ID), apperrors.ErrLocationTicketed)
+		require.NoError(t, s.Locations.DeleteDetached(ctx, locations[1].ID))
+	})
+
+	t.Run("locations are not detached from routes left with one stop", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "A", "B", "C")
+		long := newBusRoute("1A", ids(locations)...)
+		short := newBusRoute("2B", locations[0].ID, locations[1].ID)
+		require.NoError(t, s.BusRoutes.Insert(ctx, long))
+		require.NoError(t, s.BusRoutes.Insert(ctx, short))
+
+		err := s.Locations.DeleteDetached(ctx, locations[1].ID)
+		require.ErrorIs(t, err, apperrors.ErrTooFewStops)
+
+		_, err = s.Locations.SelectByID(ctx, locations[1].ID)
+		require.NoError(t, err)
+		got, err := s.BusRoutes.SelectByID(ctx, long.ID)
+		require.NoError(t, err)
+		require.Equal(t, ids(locations), got.LocationIDS)
+	})
+}
+
+func testBusRoutes(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+
+	t.Run("inserted routes can be selected", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "A", "B", "C")
+		busRoute := newBusRoute("1A", ids(locations)...)
+		busRoute.FareStrategy = bitsb.FareZone
+		busRoute.FareConfig = bitsb.FareConfig{Zones: []int64{1, 1, 2}, ZonePrices: []int64{10, 20}}
+		require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		require.NotZero(t, busRoute.ID)
+
+		got, err := s.BusRoutes.SelectByID(ctx, busRoute.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Route 1A", got.Name)
+		require.Equal(t, "06:00", got.StartTime.Format("15:04"))
+		require.Equal(t, "22:00", got.EndTime.Format("15:04"))
+		require.Equal(t, ids(locations), got.LocationIDS)
+		require.Equal(t, []int64{}, got.SegmentDurations)
+		require.Equal(t, busRoute.FareConfig, got.FareConfig)
+		require.False(t, got.CalendarID.Valid)
+	})
+
+	t.Run("missing routes are not found", func(t *testing.T) {
+		s := newStorers(t)
+		_, err := s.BusRoutes.SelectByID(ctx, 404)
+		require.ErrorIs(t, err, apperrors.ErrRouteNotFound)
+		busRoute := newBusRoute("1A", 1, 2)
+		busRoute.ID = 404
+		require.ErrorIs(t, s.BusRoutes.Update(ctx, busRoute), apperrors.ErrRouteNotFound)
+		require.ErrorIs(t, s.BusRoutes.Delete(ctx, 404), apperrors.ErrRouteNotFound)
+	})
+
+	t.Run("route numbers are unique", func(t *testing.T) {
+		s := newStorers(t)
+		require.NoError(t, s.BusRoutes.Insert(ctx, newBusRoute("1A", 1, 2)))
+		requireAppError(t, s.BusRoutes.Insert(ctx, newBusRoute("1A", 1, 2)), apperrors.ErrEntityAlreadyExist)
+	})
+
+	t.Run("routes reference existing calendars", func(t *testing.T) {
+		s := newStorers(t)
+		busRoute := newBusRoute("1A", 1, 2)
+		busRoute.CalendarID = null.IntFrom(404)
+		requireAppError(t, s.BusRoutes.Insert(ctx, busRoute), apperrors.ErrEntityInUse)
+
+		calendar := &bitsb.ServiceCalendar{Name: "Weekdays", Weekdays: []int64{1, 2, 3, 4, 5}}
+		require.NoError(t, s.Calendars.Insert(ctx, calendar))
+		busRoute.CalendarID = null.IntFrom(calendar.ID)
+		require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		requireAppError(t, s.Calendars.Delete(ctx, calendar.ID), apperrors.ErrEntityInUse)
+	})
+
+	t.Run("updated routes are stored", func(t *testing.T) {
+		s := newStorers(t)
+		busRoute := newBusRoute("1A", 1, 2)
+		require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		busRoute.Name = "Airport Express"
+		busRoute.LocationIDS = []int64{1, 2, 3}
+		busRoute.SegmentDurations = []int64{60, 60}
+		require.NoError(t, s.BusRoutes.Update(ctx, busRoute))
+
+		got, err := s.BusRoutes.SelectByID(ctx, busRoute.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Airport Express", got.Name)
+		require.Equal(t, []int64{1, 2, 3}, got.LocationIDS)
+		require.Equal(t, []int64{60, 60}, got.SegmentDurations)
+	})
+
+	t.Run("lists are sorted and paged", func(t *testing.T) {
+		s := newStorers(t)
+		for _, number := range []string{"4D", "1A", "3C", "2B"} {
+			require.NoError(t, s.BusRoutes.Insert(ctx, newBusRoute(number, 1, 2)))
+		}
+		sort := repo.Sort{Key: "number"}
+
+		first, page, err := s.BusRoutes.SelectAll(ctx, "", 3, bitsb.BusRouteFilters{}, sort)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1A", "2B", "3C"}, numbers(first))
+		require.True(t, page.HasMore)
+
+		last, page, err := s.BusRoutes.SelectAll(ctx, page.Next, 3, bitsb.BusRouteFilters{}, sort)
+		require.NoError(t, err)
+		require.Equal(t, []string{"4D"}, numbers(last))
+		require.False(t, page.HasMore)
+
+		back, _, err := s.BusRoutes.SelectAll(ctx, page.Prev, 3, bitsb.BusRouteFilters{}, sort)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1A", "2B", "3C"}, numbers(back))
+
+		desc, _, err := s.BusRoutes.SelectAll(ctx, "", 2, bitsb.BusRouteFilters{}, repo.Sort{Key: "number", Desc: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"4D", "3C"}, numbers(desc))
+
+		_, _, err = s.BusRoutes.SelectAll(ctx, "", 2, bitsb.BusRouteFilters{}, repo.Sort{Key: "interval"})
+		require.Equal(t, "sort.bad_column", apperrors.ParseError(err).Code)
+	})
+
+	t.Run("lists are filtered", func(t *testing.T) {
+		s := newStorers(t)
+		outbound := newBusRoute("1A", 1, 2, 3)
+		inbound := newBusRoute("1B", 3, 2, 1)
+		inbound.Name = "Harbour Line"
+		inbound.MinPrice, inbound.MaxPrice = 30, 50
+		night := newBusRoute("N1", 4, 5)
+		night.StartTime = time.Date(0, 1, 1, 22, 0, 0, 0, time.UTC)
+		night.EndTime = time.Date(0, 1, 1, 4, 0, 0, 0, time.UTC)
+		for _, busRoute := range []*bitsb.BusRoute{outbound, inbound, night} {
+			require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		}
+		sort := repo.Sort{Key: "number"}
+
+		for name, test := range map[string]struct {
+			filters bitsb.BusRouteFilters
+			want    []string
+		}{
+			"by stops":            {bitsb.BusRouteFilters{Locations: []int64{1, 3}}, []string{"1A", "1B"}},
+			"by stops in order":   {bitsb.BusRouteFilters{Locations: []int64{1, 3}, Ordered: true}, []string{"1A"}},
+			"by name or number":   {bitsb.BusRouteFilters{Query: "harbour"}, []string{"1B"}},
+			"by lowest price":     {bitsb.BusRouteFilters{PriceFrom: null.IntFrom(26)}, []string{"1B"}},
+			"by highest price":    {bitsb.BusRouteFilters{PriceTo: null.IntFrom(20)}, []string{"1A", "N1"}},
+			"by time of day":      {bitsb.BusRouteFilters{RunningAt: null.TimeFrom(clock(t, "02:00"))}, []string{"N1"}},
+			"by column":           {bitsb.BusRouteFilters{Filters: repo.Filters{"min_price:gt": "10"}}, []string{"1B"}},
+			"by list of columns":  {bitsb.BusRouteFilters{Filters: repo.Filters{"number:in": []string{"1A", "N1"}}}, []string{"1A", "N1"}},
+			"by range of columns": {bitsb.BusRouteFilters{Filters: repo.Filters{"max_price:between": []string{"20", "30"}}}, []string{"1A", "N1"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				got, _, err := s.BusRoutes.SelectAll(ctx, "", 10, test.filters, sort)
+				require.NoError(t, err)
+				require.Equal(t, test.want, numbers(got))
+			})
+		}
+	})
+
+	t.Run("routes are selected by their stops", func(t *testing.T) {
+		s := newStorers(t)
+		for _, busRoute := range []*bitsb.BusRoute{newBusRoute("1A", 1, 2), newBusRoute("2B", 2, 3), newBusRoute("3C", 4, 5)} {
+			require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		}
+
+		got, err := s.BusRoutes.SelectByLocations(ctx, []int64{2, 4})
+		require.NoError(t, err)
+		require.Equal(t, []string{"1A", "2B", "3C"}, numbers(got))
+	})
+}
+
+func testServiceCalendars(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+	newCalendar := func(t *testing.T, s *Storers, name string) *bitsb.ServiceCalendar {
+		t.Helper()
+		calendar := &bitsb.ServiceCalendar{
+			Name:         name,
+			Weekdays:     []int64{1, 2, 3, 4, 5},
+			StartDate:    date(t, "2020-01-01"),
+			AddedDates:   []bitsb.Date{date(t, "2020-01-04")},
+			RemovedDates: []bitsb.Date{date(t, "2020-12-25"), date(t, "2021-01-01")},
+		}
+		require.NoError(t, s.Calendars.Insert(ctx, calendar))
+		return calendar
+	}
+
+	t.Run("inserted calendars can be selected", func(t *testing.T) {
+		s := newStorers(t)
+		calendar := newCalendar(t, s, "Weekdays")
+		require.NotZero(t, calendar.ID)
+
+		got, err := s.Calendars.SelectByID(ctx, calendar.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Weekdays", got.Name)
+		require.Equal(t, []int64{1, 2, 3, 4, 5}, got.Weekdays)
+		require.Equal(t, "2020-01-01", got.StartDate.String())
+		require.True(t, got.EndDate.IsZero())
+		require.Equal(t, []string{"2020-01-04"}, dates(got.AddedDates))
+		require.Equal(t, []string{"2020-12-25", "2021-01-01"}, dates(got.RemovedDates))
+		require.True(t, got.RunsOn(date(t, "2020-01-04")))
+		require.False(t, got.RunsOn(date(t, "2020-12-25")))
+	})
+
+	t.Run("calendars are listed in the order of their ids", func(t *testing.T) {
+		s := newStorers(t)
+		first := newCalendar(t, s, "Weekdays")
+		second := newCalendar(t, s, "Holidays")
+
+		got, err := s.Calendars.SelectAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Equal(t, first.ID, got[0].ID)
+		require.Equal(t, second.ID, got[1].ID)
+	})
+
+	t.Run("missing calendars are not found", func(t *testing.T) {
+		s := newStorers(t)
+		_, err := s.Calendars.SelectByID(ctx, 404)
+		require.ErrorIs(t, err, apperrors.ErrCalendarNotFound)
+		require.ErrorIs(t, s.Calendars.Update(ctx, &bitsb.ServiceCalendar{ID: 404, Name: "Missing"}), apperrors.ErrCalendarNotFound)
+		require.ErrorIs(t, s.Calendars.Delete(ctx, 404), apperrors.ErrCalendarNotFound)
+	})
+
+	t.Run("updated calendars are stored", func(t *testing.T) {
+		s := newStorers(t)
+		calendar := newCalendar(t, s, "Weekdays")
+		calendar.Weekdays = []int64{0, 6}
+		calendar.EndDate = date(t, "2020-12-31")
+		calendar.AddedDates = nil
+		require.NoError(t, s.Calendars.Update(ctx, calendar))
+
+		got, err := s.Calendars.SelectByID(ctx, calendar.ID)
+		require.NoError(t, err)
+		require.Equal(t, []int64{0, 6}, got.Weekdays)
+		require.Equal(t, "2020-12-31", got.EndDate.String())
+		require.Empty(t, got.AddedDates)
+	})
+
+	t.Run("deleted calendars are gone", func(t *testing.T) {
+		s := newStorers(t)
+		calendar := newCalendar(t, s, "Weekdays")
+		require.NoError(t, s.Calendars.Delete(ctx, calendar.ID))
+		_, err := s.Calendars.SelectByID(ctx, calendar.ID)
+		require.ErrorIs(t, err, apperrors.ErrCalendarNotFound)
+	})
+}
+
+func testTickets(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+	// newUser inserts a user with a route of three stops to book tickets on
+	newUser := func(t *testing.T, s *Storers, email string) (*users.User, *bitsb.BusRoute) {
+		t.Helper()
+		user := &users.User{Email: email, Password: "hash", Access: users.Passenger}
+		require.NoError(t, s.Users.Insert(ctx, user))
+		busRoute := newBusRoute(email, ids(insertLocations(t, s, email+" A", email+" B", email+" C"))...)
+		require.NoError(t, s.BusRoutes.Insert(ctx, busRoute))
+		return user, busRoute
+	}
+	newTicket := func(t *testing.T, s *Storers, user *users.User, busRoute *bitsb.BusRoute, travelDate string) *tickets.Ticket {
+		t.Helper()
+		ticket := &tickets.Ticket{
+			UserID:         user.ID,
+			BusRouteID:     busRoute.ID,
+			FromLocationID: busRoute.LocationIDS[0],
+			ToLocationID:   busRoute.LocationIDS[2],
+			TravelDate:     date(t, travelDate),
+			Departure:      clock(t, "08:00"),
+			Arrival:        clock(t, "08:20"),
+			Price:          20,
+			Status:         tickets.StatusBooked,
+		}
+		require.NoError(t, s.Tickets.Insert(ctx, ticket))
+		return ticket
+	}
+	ticketIDs := func(list []*tickets.Ticket) []int64 {
+		result := make([]int64, 0, len(list))
+		for _, ticket := range list {
+			result = append(result, ticket.ID)
+		}
+		return result
+	}
+
+	t.Run("inserted tickets can be selected", func(t *testing.T) {
+		s := newStorers(t)
+		user, busRoute := newUser(t, s, "ada@example.com")
+		ticket := newTicket(t, s, user, busRoute, "2020-11-02")
+		require.NotZero(t, ticket.ID)
+
+		got, err := s.Tickets.SelectByID(ctx, ticket.ID)
+		require.NoError(t, err)
+		require.Equal(t, user.ID, got.UserID)
+		require.Equal(t, busRoute.LocationIDS[2], got.ToLocationID)
+		require.Equal(t, "2020-11-02", got.TravelDate.String())
+		require.Equal(t, "08:00", got.Departure.Format("15:04"))
+		require.Equal(t, "08:20", got.Arrival.Format("15:04"))
+		require.Equal(t, tickets.StatusBooked, got.Status)
+		require.False(t, got.CancelledAt.Valid)
+	})
+
+	t.Run("missing tickets are not found", func(t *testing.T) {
+		s := newStorers(t)
+		_, err := s.Tickets.SelectByID(ctx, 404)
+		require.ErrorIs(t, err, apperrors.ErrTicketNotFound)
+	})
+
+	t.Run("tickets need a user, a route and their stops", func(t *testing.T) {
+		s := newStorers(t)
+		user, busRoute := newUser(t, s, "ada@example.com")
+		ticket := &tickets.Ticket{
+			UserID:         404,
+			BusRouteID:     busRoute.ID,
+			FromLocationID: busRoute.LocationIDS[0],
+			ToLocationID:   busRoute.LocationIDS[2],
+			TravelDate:     date(t, "2020-11-02"),
+			Departure:      clock(t, "08:00"),
+			Arrival:        clock(t, "08:20"),
+			Status:         tickets.StatusBooked,
+		}
+		requireAppError(t, s.Tickets.Insert(ctx, ticket), apperrors.ErrEntityInUse)
+
+		ticket.UserID = user.ID
+		ticket.ToLocationID = 404
+		requireAppError(t, s.Tickets.Insert(ctx, ticket), apperrors.ErrEntityInUse)
+	})
+
+	t.Run("lists are filtered and hold the tickets of the user", func(t *testing.T) {
+		s := newStorers(t)
+		user, busRoute := newUser(t, s, "ada@example.com")
+		other, otherRoute := newUser(t, s, "grace@example.com")
+		monday := newTicket(t, s, user, busRoute, "2020-11-02")
+		tuesday := newTicket(t, s, user, busRoute, "2020-11-03")
+		cancelled := newTicket(t, s, user, busRoute, "2020-11-02")
+		require.NoError(t, s.Tickets.MarkCancelled(ctx, cancelled))
+		newTicket(t, s, other, otherRoute, "2020-11-02")
+
+		for name, test := range map[string]struct {
+			filters repo.Filters
+			want    []int64
+		}{
+			"by nothing":            {repo.Filters{}, []int64{cancelled.ID, tuesday.ID, monday.ID}},
+			"by travel date":        {repo.Filters{"travel_date:eq": date(t, "2020-11-02")}, []int64{cancelled.ID, monday.ID}},
+			"by travel date string": {repo.Filters{"travel_date:gt": "2020-11-02"}, []int64{tuesday.ID}},
+			"by status":             {repo.Filters{"status:eq": tickets.StatusBooked}, []int64{tuesday.ID, monday.ID}},
+			"by route":              {repo.Filters{"bus_route_id:eq": otherRoute.ID}, []int64{}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				got, _, err := s.Tickets.SelectAllForUser(ctx, user.ID, "", 10, test.filters)
+				require.NoError(t, err)
+				require.Equal(t, test.want, ticketIDs(got))
+			})
+		}
+	})
+
+	t.Run("lists are paged with cursors of their user", func(t *testing.T) {
+		s := newStorers(t)
+		user, busRoute := newUser(t, s, "ada@example.com")
+		other, _ := newUser(t, s, "grace@example.com")
+		list := make([]*tickets.Ticket, 0, 3)
+		for _, travelDate := range []string{"2020-11-02", "2020-11-03", "2020-11-04"} {
+			list = append(list, newTicket(t, s, user, busRoute, travelDate))
+		}
+		filters := repo.Filters{"travel_date:gt": date(t, "2020-11-01")}
+
+		first, page, err := s.Tickets.SelectAllForUser(ctx, user.ID, "", 2, filters)
+		require.NoError(t, err)
+		require.Equal(t, []int64{list[2].ID, list[1].ID}, ticketIDs(first))
+		require.True(t, page.HasMore)
+
+		last, page, err := s.Tickets.SelectAllForUser(ctx, user.ID, page.Next, 2, filters)
+		require.NoError(t, err)
+		require.Equal(t, []int64{list[0].ID}, ticketIDs(last))
+		require.False(t, page.HasMore)
+
+		back, _, err := s.Tickets.SelectAllForUser(ctx, user.ID, page.Prev, 2, filters)
+		require.NoError(t, err)
+		require.Equal(t, []int64{list[2].ID, list[1].ID}, ticketIDs(back))
+
+		_, _, err = s.Tickets.SelectAllForUser(ctx, other.ID, page.Prev, 2, filters)
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+	})
+
+	t.Run("booked tickets are cancelled once", func(t *testing.T) {
+		s := newStorers(t)
+		user, busRoute := newUser(t, s, "ada@example.com")
+		ticket := newTicket(t, s, user, busRoute, "2020-11-02")
+		require.NoError(t, s.Tickets.MarkCancelled(ctx, ticket))
+		require.Equal(t, tickets.StatusCancelled, ticket.Status)
+
+		got, err := s.Tickets.SelectByID(ctx, ticket.ID)
+		require.NoError(t, err)
+		require.Equal(t, tickets.StatusCancelled, got.Status)
+		require.True(t, got.CancelledAt.Valid)
+
+		require.ErrorIs(t, s.Tickets.MarkCancelled(ctx, got), apperrors.ErrConflict)
+		require.ErrorIs(t, s.Tickets.MarkUsed(ctx, got), apperrors.ErrConflict)
+	})
+
+	t.Run("used tickets are not cancelled", func(t *testing.T) {
+		s := newStorers(t)
+		user, busRoute := newUser(t, s, "ada@example.com")
+		ticket := newTicket(t, s, user, busRoute, "2020-11-02")
+		require.NoError(t, s.Tickets.MarkUsed(ctx, ticket))
+
+		got, err := s.Tickets.SelectByID(ctx, ticket.ID)
+		require.NoError(t, err)
+		require.Equal(t, tickets.StatusUsed, got.Status)
+		require.True(t, got.UsedAt.Valid)
+		require.ErrorIs(t, s.Tickets.MarkCancelled(ctx, got), apperrors.ErrConflict)
+	})
+}
+
+func testUsers(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+	newUser := func(email string) *users.User {
+		return &users.User{Email: email, FirstName: "Ada", LastName: "Lovelace", Password: "hash", Access: users.Passenger}
+	}
+
+	t.Run("inserted users can be selected", func(t *testing.T) {
+		s := newStorers(t)
+		user := newUser("ada@example.com")
+		require.NoError(t, s.Users.Insert(ctx, user))
+		require.NotZero(t, user.ID)
+
+		got, err := s.Users.SelectByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "ada@example.com", got.Email)
+		require.Equal(t, users.Passenger, got.Access)
+		require.Equal(t, "hash", got.Password)
+
+		got, err = s.Users.SelectByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		require.Equal(t, user.ID, got.ID)
+	})
+
+	t.Run("missing users are not found", func(t *testing.T) {
+		s := newStorers(t)
+		_, err := s.Users.SelectByID(ctx, 404)
+		require.ErrorIs(t, err, apperrors.ErrUserNotFound)
+		_, err = s.Users.SelectByEmail(ctx, "nobody@example.com")
+		require.ErrorIs(t, err, apperrors.ErrUserNotFound)
+		user := newUser("nobody@example.com")
+		user.ID = 404
+		require.ErrorIs(t, s.Users.Update(ctx, user), apperrors.ErrUserNotFound)
+	})
+
+	t.Run("emails are unique", func(t *testing.T) {
+		s := newStorers(t)
+		require.NoError(t, s.Users.Insert(ctx, newUser("ada@example.com")))
+		requireAppError(t, s.Users.Insert(ctx, newUser("ada@example.com")), apperrors.ErrEntityAlreadyExist)
+	})
+
+	t.Run("updated users are stored", func(t *testing.T) {
+		s := newStorers(t)
+		user := newUser("ada@example.com")
+		require.NoError(t, s.Users.Insert(ctx, user))
+		user.FirstName = "Augusta"
+		user.LastLogin = null.TimeFrom(time.Now())
+		require.NoError(t, s.Users.Update(ctx, user))
+
+		got, err := s.Users.SelectByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Augusta", got.FirstName)
+		require.True(t, got.LastLogin.Valid)
+		require.False(t, got.VerifiedAt.Valid)
+	})
+
+	t.Run("commute locations are stored", func(t *testing.T) {
+		s := newStorers(t)
+		locations := insertLocations(t, s, "Home", "Work")
+		user := newUser("ada@example.com")
+		user.HomeLocationID = null.IntFrom(locations[0].ID)
+		require.NoError(t, s.Users.Insert(ctx, user))
+
+		got, err := s.Users.SelectByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, null.IntFrom(locations[0].ID), got.HomeLocationID)
+		require.False(t, got.WorkLocationID.Valid)
+
+		user.WorkLocationID = null.IntFrom(locations[1].ID)
+		require.NoError(t, s.Users.Update(ctx, user))
+		got, err = s.Users.SelectByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		require.Equal(t, null.IntFrom(locations[0].ID), got.HomeLocationID)
+		require.Equal(t, null.IntFrom(locations[1].ID), got.WorkLocationID)
+
+		user.HomeLocationID = null.IntFrom(404)
+		requireAppError(t, s.Users.Update(ctx, user), apperrors.ErrEntityInUse)
+	})
+
+	t.Run("users are verified once", func(t *testing.T) {
+		s := newStorers(t)
+		user := newUser("ada@example.com")
+		require.NoError(t, s.Users.Insert(ctx, user))
+		require.NoError(t, s.Users.MarkVerified(ctx, user))
+		require.True(t, user.VerifiedAt.Valid)
+		verifiedAt := user.VerifiedAt.Time
+
+		require.NoError(t, s.Users.MarkVerified(ctx, user))
+		require.True(t, verifiedAt.Equal(user.VerifiedAt.Time))
+		got, err := s.Users.SelectByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.True(t, verifiedAt.Equal(got.VerifiedAt.Time))
+
+		user.ID = 404
+		require.ErrorIs(t, s.Users.MarkVerified(ctx, user), apperrors.ErrUserNotFound)
+	})
+}
+
+func testSessions(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+	newSession := func(t *testing.T, s *Storers, id string) *users.Session {
+		t.Helper()
+		user := &users.User{Email: id + "@example.com", Password: "hash", Access: users.Passenger}
+		require.NoError(t, s.Users.Insert(ctx, user))
+		now := time.Now().Truncate(time.Second)
+		session := &users.Session{
+			ID:         id,
+			UserID:     user.ID,
+			TokenID:    "token-1",
+			CreatedAt:  now,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(time.Hour),
+			Device:     users.Device{UserAgent: "test-agent", IP: "203.0.113.7"},
+		}
+		require.NoError(t, s.Sessions.Insert(ctx, session))
+		return session
+	}
+
+	t.Run("inserted sessions can be selected", func(t *testing.T) {
+		s := newStorers(t)
+		session := newSession(t, s, "session-1")
+
+		got, err := s.Sessions.SelectByID(ctx, session.ID)
+		require.NoError(t, err)
+		require.Equal(t, session.UserID, got.UserID)
+		require.Equal(t, "token-1", got.TokenID)
+		require.True(t, session.ExpiresAt.Equal(got.ExpiresAt))
+		require.Equal(t, session.Device, got.Device)
+		require.True(t, got.Active(time.Now()))
+	})
+
+	t.Run("missing sessions are not found", func(t *testing.T) {
+		s := newStorers(t)
+		_, err := s.Sessions.SelectByID(ctx, "nope")
+		require.ErrorIs(t, err, apperrors.ErrSessionNotFound)
+		require.ErrorIs(t, s.Sessions.Revoke(ctx, "nope"), apperrors.ErrSessionNotFound)
+	})
+
+	t.Run("sessions need a user", func(t *testing.T) {
+		s := newStorers(t)
+		session := &users.Session{ID: "session-1", UserID: 404, TokenID: "token-1", ExpiresAt: time.Now()}
+		requireAppError(t, s.Sessions.Insert(ctx, session), apperrors.ErrEntityInUse)
+	})
+
+	t.Run("a token is rotated once", func(t *testing.T) {
+		s := newStorers(t)
+		session := newSession(t, s, "session-1")
+		session.TokenID = "token-2"
+		session.ExpiresAt = session.ExpiresAt.Add(time.Hour)
+		require.NoError(t, s.Sessions.Rotate(ctx, session, "token-1"))
+
+		session.TokenID = "token-3"
+		require.ErrorIs(t, s.Sessions.Rotate(ctx, session, "token-1"), apperrors.ErrConflict)
+
+		got, err := s.Sessions.SelectByID(ctx, session.ID)
+		require.NoError(t, err)
+		require.Equal(t, "token-2", got.TokenID)
+		require.True(t, session.ExpiresAt.Equal(got.ExpiresAt))
+	})
+
+	t.Run("revoked sessions are not rotated", func(t *testing.T) {
+		s := newStorers(t)
+		session := newSession(t, s, "session-1")
+		require.NoError(t, s.Sessions.Revoke(ctx, session.ID))
+		require.NoError(t, s.Sessions.Revoke(ctx, session.ID))
+
+		got, err := s.Sessions.SelectByID(ctx, session.ID)
+		require.NoError(t, err)
+		require.False(t, got.Active(time.Now()))
+
+		session.TokenID = "token-2"
+		require.ErrorIs(t, s.Sessions.Rotate(ctx, session, "token-1"), apperrors.ErrConflict)
+	})
+
+	t.Run("all sessions of a user are revoked", func(t *testing.T) {
+		s := newStorers(t)
+		first := newSession(t, s, "session-1")
+		other := newSession(t, s, "session-2")
+		second := &users.Session{
+			ID:         "session-3",
+			UserID:     first.UserID,
+			TokenID:    "token-1",
+			CreatedAt:  first.CreatedAt,
+			LastUsedAt: first.LastUsedAt,
+			ExpiresAt:  first.ExpiresAt,
+		}
+		require.NoError(t, s.Sessions.Insert(ctx, second))
+		require.NoError(t, s.Sessions.RevokeAllForUser(ctx, first.UserID))
+
+		for id, active := range map[string]bool{first.ID: false, second.ID: false, other.ID: true} {
+			got, err := s.Sessions.SelectByID(ctx, id)
+			require.NoError(t, err)
+			require.Equal(t, active, got.Active(time.Now()), id)
+		}
+	})
+
+	t.Run("active sessions are listed by last use", func(t *testing.T) {
+		s := newStorers(t)
+		first := newSession(t, s, "session-1")
+		add := func(id string, lastUsed time.Duration, expires time.Duration) *users.Session {
+			session := &users.Session{
+				ID:         id,
+				UserID:     first.UserID,
+				TokenID:    "token-1",
+				CreatedAt:  first.CreatedAt,
+				LastUsedAt: first.LastUsedAt.Add(lastUsed),
+				ExpiresAt:  first.CreatedAt.Add(expires),
+			}
+			require.NoError(t, s.Sessions.Insert(ctx, session))
+			return session
+		}
+		add("session-2", time.Minute, time.Hour)
+		add("session-3", -time.Minute, time.Hour)
+		add("expired", time.Minute, -time.Hour)
+		revoked := add("revoked", time.Minute, time.Hour)
+		require.NoError(t, s.Sessions.Revoke(ctx, revoked.ID))
+		newSession(t, s, "other-user")
+
+		got, err := s.Sessions.SelectActiveForUser(ctx, first.UserID)
+		require.NoError(t, err)
+		sessionIDs := make([]string, 0, len(got))
+		for _, session := range got {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+		require.Equal(t, []string{"session-2", "session-1", "session-3"}, sessionIDs)
+	})
+}
+
+func testPasswordResets(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+	newReset := func(t *testing.T, s *Storers, userID int64, tokenHash string, expiresIn time.Duration) {
+		t.Helper()
+		reset := &users.PasswordReset{UserID: userID, TokenHash: tokenHash, ExpiresAt: time.Now().Add(expiresIn)}
+		require.NoError(t, s.Resets.Insert(ctx, reset))
+		require.NotZero(t, reset.ID)
+	}
+	newUser := func(t *testing.T, s *Storers, email string) int64 {
+		t.Helper()
+		user := &users.User{Email: email, Password: "hash", Access: users.Passenger}
+		require.NoError(t, s.Users.Insert(ctx, user))
+		return user.ID
+	}
+
+	t.Run("a token is consumed once", func(t *testing.T) {
+		s := newStorers(t)
+		userID := newUser(t, s, "ada@example.com")
+		newReset(t, s, userID, "hash-1", time.Hour)
+
+		got, err := s.Resets.Consume(ctx, "hash-1")
+		require.NoError(t, err)
+		require.Equal(t, userID, got)
+		_, err = s.Resets.Consume(ctx, "hash-1")
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("consuming a token consumes the other tokens of its user", func(t *testing.T) {
+		s := newStorers(t)
+		userID := newUser(t, s, "ada@example.com")
+		otherID := newUser(t, s, "grace@example.com")
+		newReset(t, s, userID, "hash-1", time.Hour)
+		newReset(t, s, userID, "hash-2", time.Hour)
+		newReset(t, s, otherID, "hash-3", time.Hour)
+
+		_, err := s.Resets.Consume(ctx, "hash-2")
+		require.NoError(t, err)
+		_, err = s.Resets.Consume(ctx, "hash-1")
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+		got, err := s.Resets.Consume(ctx, "hash-3")
+		require.NoError(t, err)
+		require.Equal(t, otherID, got)
+	})
+
+	t.Run("expired and unknown tokens are not consumed", func(t *testing.T) {
+		s := newStorers(t)
+		userID := newUser(t, s, "ada@example.com")
+		newReset(t, s, userID, "hash-1", -time.Minute)
+
+		_, err := s.Resets.Consume(ctx, "hash-1")
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+		_, err = s.Resets.Consume(ctx, "unknown")
+		require.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("resets need a user", func(t *testing.T) {
+		s := newStorers(t)
+		reset := &users.PasswordReset{UserID: 404, TokenHash: "hash-1", ExpiresAt: time.Now()}
+		requireAppError(t, s.Resets.Insert(ctx, reset), apperrors.ErrEntityInUse)
+	})
+}
+
+func testUnitOfWork(t *testing.T, newStorers func(t *testing.T) *Storers) {
+	ctx := context.Background()
+
+	t.Run("changes are kept when the work succeeds", func(t *testing.T) {
+		s := newStorers(t)
+		location := &bitsb.Location{Name: "Central"}
+		err := s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+			return s.Locations.Insert(ctx, location)
+		})
+		require.NoError(t, err)
+		_, err = s.Locations.SelectByID(ctx, location.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("changes are undone when the work fails", func(t *testing.T) {
+		s := newStorers(t)
+		kept := insertLocations(t, s, "Central")[0]
+		failure := errors.New("failure")
+		added := &bitsb.Location{Name: "Market"}
+
+		err := s.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+			if err := s.Locations.Insert(ctx, added); err != nil {
+				return err
+			}
+			if err := s.Locations.Delete(ctx, kept.ID); err != nil {
+				return err
+			}
+			// changes are seen inside the work
+			if _, err := s.Locations.SelectByID(ctx, added.ID); err != nil {
+				return err
+			}
+			return failure
+		})
+		require.ErrorIs(t, err, failure)
+
+		_, err = s.Locations.SelectByID(ctx, added.ID)
+		require.ErrorIs(t, err, apperrors.ErrLocationNotFound)
+		_, err = s.Locations.SelectByID(ctx, kept.ID)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/handler"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/tickets"
+	"github.com/sainak/bitsb/users"
+	"github.com/sainak/bitsb/users/delivery/http/middleware"
+)
+
+type TicketHandler struct {
+	service tickets.TicketServiceProvider
+}
+
+func New(service tickets.TicketServiceProvider) *TicketHandler {
+	return &TicketHandler{service}
+}
+
+func (h *TicketHandler) ListAll(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+	limit := handler.GetLimit(r)
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	filters := make(repo.Filters)
+	if status := r.URL.Query().Get("status"); status != "" {
+		filters["status:eq"] = status
+	}
+	if d := r.URL.Query().Get("travel_date"); d != "" {
+		travelDate, err := bitsb.ParseDate(d)
+		if err != nil {
+			api.RespondForError(w, r, apperrors.ErrBadInputParam)
+			return
+		}
+		filters["travel_date:eq"] = travelDate
+	}
+
+	userTickets, page, err := h.service.ListForUser(r.Context(), user.ID, cursor, limit, filters)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	api.RespondList(w, r, userTickets, page)
+}
+
+func (h *TicketHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	ticket, err := h.service.GetForUser(r.Context(), user.ID, id)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, ticket)
+}
+
+func (h *TicketHandler) Book(w http.ResponseWriter, r *http.Request) {
+	data := &tickets.TicketForm{}
+	if err := render.Bind(r, data); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	ticket := &tickets.Ticket{
+		UserID:         user.ID,
+		BusRouteID:     data.BusRouteID,
+		FromLocationID: data.FromLocationID,
+		ToLocationID:   data.ToLocationID,
+		TravelDate:     data.TravelDate,
+		Departure:      data.Departure,
+	}
+
+	if err := h.service.Book(r.Context(), ticket); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, ticket)
+}
+
+func (h *TicketHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	ticket, err := h.service.Cancel(r.Context(), user.ID, id)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, ticket)
+}
+
+func (h *TicketHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	data := &tickets.TicketValidationForm{}
+	if err := render.Bind(r, data); err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	ticket, err := h.service.Validate(r.Context(), data.Pass, data.BusRouteID)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, ticket)
+}
+
+func (h *TicketHandler) PassKey(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, h.service.PassKey())
+}
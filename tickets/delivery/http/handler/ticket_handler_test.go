@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/mocks"
+	"github.com/sainak/bitsb/tickets"
+	"github.com/sainak/bitsb/tickets/service"
+	"github.com/sainak/bitsb/users"
+	"github.com/sainak/bitsb/users/delivery/http/middleware"
+)
+
+type TicketHandlerTestSuite struct {
+	suite.Suite
+	handler *TicketHandler
+	service *mocks.TicketServiceProvider
+}
+
+func TestTicketHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(TicketHandlerTestSuite))
+}
+
+func (s *TicketHandlerTestSuite) SetupTest() {
+	s.service = new(mocks.TicketServiceProvider)
+	s.handler = New(s.service)
+}
+
+// request returns a request of the user with the id url param set
+func request(method, url, body, id string) *http.Request {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, middleware.UserCtxKey, &users.User{ID: 7})
+	return r.WithContext(ctx)
+}
+
+func (s *TicketHandlerTestSuite) TestBook() {
+	t := s.T()
+
+	url := "/tickets"
+	body := `{"bus_route_id": 1, "from_location_id": 1, "to_location_id": 3, "travel_date": "2099-01-05", "departure": "08:00"}`
+
+	t.Run("when the ticket is booked", func(t *testing.T) {
+		s.service.
+			On("Book", mock.Anything, mock.MatchedBy(func(ticket *tickets.Ticket) bool {
+				return ticket.UserID == 7 && ticket.BusRouteID == 1 && ticket.Departure.Format("15:04") == "08:00"
+			})).
+			Return(nil).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Book(w, request(http.MethodPost, url, body, ""))
+
+		require.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("when the departure is not a time of day", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handler.Book(w, request(http.MethodPost, url, strings.Replace(body, "08:00", "8am", 1), ""))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("when a field is missing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handler.Book(w, request(http.MethodPost, url, `{"bus_route_id": 1}`, ""))
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("when the trip has already departed", func(t *testing.T) {
+		s.service.
+			On("Book", mock.Anything, mock.Anything).
+			Return(service.ErrDeparted).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Book(w, request(http.MethodPost, url, body, ""))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		require.Contains(t, w.Body.String(), "ticket.departed")
+	})
+}
+
+func (s *TicketHandlerTestSuite) TestCancel() {
+	t := s.T()
+
+	url := "/tickets/1/cancel"
+
+	t.Run("when the ticket is cancelled", func(t *testing.T) {
+		s.service.
+			On("Cancel", mock.Anything, int64(7), int64(1)).
+			Return(&tickets.Ticket{ID: 1, Status: tickets.StatusCancelled}, nil).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Cancel(w, request(http.MethodPost, url, "", "1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("when the id is not a number", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handler.Cancel(w, request(http.MethodPost, url, "", "one"))
+
+		require.NotEqual(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("when the ticket is of another user", func(t *testing.T) {
+		s.service.
+			On("Cancel", mock.Anything, int64(7), int64(2)).
+			Return(&tickets.Ticket{}, apperrors.ErrTicketNotFound).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Cancel(w, request(http.MethodPost, url, "", "2"))
+
+		require.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("when the ticket is already used", func(t *testing.T) {
+		s.service.
+			On("Cancel", mock.Anything, int64(7), int64(3)).
+			Return(&tickets.Ticket{}, service.ErrUsed).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Cancel(w, request(http.MethodPost, url, "", "3"))
+
+		require.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func (s *TicketHandlerTestSuite) TestValidate() {
+	t := s.T()
+
+	url := "/tickets/validate"
+
+	t.Run("when the pass is valid", func(t *testing.T) {
+		s.service.
+			On("Validate", mock.Anything, "pass", int64(1)).
+			Return(&tickets.Ticket{ID: 1, Status: tickets.StatusUsed}, nil).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Validate(w, request(http.MethodPost, url, `{"pass": "pass", "bus_route_id": 1}`, ""))
+
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("when the pass is missing", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handler.Validate(w, request(http.MethodPost, url, `{"bus_route_id": 1}`, ""))
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("when the pass is replayed", func(t *testing.T) {
+		s.service.
+			On("Validate", mock.Anything, "used", int64(1)).
+			Return(&tickets.Ticket{}, service.ErrUsed).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Validate(w, request(http.MethodPost, url, `{"pass": "used", "bus_route_id": 1}`, ""))
+
+		require.Equal(t, http.StatusConflict, w.Code)
+		require.Contains(t, w.Body.String(), "pass.used")
+	})
+
+	t.Run("when the pass is for another route", func(t *testing.T) {
+		s.service.
+			On("Validate", mock.Anything, "pass", int64(2)).
+			Return(&tickets.Ticket{}, service.ErrWrongRoute).
+			Once()
+
+		w := httptest.NewRecorder()
+		s.handler.Validate(w, request(http.MethodPost, url, `{"pass": "pass", "bus_route_id": 2}`, ""))
+
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
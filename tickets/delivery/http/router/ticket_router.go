@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sainak/bitsb/tickets"
+	"github.com/sainak/bitsb/tickets/delivery/http/handler"
+	"github.com/sainak/bitsb/users"
+	"github.com/sainak/bitsb/users/delivery/http/middleware"
+)
+
+func RegisterRoutes(
+	router chi.Router,
+	service tickets.TicketServiceProvider,
+	jwtMiddleware func(next http.Handler) http.Handler,
+) {
+	h := handler.New(service)
+
+	router.Group(func(r chi.Router) {
+		r.Use(jwtMiddleware)
+		r.Route("/tickets", func(r chi.Router) {
+			r.Get("/", h.ListAll)
+			r.With(middleware.EmailVerified).Post("/", h.Book)
+			r.Get("/{id}", h.GetByID)
+			r.Post("/{id}/cancel", h.Cancel)
+			r.Get("/pass-key", h.PassKey)
+			r.With(middleware.AccessAbove(users.Driver)).Post("/validate", h.Validate)
+		})
+	})
+}
@@ -0,0 +1,124 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/pkg/validate"
+)
+
+const (
+	StatusBooked    = "booked"
+	StatusCancelled = "cancelled"
+	StatusUsed      = "used"
+)
+
+// Ticket is a seat booked by a user on one trip of a bus route.
+// Departure and Arrival are the times the trip reaches the
+// boarding and alighting stops on TravelDate.
+// Pass is the signed token of a booked ticket that is shown to the driver.
+type Ticket struct {
+	ID             int64      `json:"id" db:"id"`
+	UserID         int64      `json:"user_id" db:"user_id"`
+	BusRouteID     int64      `json:"bus_route_id" db:"bus_route_id"`
+	FromLocationID int64      `json:"from_location_id" db:"from_location_id"`
+	ToLocationID   int64      `json:"to_location_id" db:"to_location_id"`
+	TravelDate     bitsb.Date `json:"travel_date" db:"travel_date"`
+	Departure      time.Time  `json:"departure" db:"departure_time"`
+	Arrival        time.Time  `json:"arrival" db:"arrival_time"`
+	Price          int64      `json:"price" db:"price"`
+	Status         string     `json:"status" db:"status"`
+	CancelledAt    null.Time  `json:"cancelled_at" db:"cancelled_at"`
+	UsedAt         null.Time  `json:"used_at" db:"used_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	Pass           string     `json:"pass,omitempty"`
+}
+
+func (t *Ticket) MarshalJSON() ([]byte, error) {
+	type Alias Ticket
+	return json.Marshal(&struct {
+		*Alias
+		Departure string `json:"departure"`
+		Arrival   string `json:"arrival"`
+	}{
+		Alias:     (*Alias)(t),
+		Departure: t.Departure.Format("15:04"),
+		Arrival:   t.Arrival.Format("15:04"),
+	})
+}
+
+type TicketForm struct {
+	BusRouteID     int64      `json:"bus_route_id" binding:"required"`
+	FromLocationID int64      `json:"from_location_id" binding:"required"`
+	ToLocationID   int64      `json:"to_location_id" binding:"required"`
+	TravelDate     bitsb.Date `json:"travel_date" binding:"required"`
+	Departure      time.Time  `json:"departure" binding:"required"`
+}
+
+func (t *TicketForm) Bind(r *http.Request) error {
+	return validate.Struct(t).Err()
+}
+
+func (t *TicketForm) UnmarshalJSON(data []byte) error {
+	type Alias TicketForm
+	aux := &struct {
+		*Alias
+		Departure string `json:"departure"`
+	}{
+		Alias: (*Alias)(t),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Departure != "" {
+		departure, err := time.Parse("15:04", aux.Departure)
+		if err != nil {
+			return apperrors.ErrBadInputParam.Wrap(err)
+		}
+		t.Departure = departure
+	}
+	return nil
+}
+
+// TicketValidationForm is sent by a driver to check the pass of a passenger boarding the bus route
+type TicketValidationForm struct {
+	Pass       string `json:"pass" binding:"required"`
+	BusRouteID int64  `json:"bus_route_id" binding:"required"`
+}
+
+func (t *TicketValidationForm) Bind(r *http.Request) error {
+	return validate.Struct(t).Err()
+}
+
+// PassKey is the public key passes can be verified with offline
+type PassKey struct {
+	Algorithm string `json:"alg"`
+	PublicKey string `json:"public_key"`
+}
+
+type (
+	TicketStorer interface {
+		SelectAllForUser(ctx context.Context, userID int64, cursor string, limit int64, filters repo.Filters) ([]*Ticket, repo.Page, error)
+		SelectByID(ctx context.Context, id int64) (*Ticket, error)
+		Insert(ctx context.Context, ticket *Ticket) error
+		MarkCancelled(ctx context.Context, ticket *Ticket) error
+		MarkUsed(ctx context.Context, ticket *Ticket) error
+	}
+
+	TicketServiceProvider interface {
+		ListForUser(ctx context.Context, userID int64, cursor string, limit int64, filters repo.Filters) ([]*Ticket, repo.Page, error)
+		GetForUser(ctx context.Context, userID, id int64) (*Ticket, error)
+		Book(ctx context.Context, ticket *Ticket) error
+		Cancel(ctx context.Context, userID, id int64) (*Ticket, error)
+		Validate(ctx context.Context, pass string, busRouteID int64) (*Ticket, error)
+		PassKey() *PassKey
+	}
+)
@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/tickets"
+)
+
+// ticketColumns are the columns tickets can be filtered on
+var ticketColumns = repo.Columns{
+	"status":       "status",
+	"bus_route_id": "bus_route_id",
+	"travel_date":  "travel_date",
+}
+
+// ticketSorts are the columns tickets can be sorted on
+var ticketSorts = repo.SortColumns{
+	"created_at": {Column: "created_at", Type: "timestamptz"},
+}
+
+var defaultTicketSort = repo.Sort{Key: "created_at", Desc: true}
+
+type TicketRepository struct {
+	conn *sql.DB
+}
+
+func NewTicketRepository(conn *sql.DB) tickets.TicketStorer {
+	return &TicketRepository{conn}
+}
+
+func (t TicketRepository) SelectAllForUser(
+	ctx context.Context,
+	userID int64,
+	cursor string,
+	limit int64,
+	filters repo.Filters,
+) ([]*tickets.Ticket, repo.Page, error) {
+	// the user is part of the filters so a cursor only pages through the tickets of its user
+	paginator, err := repo.NewPaginator(cursor, limit, defaultTicketSort, ticketSorts, []interface{}{userID, filters})
+	if err != nil {
+		return []*tickets.Ticket{}, repo.Page{}, err
+	}
+
+	conditions := []string{"user_id = $2"}
+	filterConditions, args, err := filters.Build(ticketColumns, []interface{}{paginator.Limit(), userID})
+	if err != nil {
+		return []*tickets.Ticket{}, repo.Page{}, err
+	}
+	if filterConditions != "" {
+		conditions = append(conditions, filterConditions)
+	}
+	after, args := paginator.Condition(args)
+	if after != "" {
+		conditions = append(conditions, after)
+	}
+	query := `SELECT id, user_id, bus_route_id, from_location_id, to_location_id, travel_date, departure_time, departure_day_offset,
+					arrival_time, arrival_day_offset, price, status, cancelled_at, used_at, created_at, updated_at
+				FROM tickets
+				WHERE ` + strings.Join(conditions, " AND ") + `
+				ORDER BY ` + paginator.OrderBy() + ` LIMIT $1;`
+
+	rows, err := repo.Conn(ctx, t.conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		return []*tickets.Ticket{}, repo.Page{}, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+
+	result := make([]*tickets.Ticket, 0)
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			return []*tickets.Ticket{}, repo.Page{}, err
+		}
+		result = append(result, ticket)
+	}
+	if err = rows.Err(); err != nil {
+		return []*tickets.Ticket{}, repo.Page{}, err
+	}
+
+	result, page := repo.Paginate(paginator, result, func(ticket *tickets.Ticket) (string, int64) {
+		return ticket.CreatedAt.Format(time.RFC3339Nano), ticket.ID
+	})
+	return result, page, nil
+}
+
+func (t TicketRepository) SelectByID(ctx context.Context, id int64) (*tickets.Ticket, error) {
+	query := `SELECT id, user_id, bus_route_id, from_location_id, to_location_id, travel_date, departure_time, departure_day_offset,
+					arrival_time, arrival_day_offset, price, status, cancelled_at, used_at, created_at, updated_at
+				FROM tickets
+				WHERE id = $1;`
+
+	ticket, err := scanTicket(repo.Conn(ctx, t.conn).QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrTicketNotFound.Wrap(err)
+	}
+	return ticket, err
+}
+
+func (t TicketRepository) Insert(ctx context.Context, ticket *tickets.Ticket) error {
+	query := `INSERT INTO tickets (user_id, bus_route_id, from_location_id, to_location_id, travel_date,
+					departure_time, departure_day_offset, arrival_time, arrival_day_offset, price, status, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				RETURNING id;`
+
+	currentTime := time.Now()
+	ticket.CreatedAt = currentTime
+	ticket.UpdatedAt = currentTime
+
+	return repo.Conn(ctx, t.conn).QueryRowContext(
+		ctx,
+		query,
+		ticket.UserID,
+		ticket.BusRouteID,
+		ticket.FromLocationID,
+		ticket.ToLocationID,
+		ticket.TravelDate,
+		ticket.Departure,
+		dayOffset(ticket.Departure),
+		ticket.Arrival,
+		dayOffset(ticket.Arrival),
+		ticket.Price,
+		ticket.Status,
+		ticket.CreatedAt,
+		ticket.UpdatedAt,
+	).Scan(&ticket.ID)
+}
+
+// scanTicket scans a row of the ticket columns, the stop times are moved
+// to the day after the travel date they fall on
+func scanTicket(row interface{ Scan(dest ...any) error }) (*tickets.Ticket, error) {
+	ticket := &tickets.Ticket{}
+	var departureOffset, arrivalOffset int
+	err := row.Scan(
+		&ticket.ID,
+		&ticket.UserID,
+		&ticket.BusRouteID,
+		&ticket.FromLocationID,
+		&ticket.ToLocationID,
+		&ticket.TravelDate,
+		&ticket.Departure,
+		&departureOffset,
+		&ticket.Arrival,
+		&arrivalOffset,
+		&ticket.Price,
+		&ticket.Status,
+		&ticket.CancelledAt,
+		&ticket.UsedAt,
+		&ticket.CreatedAt,
+		&ticket.UpdatedAt,
+	)
+	ticket.Departure = ticket.Departure.AddDate(0, 0, departureOffset)
+	ticket.Arrival = ticket.Arrival.AddDate(0, 0, arrivalOffset)
+	return ticket, err
+}
+
+// dayOffset returns the days a stop time of a timetable is past its service day,
+// the stop times of trips running past midnight fall on the following days
+func dayOffset(timeOfDay time.Time) int {
+	return timeOfDay.Day() - 1
+}
+
+// MarkCancelled marks a booked ticket as cancelled, it fails with apperrors.ErrConflict
+// when the ticket is no longer booked so a used ticket cannot be cancelled
+func (t TicketRepository) MarkCancelled(ctx context.Context, ticket *tickets.Ticket) error {
+	query := `UPDATE tickets
+				SET status = $2, cancelled_at = $3, updated_at = $3
+				WHERE id = $1 AND status = $4;`
+
+	currentTime := time.Now()
+
+	res, err := repo.Conn(ctx, t.conn).ExecContext(ctx, query, ticket.ID, tickets.StatusCancelled, currentTime, tickets.StatusBooked)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return apperrors.ErrConflict
+	}
+	ticket.Status = tickets.StatusCancelled
+	ticket.CancelledAt = null.TimeFrom(currentTime)
+	ticket.UpdatedAt = currentTime
+	return nil
+}
+
+// MarkUsed marks a booked ticket as used, it fails with apperrors.ErrConflict
+// when the ticket is no longer booked so a pass can only be used once
+func (t TicketRepository) MarkUsed(ctx context.Context, ticket *tickets.Ticket) error {
+	query := `UPDATE tickets
+				SET status = $2, used_at = $3, updated_at = $3
+				WHERE id = $1 AND status = $4;`
+
+	currentTime := time.Now()
+
+	res, err := repo.Conn(ctx, t.conn).ExecContext(ctx, query, ticket.ID, tickets.StatusUsed, currentTime, tickets.StatusBooked)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return apperrors.ErrConflict
+	}
+	ticket.Status = tickets.StatusUsed
+	ticket.UsedAt = null.TimeFrom(currentTime)
+	ticket.UpdatedAt = currentTime
+	return nil
+}
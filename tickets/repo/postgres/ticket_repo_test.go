@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/undefinedlabs/go-mpatch"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/tickets"
+)
+
+type TicketRepositoryTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+	repo tickets.TicketStorer
+}
+
+func TestTicketRepositoryTestSuite(t *testing.T) {
+	patch, err := mpatch.PatchMethod(time.Now, func() time.Time {
+		return time.Date(2020, 11, 01, 00, 00, 00, 0, time.UTC)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(patch *mpatch.Patch) {
+		if err := patch.Unpatch(); err != nil {
+			t.Fatal(err)
+		}
+	}(patch)
+
+	suite.Run(t, new(TicketRepositoryTestSuite))
+}
+
+func (s *TicketRepositoryTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.db = db
+	s.mock = mock
+	s.repo = NewTicketRepository(db)
+}
+
+func (s *TicketRepositoryTestSuite) TearDownTest() {
+	require.NoError(s.T(), s.mock.ExpectationsWereMet())
+}
+
+// clock returns a time of day as lib/pq scans TIME columns
+func clock(value string) time.Time {
+	t, _ := time.Parse("15:04", value)
+	return t
+}
+
+func ticketRows() *sqlmock.Rows {
+	return sqlmock.
+		NewRows([]string{
+			"id",
+			"user_id",
+			"bus_route_id",
+			"from_location_id",
+			"to_location_id",
+			"travel_date",
+			"departure_time",
+			"departure_day_offset",
+			"arrival_time",
+			"arrival_day_offset",
+			"price",
+			"status",
+			"cancelled_at",
+			"used_at",
+			"created_at",
+			"updated_at",
+		}).
+		AddRow(2, 7, 1, 1, 3, time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC), clock("23:50"), 0, clock("00:10"), 1,
+			20, tickets.StatusBooked, nil, nil, time.Now(), time.Now()).
+		AddRow(1, 7, 1, 1, 3, time.Date(2020, 11, 2, 0, 0, 0, 0, time.UTC), clock("00:30"), 1, clock("00:50"), 1,
+			20, tickets.StatusBooked, nil, nil, time.Now(), time.Now())
+}
+
+func (s *TicketRepositoryTestSuite) TestSelectAllForUser() {
+	t := s.T()
+
+	filters := repo.Filters{"status:eq": tickets.StatusBooked}
+
+	t.Run("when the tickets of the user are filtered", func(t *testing.T) {
+		s.mock.ExpectQuery(regexp.QuoteMeta("WHERE user_id = $2 AND status = $3 ORDER BY created_at DESC, id DESC LIMIT $1;")).
+			WithArgs(int64(2), int64(7), tickets.StatusBooked).
+			WillReturnRows(ticketRows())
+
+		got, page, err := s.repo.SelectAllForUser(context.Background(), 7, "", 1, filters)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.True(t, page.HasMore)
+
+		next, err := repo.DecodeCursor(page.Next)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), next.ID)
+		require.Equal(t, "-created_at", next.Sort)
+	})
+
+	t.Run("when paging with a cursor", func(t *testing.T) {
+		cursor := &repo.Cursor{
+			Sort:    "-created_at",
+			Value:   "2020-11-01T00:00:00Z",
+			ID:      3,
+			Filters: repo.Fingerprint([]interface{}{int64(7), filters}),
+		}
+		s.mock.ExpectQuery(regexp.QuoteMeta("WHERE user_id = $2 AND status = $3 AND (created_at, id) < (cast($4 as timestamptz), $5)")).
+			WithArgs(int64(11), int64(7), tickets.StatusBooked, "2020-11-01T00:00:00Z", int64(3)).
+			WillReturnRows(ticketRows())
+
+		got, page, err := s.repo.SelectAllForUser(context.Background(), 7, cursor.Encode(), 10, filters)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.False(t, page.HasMore)
+		require.NotEmpty(t, page.Prev)
+	})
+
+	t.Run("when the cursor was made for another user", func(t *testing.T) {
+		cursor := &repo.Cursor{
+			Sort:    "-created_at",
+			Value:   "2020-11-01T00:00:00Z",
+			ID:      3,
+			Filters: repo.Fingerprint([]interface{}{int64(8), filters}),
+		}
+
+		_, _, err := s.repo.SelectAllForUser(context.Background(), 7, cursor.Encode(), 10, filters)
+		require.ErrorIs(t, err, apperrors.ErrBadCursor)
+	})
+
+	t.Run("when the filter is on an unknown column", func(t *testing.T) {
+		_, _, err := s.repo.SelectAllForUser(context.Background(), 7, "", 10, repo.Filters{"pass:eq": "x"})
+		require.Error(t, err)
+	})
+}
+
+func (s *TicketRepositoryTestSuite) TestSelectByID() {
+	t := s.T()
+
+	t.Run("when the stop times are past midnight", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM tickets WHERE id = \\$1").
+			WithArgs(int64(1)).
+			WillReturnRows(ticketRows())
+
+		got, err := s.repo.SelectByID(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(0, 1, 1, 23, 50, 0, 0, time.UTC), got.Departure)
+		require.Equal(t, time.Date(0, 1, 2, 0, 10, 0, 0, time.UTC), got.Arrival)
+	})
+
+	t.Run("when the ticket does not exist", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM tickets WHERE id = \\$1").
+			WithArgs(int64(404)).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := s.repo.SelectByID(context.Background(), 404)
+		require.ErrorIs(t, err, apperrors.ErrTicketNotFound)
+	})
+}
+
+func (s *TicketRepositoryTestSuite) TestInsert() {
+	t := s.T()
+
+	t.Run("when the trip runs past midnight", func(t *testing.T) {
+		travelDate, _ := bitsb.ParseDate("2020-11-02")
+		ticket := &tickets.Ticket{
+			UserID:         7,
+			BusRouteID:     1,
+			FromLocationID: 1,
+			ToLocationID:   3,
+			TravelDate:     travelDate,
+			Departure:      clock("00:30").Add(24 * time.Hour),
+			Arrival:        clock("00:50").Add(24 * time.Hour),
+			Price:          20,
+			Status:         tickets.StatusBooked,
+		}
+		s.mock.ExpectQuery("INSERT INTO tickets").
+			WithArgs(int64(7), int64(1), int64(1), int64(3), travelDate, ticket.Departure, 1, ticket.Arrival, 1,
+				int64(20), tickets.StatusBooked, time.Now(), time.Now()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		require.NoError(t, s.repo.Insert(context.Background(), ticket))
+		require.Equal(t, int64(1), ticket.ID)
+	})
+}
+
+func (s *TicketRepositoryTestSuite) TestMarkUsed() {
+	t := s.T()
+
+	query := regexp.QuoteMeta("UPDATE tickets SET status = $2, used_at = $3, updated_at = $3 WHERE id = $1 AND status = $4;")
+
+	t.Run("when the ticket is booked", func(t *testing.T) {
+		s.mock.ExpectExec(query).
+			WithArgs(int64(1), tickets.StatusUsed, time.Now(), tickets.StatusBooked).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ticket := &tickets.Ticket{ID: 1, Status: tickets.StatusBooked}
+		require.NoError(t, s.repo.MarkUsed(context.Background(), ticket))
+		require.Equal(t, tickets.StatusUsed, ticket.Status)
+		require.True(t, ticket.UsedAt.Valid)
+	})
+
+	t.Run("when the ticket is no longer booked", func(t *testing.T) {
+		s.mock.ExpectExec(query).
+			WithArgs(int64(1), tickets.StatusUsed, time.Now(), tickets.StatusBooked).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		ticket := &tickets.Ticket{ID: 1, Status: tickets.StatusBooked}
+		require.ErrorIs(t, s.repo.MarkUsed(context.Background(), ticket), apperrors.ErrConflict)
+		require.Equal(t, tickets.StatusBooked, ticket.Status)
+	})
+}
+
+func (s *TicketRepositoryTestSuite) TestMarkCancelled() {
+	t := s.T()
+
+	query := regexp.QuoteMeta("UPDATE tickets SET status = $2, cancelled_at = $3, updated_at = $3 WHERE id = $1 AND status = $4;")
+
+	t.Run("when the ticket is booked", func(t *testing.T) {
+		s.mock.ExpectExec(query).
+			WithArgs(int64(1), tickets.StatusCancelled, time.Now(), tickets.StatusBooked).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ticket := &tickets.Ticket{ID: 1, Status: tickets.StatusBooked}
+		require.NoError(t, s.repo.MarkCancelled(context.Background(), ticket))
+		require.Equal(t, tickets.StatusCancelled, ticket.Status)
+		require.True(t, ticket.CancelledAt.Valid)
+	})
+
+	t.Run("when the ticket is no longer booked", func(t *testing.T) {
+		s.mock.ExpectExec(query).
+			WithArgs(int64(1), tickets.StatusCancelled, time.Now(), tickets.StatusBooked).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		ticket := &tickets.Ticket{ID: 1, Status: tickets.StatusUsed}
+		require.ErrorIs(t, s.repo.MarkCancelled(context.Background(), ticket), apperrors.ErrConflict)
+	})
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/pkg/jwt"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/tickets"
+)
+
+var (
+	ErrNoSuchTrip = apperrors.NewWithCode(http.StatusBadRequest, "ticket.no_such_trip", "the route has no trip leaving the stop at the given time")
+	ErrDeparted   = apperrors.NewWithCode(http.StatusBadRequest, "ticket.departed", "the trip has already departed")
+	ErrCancelled  = apperrors.NewWithCode(http.StatusConflict, "ticket.cancelled", "the ticket is already cancelled")
+
+	ErrInvalidPass = apperrors.NewWithCode(http.StatusBadRequest, "pass.invalid", "the pass is invalid")
+	ErrWrongRoute  = apperrors.NewWithCode(http.StatusBadRequest, "pass.wrong_route", "the pass is for another bus route")
+	ErrNotBoarding = apperrors.NewWithCode(http.StatusBadRequest, "pass.not_boarding", "the pass is not valid at this time")
+	ErrUsed        = apperrors.NewWithCode(http.StatusConflict, "pass.used", "the pass has already been used")
+)
+
+const (
+	// boardingOpens is how long before the departure of its trip a pass can be used
+	boardingOpens = 30 * time.Minute
+	// boardingCloses is how long after the arrival of its trip a pass can still be used
+	boardingCloses = 30 * time.Minute
+)
+
+type TicketService struct {
+	repo            tickets.TicketStorer
+	busRouteService bitsb.BusRouteServiceProvider
+	signer          *jwt.TicketSigner
+	uow             repo.UnitOfWork
+}
+
+func NewTicketService(
+	r tickets.TicketStorer,
+	b bitsb.BusRouteServiceProvider,
+	signer *jwt.TicketSigner,
+	uow repo.UnitOfWork,
+) tickets.TicketServiceProvider {
+	return &TicketService{
+		repo:            r,
+		busRouteService: b,
+		signer:          signer,
+		uow:             uow,
+	}
+}
+
+func (t *TicketService) ListForUser(
+	ctx context.Context,
+	userID int64,
+	cursor string,
+	limit int64,
+	filters repo.Filters,
+) ([]*tickets.Ticket, repo.Page, error) {
+	return t.repo.SelectAllForUser(ctx, userID, cursor, limit, filters)
+}
+
+// GetForUser returns the ticket when it belongs to the user,
+// tickets of other users are reported as not found
+func (t *TicketService) GetForUser(ctx context.Context, userID, id int64) (*tickets.Ticket, error) {
+	ticket, err := t.repo.SelectByID(ctx, id)
+	if err != nil {
+		return &tickets.Ticket{}, err
+	}
+	if ticket.UserID != userID {
+		return &tickets.Ticket{}, apperrors.ErrTicketNotFound
+	}
+	if ticket.Status == tickets.StatusBooked {
+		if ticket.Pass, err = t.sign(ticket); err != nil {
+			return &tickets.Ticket{}, err
+		}
+	}
+	return ticket, nil
+}
+
+// Book checks that a trip of the route leaves the boarding stop at the
+// departure time of the ticket on its travel date, then records the ticket
+// with the arrival time and the price of travelling between its stops
+func (t *TicketService) Book(ctx context.Context, ticket *tickets.Ticket) error {
+	err := t.uow.Do(ctx, func(ctx context.Context) error {
+		timetable, err := t.busRouteService.Timetable(ctx, ticket.BusRouteID, ticket.TravelDate)
+		if err != nil {
+			return err
+		}
+		departure, arrival, err := findTrip(timetable, ticket)
+		if err != nil {
+			return err
+		}
+		if departsAt(ticket.TravelDate, departure).Before(wallClock(time.Now())) {
+			return ErrDeparted
+		}
+
+		fare, err := t.busRouteService.CalculateTicketPrice(ctx, ticket.BusRouteID, ticket.FromLocationID, ticket.ToLocationID)
+		if err != nil {
+			return err
+		}
+
+		ticket.Departure = departure
+		ticket.Arrival = arrival
+		ticket.Price = fare.TicketPrice
+		ticket.Status = tickets.StatusBooked
+		return t.repo.Insert(ctx, ticket)
+	})
+	if err != nil {
+		return err
+	}
+	ticket.Pass, err = t.sign(ticket)
+	return err
+}
+
+// Cancel cancels a booked ticket of the user before its trip departs, the ticket is
+// only marked cancelled while it is still booked so a pass validated meanwhile wins
+func (t *TicketService) Cancel(ctx context.Context, userID, id int64) (*tickets.Ticket, error) {
+	ticket := &tickets.Ticket{}
+	err := t.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		ticket, err = t.GetForUser(ctx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := notBooked(ticket.Status); err != nil {
+			return err
+		}
+		if departsAt(ticket.TravelDate, ticket.Departure).Before(wallClock(time.Now())) {
+			return ErrDeparted
+		}
+
+		err = t.repo.MarkCancelled(ctx, ticket)
+		if errors.Is(err, apperrors.ErrConflict) {
+			// the ticket was used or cancelled by another request in the meantime
+			current, selectErr := t.repo.SelectByID(ctx, ticket.ID)
+			if selectErr != nil {
+				return selectErr
+			}
+			if err := notBooked(current.Status); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		ticket.Pass = ""
+		return nil
+	})
+	if err != nil {
+		return &tickets.Ticket{}, err
+	}
+	return ticket, nil
+}
+
+// Validate checks the pass of a passenger boarding the bus route and marks its ticket as used.
+// The signature, route and boarding window are checked from the pass alone, like a driver
+// without a connection would, then the ticket is checked so a pass is only accepted once.
+func (t *TicketService) Validate(ctx context.Context, pass string, busRouteID int64) (*tickets.Ticket, error) {
+	claims, err := t.signer.Parse(pass)
+	if err != nil {
+		return &tickets.Ticket{}, ErrInvalidPass
+	}
+	if claims.BusRouteID != busRouteID {
+		return &tickets.Ticket{}, ErrWrongRoute
+	}
+	opens, closes, err := boardingWindow(claims)
+	if err != nil {
+		return &tickets.Ticket{}, ErrInvalidPass
+	}
+	if now := wallClock(time.Now()); now.Before(opens) || now.After(closes) {
+		return &tickets.Ticket{}, ErrNotBoarding
+	}
+
+	ticket := &tickets.Ticket{}
+	err = t.uow.Do(ctx, func(ctx context.Context) error {
+		ticket, err = t.repo.SelectByID(ctx, claims.TicketID)
+		if err != nil {
+			return err
+		}
+		if err := notBooked(ticket.Status); err != nil {
+			return err
+		}
+		err = t.repo.MarkUsed(ctx, ticket)
+		if errors.Is(err, apperrors.ErrConflict) {
+			// the pass was used by another request in the meantime
+			err = ErrUsed
+		}
+		return err
+	})
+	if err != nil {
+		return &tickets.Ticket{}, err
+	}
+	return ticket, nil
+}
+
+func (t *TicketService) PassKey() *tickets.PassKey {
+	return &tickets.PassKey{
+		Algorithm: "EdDSA",
+		PublicKey: base64.StdEncoding.EncodeToString(t.signer.PublicKey()),
+	}
+}
+
+// notBooked returns the error of acting on a ticket with the given status
+// when the ticket is no longer booked
+func notBooked(status string) error {
+	switch status {
+	case tickets.StatusBooked:
+		return nil
+	case tickets.StatusCancelled:
+		return ErrCancelled
+	default:
+		return ErrUsed
+	}
+}
+
+// sign returns the pass of the ticket
+func (t *TicketService) sign(ticket *tickets.Ticket) (string, error) {
+	return t.signer.Sign(&jwt.TicketClaims{
+		TicketID:       ticket.ID,
+		BusRouteID:     ticket.BusRouteID,
+		FromLocationID: ticket.FromLocationID,
+		ToLocationID:   ticket.ToLocationID,
+		TravelDate:     ticket.TravelDate.String(),
+		Departure:      ticket.Departure.Format("15:04"),
+		Arrival:        ticket.Arrival.Format("15:04"),
+	})
+}
+
+// boardingWindow returns the times between which the pass can be used
+func boardingWindow(claims *jwt.TicketClaims) (time.Time, time.Time, error) {
+	date, err := bitsb.ParseDate(claims.TravelDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	departure, err := time.Parse("15:04", claims.Departure)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	arrival, err := time.Parse("15:04", claims.Arrival)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if arrival.Before(departure) {
+		// the trip runs past midnight
+		arrival = arrival.Add(24 * time.Hour)
+	}
+	return departsAt(date, departure).Add(-boardingOpens), departsAt(date, arrival).Add(boardingCloses), nil
+}
+
+// findTrip returns the times the trip of the ticket reaches its boarding and alighting stops
+func findTrip(timetable *bitsb.Timetable, ticket *tickets.Ticket) (time.Time, time.Time, error) {
+	departure := ticket.Departure.Format("15:04")
+	for _, trip := range timetable.Trips {
+		from, to := -1, -1
+		for i, stop := range trip.Stops {
+			switch stop.LocationID {
+			case ticket.FromLocationID:
+				from = i
+			case ticket.ToLocationID:
+				to = i
+			}
+		}
+		if from == -1 || to == -1 || from >= to {
+			return time.Time{}, time.Time{}, apperrors.ErrInvalidLocation
+		}
+		if trip.Stops[from].Time.Format("15:04") == departure {
+			return trip.Stops[from].Time, trip.Stops[to].Time, nil
+		}
+	}
+	return time.Time{}, time.Time{}, ErrNoSuchTrip
+}
+
+// departsAt returns the moment a trip of the service day date reaches a stop
+// at the given time of day, which can be past midnight for late trips
+func departsAt(date bitsb.Date, timeOfDay time.Time) time.Time {
+	midnight := time.Date(timeOfDay.Year(), timeOfDay.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return date.Add(timeOfDay.Sub(midnight))
+}
+
+// wallClock returns the local date and time of t as if it were in UTC,
+// so it can be compared with dates of service days
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/undefinedlabs/go-mpatch"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/bitsb"
+	"github.com/sainak/bitsb/mocks"
+	"github.com/sainak/bitsb/pkg/jwt"
+	"github.com/sainak/bitsb/tickets"
+)
+
+type TicketServiceTestSuite struct {
+	suite.Suite
+	service         tickets.TicketServiceProvider
+	repo            *mocks.TicketStorer
+	busRouteService *mocks.BusRouteServiceProvider
+	signer          *jwt.TicketSigner
+	uow             *mocks.UnitOfWork
+}
+
+func TestTicketServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TicketServiceTestSuite))
+}
+
+func (s *TicketServiceTestSuite) SetupTest() {
+	s.repo = mocks.NewTicketStorer(s.T())
+	s.busRouteService = mocks.NewBusRouteServiceProvider(s.T())
+	signer, err := jwt.NewTicketSigner("")
+	require.NoError(s.T(), err)
+	s.signer = signer
+	s.uow = mocks.NewUnitOfWork(s.T())
+	s.uow.On("Do", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) }).
+		Maybe()
+	s.service = NewTicketService(s.repo, s.busRouteService, signer, s.uow)
+}
+
+func clock(t *testing.T, value string) time.Time {
+	c, err := time.Parse("15:04", value)
+	require.NoError(t, err)
+	return c
+}
+
+func (s *TicketServiceTestSuite) TestBook() {
+	t := s.T()
+
+	travelDate, _ := bitsb.ParseDate("2099-01-05")
+	timetable := &bitsb.Timetable{
+		BusRouteID: 1,
+		Date:       travelDate,
+		Trips: []*bitsb.Trip{
+			{
+				Departure: clock(t, "08:00"),
+				Stops: []*bitsb.StopTime{
+					{LocationID: 1, Time: clock(t, "08:00")},
+					{LocationID: 2, Time: clock(t, "08:05")},
+					{LocationID: 3, Time: clock(t, "08:15")},
+				},
+			},
+			{
+				Departure: clock(t, "08:30"),
+				Stops: []*bitsb.StopTime{
+					{LocationID: 1, Time: clock(t, "08:30")},
+					{LocationID: 2, Time: clock(t, "08:35")},
+					{LocationID: 3, Time: clock(t, "08:45")},
+				},
+			},
+		},
+	}
+
+	t.Run("when the ticket is booked", func(t *testing.T) {
+		ticket := &tickets.Ticket{
+			UserID:         7,
+			BusRouteID:     1,
+			FromLocationID: 2,
+			ToLocationID:   3,
+			TravelDate:     travelDate,
+			Departure:      clock(t, "08:35"),
+		}
+		s.busRouteService.
+			On("Timetable", mock.Anything, int64(1), travelDate).
+			Return(timetable, nil).
+			Once()
+		s.busRouteService.
+			On("CalculateTicketPrice", mock.Anything, int64(1), int64(2), int64(3)).
+			Return(&bitsb.Fare{TicketPrice: 6}, nil).
+			Once()
+		s.repo.
+			On("Insert", mock.Anything, ticket).
+			Return(nil).
+			Once()
+
+		err := s.service.Book(context.Background(), ticket)
+		require.NoError(t, err)
+		require.Equal(t, int64(6), ticket.Price)
+		require.Equal(t, tickets.StatusBooked, ticket.Status)
+		require.Equal(t, "08:45", ticket.Arrival.Format("15:04"))
+
+		claims, err := s.signer.Parse(ticket.Pass)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), claims.BusRouteID)
+		require.Equal(t, "08:35", claims.Departure)
+	})
+
+	t.Run("when no trip leaves at the departure time", func(t *testing.T) {
+		s.busRouteService.
+			On("Timetable", mock.Anything, int64(1), travelDate).
+			Return(timetable, nil).
+			Once()
+
+		err := s.service.Book(context.Background(), &tickets.Ticket{
+			BusRouteID:     1,
+			FromLocationID: 1,
+			ToLocationID:   3,
+			TravelDate:     travelDate,
+			Departure:      clock(t, "08:10"),
+		})
+		require.ErrorIs(t, err, ErrNoSuchTrip)
+	})
+
+	t.Run("when travelling against the direction of the route", func(t *testing.T) {
+		s.busRouteService.
+			On("Timetable", mock.Anything, int64(1), travelDate).
+			Return(timetable, nil).
+			Once()
+
+		err := s.service.Book(context.Background(), &tickets.Ticket{
+			BusRouteID:     1,
+			FromLocationID: 3,
+			ToLocationID:   1,
+			TravelDate:     travelDate,
+			Departure:      clock(t, "08:15"),
+		})
+		require.ErrorIs(t, err, apperrors.ErrInvalidLocation)
+	})
+
+	t.Run("when the ticket is already used", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(4)).
+			Return(&tickets.Ticket{
+				ID:         4,
+				UserID:     7,
+				TravelDate: travelDate,
+				Departure:  clock(t, "08:00"),
+				Status:     tickets.StatusUsed,
+			}, nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 4)
+		require.ErrorIs(t, err, ErrUsed)
+	})
+
+	t.Run("when the ticket is used by another request at the same time", func(t *testing.T) {
+		ticket := &tickets.Ticket{
+			ID:         5,
+			UserID:     7,
+			TravelDate: travelDate,
+			Departure:  clock(t, "08:00"),
+			Status:     tickets.StatusBooked,
+		}
+		s.repo.
+			On("SelectByID", mock.Anything, int64(5)).
+			Return(ticket, nil).
+			Once()
+		s.repo.
+			On("MarkCancelled", mock.Anything, ticket).
+			Return(apperrors.ErrConflict).
+			Once()
+		s.repo.
+			On("SelectByID", mock.Anything, int64(5)).
+			Return(&tickets.Ticket{ID: 5, UserID: 7, Status: tickets.StatusUsed}, nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 5)
+		require.ErrorIs(t, err, ErrUsed)
+	})
+
+	t.Run("when the trip has already departed", func(t *testing.T) {
+		pastDate, _ := bitsb.ParseDate("2020-01-05")
+		s.busRouteService.
+			On("Timetable", mock.Anything, int64(1), pastDate).
+			Return(timetable, nil).
+			Once()
+
+		err := s.service.Book(context.Background(), &tickets.Ticket{
+			BusRouteID:     1,
+			FromLocationID: 1,
+			ToLocationID:   3,
+			TravelDate:     pastDate,
+			Departure:      clock(t, "08:00"),
+		})
+		require.ErrorIs(t, err, ErrDeparted)
+	})
+
+	t.Run("when the route cannot be fetched", func(t *testing.T) {
+		s.busRouteService.
+			On("Timetable", mock.Anything, int64(2), travelDate).
+			Return(nil, fmt.Errorf("error")).
+			Once()
+
+		err := s.service.Book(context.Background(), &tickets.Ticket{
+			BusRouteID: 2,
+			TravelDate: travelDate,
+		})
+		require.Error(t, err)
+	})
+}
+
+func (s *TicketServiceTestSuite) TestGetForUser() {
+	t := s.T()
+
+	ticket := &tickets.Ticket{ID: 1, UserID: 7}
+
+	t.Run("when the ticket belongs to the user", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(1)).
+			Return(ticket, nil).
+			Once()
+
+		res, err := s.service.GetForUser(context.Background(), 7, 1)
+		require.NoError(t, err)
+		require.Equal(t, ticket, res)
+	})
+
+	t.Run("when the ticket belongs to another user", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(1)).
+			Return(ticket, nil).
+			Once()
+
+		_, err := s.service.GetForUser(context.Background(), 8, 1)
+		require.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func (s *TicketServiceTestSuite) TestCancel() {
+	t := s.T()
+
+	travelDate, _ := bitsb.ParseDate("2099-01-05")
+
+	t.Run("when the ticket is cancelled", func(t *testing.T) {
+		ticket := &tickets.Ticket{
+			ID:         1,
+			UserID:     7,
+			TravelDate: travelDate,
+			Departure:  clock(t, "08:00"),
+			Status:     tickets.StatusBooked,
+		}
+		s.repo.
+			On("SelectByID", mock.Anything, int64(1)).
+			Return(ticket, nil).
+			Once()
+		s.repo.
+			On("MarkCancelled", mock.Anything, ticket).
+			Return(nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 1)
+		require.NoError(t, err)
+	})
+
+	t.Run("when the ticket is already cancelled", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(2)).
+			Return(&tickets.Ticket{ID: 2, UserID: 7, Status: tickets.StatusCancelled}, nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 2)
+		require.ErrorIs(t, err, ErrCancelled)
+	})
+
+	t.Run("when the ticket is already used", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(4)).
+			Return(&tickets.Ticket{
+				ID:         4,
+				UserID:     7,
+				TravelDate: travelDate,
+				Departure:  clock(t, "08:00"),
+				Status:     tickets.StatusUsed,
+			}, nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 4)
+		require.ErrorIs(t, err, ErrUsed)
+	})
+
+	t.Run("when the ticket is used by another request at the same time", func(t *testing.T) {
+		ticket := &tickets.Ticket{
+			ID:         5,
+			UserID:     7,
+			TravelDate: travelDate,
+			Departure:  clock(t, "08:00"),
+			Status:     tickets.StatusBooked,
+		}
+		s.repo.
+			On("SelectByID", mock.Anything, int64(5)).
+			Return(ticket, nil).
+			Once()
+		s.repo.
+			On("MarkCancelled", mock.Anything, ticket).
+			Return(apperrors.ErrConflict).
+			Once()
+		s.repo.
+			On("SelectByID", mock.Anything, int64(5)).
+			Return(&tickets.Ticket{ID: 5, UserID: 7, Status: tickets.StatusUsed}, nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 5)
+		require.ErrorIs(t, err, ErrUsed)
+	})
+
+	t.Run("when the trip has already departed", func(t *testing.T) {
+		pastDate, _ := bitsb.ParseDate("2020-01-05")
+		s.repo.
+			On("SelectByID", mock.Anything, int64(3)).
+			Return(&tickets.Ticket{
+				ID:         3,
+				UserID:     7,
+				TravelDate: pastDate,
+				Departure:  clock(t, "08:00"),
+				Status:     tickets.StatusBooked,
+			}, nil).
+			Once()
+
+		_, err := s.service.Cancel(context.Background(), 7, 3)
+		require.ErrorIs(t, err, ErrDeparted)
+	})
+}
+
+func (s *TicketServiceTestSuite) TestValidate() {
+	t := s.T()
+
+	patch, err := mpatch.PatchMethod(time.Now, func() time.Time {
+		return time.Date(2099, 1, 5, 8, 20, 0, 0, time.Local)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(patch *mpatch.Patch) {
+		if err := patch.Unpatch(); err != nil {
+			t.Fatal(err)
+		}
+	}(patch)
+
+	pass := func(ticketID int64, date string) string {
+		p, err := s.signer.Sign(&jwt.TicketClaims{
+			TicketID:   ticketID,
+			BusRouteID: 1,
+			TravelDate: date,
+			Departure:  "08:35",
+			Arrival:    "08:45",
+		})
+		require.NoError(t, err)
+		return p
+	}
+
+	t.Run("when the pass is valid", func(t *testing.T) {
+		ticket := &tickets.Ticket{ID: 1, BusRouteID: 1, Status: tickets.StatusBooked}
+		s.repo.
+			On("SelectByID", mock.Anything, int64(1)).
+			Return(ticket, nil).
+			Once()
+		s.repo.
+			On("MarkUsed", mock.Anything, ticket).
+			Return(nil).
+			Once()
+
+		_, err := s.service.Validate(context.Background(), pass(1, "2099-01-05"), 1)
+		require.NoError(t, err)
+	})
+
+	t.Run("when the pass is replayed", func(t *testing.T) {
+		s.repo.
+			On("SelectByID", mock.Anything, int64(2)).
+			Return(&tickets.Ticket{ID: 2, Status: tickets.StatusUsed}, nil).
+			Once()
+
+		_, err := s.service.Validate(context.Background(), pass(2, "2099-01-05"), 1)
+		require.ErrorIs(t, err, ErrUsed)
+	})
+
+	t.Run("when the pass is used by another request at the same time", func(t *testing.T) {
+		ticket := &tickets.Ticket{ID: 3, Status: tickets.StatusBooked}
+		s.repo.
+			On("SelectByID", mock.Anything, int64(3)).
+			Return(ticket, nil).
+			Once()
+		s.repo.
+			On("MarkUsed", mock.Anything, ticket).
+			Return(apperrors.ErrConflict).
+			Once()
+
+		_, err := s.service.Validate(context.Background(), pass(3, "2099-01-05"), 1)
+		require.ErrorIs(t, err, ErrUsed)
+	})
+
+	t.Run("when the pass is for another route", func(t *testing.T) {
+		_, err := s.service.Validate(context.Background(), pass(1, "2099-01-05"), 2)
+		require.ErrorIs(t, err, ErrWrongRoute)
+	})
+
+	t.Run("when the pass is for another day", func(t *testing.T) {
+		_, err := s.service.Validate(context.Background(), pass(1, "2099-01-06"), 1)
+		require.ErrorIs(t, err, ErrNotBoarding)
+	})
+
+	t.Run("when the pass is signed with another key", func(t *testing.T) {
+		other, err := jwt.NewTicketSigner("")
+		require.NoError(t, err)
+		p, err := other.Sign(&jwt.TicketClaims{TicketID: 1, BusRouteID: 1})
+		require.NoError(t, err)
+
+		_, err = s.service.Validate(context.Background(), p, 1)
+		require.ErrorIs(t, err, ErrInvalidPass)
+	})
+}
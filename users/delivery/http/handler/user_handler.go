@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"net"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 
 	"github.com/sainak/bitsb/api"
@@ -26,7 +30,7 @@ func (u *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := u.service.Login(r.Context(), data)
+	token, err := u.service.Login(r.Context(), data, deviceOf(r))
 	if err != nil {
 		api.RespondForError(w, r, err)
 		return
@@ -34,6 +38,34 @@ func (u *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, token)
 }
 
+// maxUserAgentLength is the length user agents are cut to
+const maxUserAgentLength = 512
+
+// deviceOf returns the device the request was sent from, the address is
+// the client address set by middleware.RealIP when it is behind a proxy
+func deviceOf(r *http.Request) users.Device {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// middleware.RealIP sets the address without a port
+		ip = r.RemoteAddr
+	}
+	return users.Device{UserAgent: truncateUserAgent(r.UserAgent()), IP: ip}
+}
+
+// truncateUserAgent replaces the invalid UTF-8 of the user agent and cuts it
+// to at most maxUserAgentLength bytes without splitting a character
+func truncateUserAgent(userAgent string) string {
+	userAgent = strings.ToValidUTF8(userAgent, string(utf8.RuneError))
+	if len(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+	end := maxUserAgentLength
+	for end > 0 && !utf8.RuneStart(userAgent[end]) {
+		end--
+	}
+	return userAgent[:end]
+}
+
 func (u *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	data := &users.RefreshTokenFrom{}
 	err := render.Bind(r, data)
@@ -42,7 +74,7 @@ func (u *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := u.service.RefreshToken(data.RefreshToken)
+	token, err := u.service.RefreshToken(r.Context(), data.RefreshToken)
 	if err != nil {
 		api.RespondForError(w, r, err)
 		return
@@ -50,6 +82,63 @@ func (u *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, token)
 }
 
+// Logout revokes the session of the refresh token
+func (u *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	data := &users.RefreshTokenFrom{}
+	err := render.Bind(r, data)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	err = u.service.Logout(r.Context(), data.RefreshToken)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll revokes every session of the current user
+func (u *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	err := u.service.LogoutAll(r.Context(), user.ID)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSessions lists the active sessions of the current user
+func (u *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+	current := r.Context().Value(middleware.SessionCtxKey).(*users.Session)
+
+	sessions, err := u.service.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == current.ID
+	}
+	api.RespondFullList(w, r, sessions, len(sessions))
+}
+
+// RevokeSession signs the current user out of one of its sessions
+func (u *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	err := u.service.RevokeSession(r.Context(), user.ID, chi.URLParam(r, "id"))
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (u *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	data := &users.UserRegisterForm{}
 	err := render.Bind(r, data)
@@ -77,6 +166,68 @@ func (u *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, user)
 }
 
+func (u *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	data := &users.VerifyEmailForm{}
+	err := render.Bind(r, data)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	user, err := u.service.VerifyEmail(r.Context(), data.Token)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	render.JSON(w, r, user)
+}
+
+// ResendVerification mails the current user a new verification token
+func (u *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
+
+	err := u.service.ResendVerification(r.Context(), user.ID)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword mails a password reset token, it is accepted
+// for unknown emails too so it does not tell which emails exist
+func (u *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	data := &users.ForgotPasswordForm{}
+	err := render.Bind(r, data)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	err = u.service.ForgotPassword(r.Context(), data.Email)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (u *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	data := &users.ResetPasswordForm{}
+	err := render.Bind(r, data)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+
+	err = u.service.ResetPassword(r.Context(), data.Token, data.Password)
+	if err != nil {
+		api.RespondForError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (u *UserHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// extract the user from the context
 	user := r.Context().Value(middleware.UserCtxKey).(*users.User)
@@ -1 +1,41 @@
 package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceOf(t *testing.T) {
+	t.Run("when the request is behind middleware.RealIP", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		r.RemoteAddr = "203.0.113.7"
+		r.Header.Set("User-Agent", "bitsb-app/1.0")
+
+		device := deviceOf(r)
+		require.Equal(t, "203.0.113.7", device.IP)
+		require.Equal(t, "bitsb-app/1.0", device.UserAgent)
+	})
+
+	t.Run("when the user agent is too long", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		// the multi byte characters straddle the length limit
+		r.Header.Set("User-Agent", strings.Repeat("a", maxUserAgentLength-1)+strings.Repeat("é", 10))
+
+		device := deviceOf(r)
+		require.Equal(t, "192.0.2.1", device.IP)
+		require.True(t, utf8.ValidString(device.UserAgent))
+		require.Equal(t, strings.Repeat("a", maxUserAgentLength-1), device.UserAgent)
+	})
+
+	t.Run("when the user agent is not valid UTF-8", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		r.Header.Set("User-Agent", "bitsb\xff-app")
+
+		require.Equal(t, "bitsb�-app", deviceOf(r).UserAgent)
+	})
+}
@@ -3,8 +3,8 @@ package middleware
 import (
 	"net/http"
 
-	"github.com/go-chi/render"
-
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/users"
 )
 
@@ -15,17 +15,32 @@ func AccessAbove(level users.AccessLevel) func(next http.Handler) http.Handler {
 			// get user form context
 			user := r.Context().Value(UserCtxKey).(*users.User)
 			if user == nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				render.JSON(w, r, render.M{"message": "user not found in context"})
+				api.RespondForError(w, r, apperrors.ErrUnauthorized)
 				return
 			}
 
 			if user.Access < level {
-				w.WriteHeader(http.StatusForbidden)
-				render.JSON(w, r, render.M{"message": "you don't have permission to perform this action"})
+				api.RespondForError(w, r, apperrors.ErrPermissionDenied)
 				return
 			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// EmailVerified checks if the user has verified its email
+func EmailVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(UserCtxKey).(*users.User)
+		if user == nil {
+			api.RespondForError(w, r, apperrors.ErrUnauthorized)
+			return
+		}
+
+		if !user.VerifiedAt.Valid {
+			api.RespondForError(w, r, apperrors.ErrEmailNotVerified)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
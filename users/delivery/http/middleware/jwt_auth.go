@@ -4,45 +4,58 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/go-chi/render"
-
+	"github.com/sainak/bitsb/api"
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/pkg/jwt"
 	"github.com/sainak/bitsb/pkg/middleware"
 	"github.com/sainak/bitsb/users"
 )
 
-var UserCtxKey = &middleware.ContextKey{"user"}
+var (
+	UserCtxKey    = &middleware.ContextKey{Name: "user"}
+	SessionCtxKey = &middleware.ContextKey{Name: "session"}
+)
 
 // JWTAuth is a middleware that checks for a valid JWT in the Authorization header.
-// If one is found, it will be parsed and the user will be added to the request context.
-func JWTAuth(j *jwt.JWT, u users.UserStorer) func(next http.Handler) http.Handler {
+// If one is found, it will be parsed and the user and its session will be added to the
+// request context. Tokens of revoked or expired sessions are rejected.
+func JWTAuth(j *jwt.JWT, u users.UserStorer, s users.SessionStorer) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get the JWT string from the auth header
 			authHeader := r.Header.Get("Authorization")
 			bearerToken := strings.Split(authHeader, " ")
 			if authHeader == "" || len(bearerToken) != 2 {
-				w.WriteHeader(http.StatusUnauthorized)
-				render.JSON(w, r, render.M{"message": "auth token not provided"})
+				api.RespondForError(w, r, apperrors.ErrMissingToken)
+				return
+			}
+
+			claims, err := j.ParseAuthToken(bearerToken[1])
+			if err != nil {
+				api.RespondForError(w, r, err)
 				return
 			}
 
-			id, err := j.GetUserID(bearerToken[1])
+			session, err := s.SelectByID(r.Context(), claims.SessionID)
 			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				render.JSON(w, r, render.M{"message": err.Error()})
+				api.RespondForError(w, r, apperrors.ErrUnauthorized.Wrap(err))
+				return
+			}
+			if session.UserID != claims.UserID || !session.Active(time.Now()) {
+				api.RespondForError(w, r, apperrors.ErrSessionRevoked)
 				return
 			}
 
-			user, err := u.SelectByID(r.Context(), id)
+			user, err := u.SelectByID(r.Context(), claims.UserID)
 			if err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				render.JSON(w, r, render.M{"message": err.Error()})
+				api.RespondForError(w, r, apperrors.ErrUnauthorized.Wrap(err))
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserCtxKey, &user)
+			ctx = context.WithValue(ctx, SessionCtxKey, session)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
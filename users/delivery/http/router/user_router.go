@@ -10,7 +10,7 @@ import (
 )
 
 func RegisterRoutes(
-	router *chi.Mux,
+	router chi.Router,
 	service users.UserServiceProvider,
 	jwtMiddleware func(next http.Handler) http.Handler,
 ) {
@@ -19,11 +19,19 @@ func RegisterRoutes(
 	router.Route("/auth", func(r chi.Router) {
 		r.Post("/login", h.Login)
 		r.Post("/refresh", h.Refresh)
+		r.Post("/logout", h.Logout)
+		r.With(jwtMiddleware).Post("/logout-all", h.LogoutAll)
+		r.Post("/verify-email", h.VerifyEmail)
+		r.With(jwtMiddleware).Post("/resend-verification", h.ResendVerification)
+		r.Post("/password/forgot", h.ForgotPassword)
+		r.Post("/password/reset", h.ResetPassword)
 		r.Post("/register", h.Register)
 	})
 
 	router.Group(func(r chi.Router) {
 		r.Use(jwtMiddleware)
 		r.Get("/user", h.GetCurrentUser)
+		r.Get("/user/sessions", h.ListSessions)
+		r.Delete("/user/sessions/{id}", h.RevokeSession)
 	})
 }
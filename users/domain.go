@@ -6,12 +6,15 @@ import (
 	"time"
 
 	"gopkg.in/guregu/null.v4"
+
+	"github.com/sainak/bitsb/pkg/validate"
 )
 
 type AccessLevel int
 
 const (
 	Admin     AccessLevel = 1000
+	Driver    AccessLevel = 100
 	Passenger AccessLevel = 10
 )
 
@@ -25,30 +28,56 @@ type User struct {
 	Password       string      `json:"-" db:"password"`
 	Access         AccessLevel `json:"-" db:"access_level"`
 	LastLogin      null.Time   `json:"last_login" db:"last_login"`
+	VerifiedAt     null.Time   `json:"verified_at" db:"verified_at"`
 	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
 }
 
 type UserLoginForm struct {
-	Email    string `json:"email" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
 
 func (u UserLoginForm) Bind(r *http.Request) error {
-	return nil
+	return validate.Struct(u).Err()
 }
 
 type UserRegisterForm struct {
-	FirstName      string   `json:"first_name"  binding:"required"`
-	LastName       string   `json:"last_name" binding:"required"`
-	Email          string   `json:"email" binding:"required"`
-	Password       string   `json:"password" binding:"required"`
-	HomeLocationID null.Int `json:"home_location_id"`
-	WorkLocationID null.Int `json:"work_location_id"`
+	FirstName      string   `json:"first_name"  binding:"required,max=100"`
+	LastName       string   `json:"last_name" binding:"required,max=100"`
+	Email          string   `json:"email" binding:"required,email"`
+	Password       string   `json:"password" binding:"required,min=8,max=72"`
+	HomeLocationID null.Int `json:"home_location_id" binding:"min=1"`
+	WorkLocationID null.Int `json:"work_location_id" binding:"min=1"`
 }
 
 func (u UserRegisterForm) Bind(r *http.Request) error {
-	return nil
+	return validate.Struct(u).Err()
+}
+
+type ForgotPasswordForm struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+func (f ForgotPasswordForm) Bind(r *http.Request) error {
+	return validate.Struct(f).Err()
+}
+
+type ResetPasswordForm struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+func (f ResetPasswordForm) Bind(r *http.Request) error {
+	return validate.Struct(f).Err()
+}
+
+type VerifyEmailForm struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (v VerifyEmailForm) Bind(r *http.Request) error {
+	return validate.Struct(v).Err()
 }
 
 type Token struct {
@@ -61,7 +90,66 @@ type RefreshTokenFrom struct {
 }
 
 func (r2 RefreshTokenFrom) Bind(r *http.Request) error {
-	return nil
+	return validate.Struct(r2).Err()
+}
+
+// Device describes the client a session was started from
+type Device struct {
+	UserAgent string `json:"user_agent" db:"user_agent"`
+	IP        string `json:"ip" db:"ip"`
+}
+
+// Session is a login of a user, it lasts as long as its refresh token is rotated before
+// it expires. TokenID is the jti of the one refresh token of the session that can be used,
+// a refresh token used again after it was rotated revokes the session.
+type Session struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     int64     `json:"-" db:"user_id"`
+	TokenID    string    `json:"-" db:"token_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	RevokedAt  null.Time `json:"revoked_at" db:"revoked_at"`
+	Device
+	// Current is set on the session of the request listing the sessions
+	Current bool `json:"current" db:"-"`
+}
+
+// Active reports whether the session can still be used at the given time
+func (s *Session) Active(now time.Time) bool {
+	return !s.RevokedAt.Valid && now.Before(s.ExpiresAt)
+}
+
+type SessionStorer interface {
+	SelectByID(ctx context.Context, id string) (*Session, error)
+	// SelectActiveForUser returns the sessions of the user that are neither revoked
+	// nor expired, the most recently used first
+	SelectActiveForUser(ctx context.Context, userID int64) ([]*Session, error)
+	Insert(ctx context.Context, session *Session) error
+	// Rotate stores the new token of the session if tokenID is still its token,
+	// it fails with apperrors.ErrConflict when the token was rotated in the meantime
+	Rotate(ctx context.Context, session *Session, tokenID string) error
+	Revoke(ctx context.Context, id string) error
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
+
+// PasswordReset is a request to reset the password of a user, only the hash
+// of its token is stored so the stored resets cannot be used to reset passwords
+type PasswordReset struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	UsedAt    null.Time `json:"used_at" db:"used_at"`
+}
+
+type PasswordResetStorer interface {
+	Insert(ctx context.Context, reset *PasswordReset) error
+	// Consume marks the reset of the token hash and the other unused resets of its user as
+	// used and returns the user, it fails with apperrors.ErrConflict when the reset is unknown,
+	// used or expired so a token can only be used once
+	Consume(ctx context.Context, tokenHash string) (int64, error)
 }
 
 type UserStorer interface {
@@ -69,12 +157,37 @@ type UserStorer interface {
 	SelectByEmail(ctx context.Context, email string) (User, error)
 	Insert(ctx context.Context, user *User) error
 	Update(ctx context.Context, user *User) error
+	// MarkVerified marks the email of the user as verified, a verified
+	// user keeps the time its email was first verified
+	MarkVerified(ctx context.Context, user *User) error
 }
 
 type UserServiceProvider interface {
 	GetByID(ctx context.Context, id int64) (User, error)
-	Login(ctx context.Context, creds *UserLoginForm) (Token, error)
-	RefreshToken(token string) (Token, error)
+	// Login starts a session on the device, see Device
+	Login(ctx context.Context, creds *UserLoginForm, device Device) (Token, error)
+	RefreshToken(ctx context.Context, token string) (Token, error)
+	// Logout revokes the session of the refresh token
+	Logout(ctx context.Context, token string) error
+	// LogoutAll revokes every session of the user
+	LogoutAll(ctx context.Context, userID int64) error
+	// ListSessions returns the active sessions of the user
+	ListSessions(ctx context.Context, userID int64) ([]*Session, error)
+	// RevokeSession revokes a session of the user, sessions of other
+	// users fail with apperrors.ErrSessionNotFound
+	RevokeSession(ctx context.Context, userID int64, id string) error
+	// Signup creates the user and mails it a token verifying its email
 	Signup(ctx context.Context, user *User) error
+	// VerifyEmail verifies the email of the user of the token
+	VerifyEmail(ctx context.Context, token string) (User, error)
+	// ResendVerification mails the user a new token verifying its email
+	ResendVerification(ctx context.Context, userID int64) error
+	// ForgotPassword mails a password reset token to the user of the email,
+	// it does not fail for unknown emails so it does not tell which emails exist
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword changes the password of the user of the reset token and revokes its sessions
+	ResetPassword(ctx context.Context, token, password string) error
 	Update(ctx context.Context, user *User) error
+	// Close waits for the mails sent off the request path, it is called on shutdown
+	Close(ctx context.Context) error
 }
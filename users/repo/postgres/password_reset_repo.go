@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/users"
+)
+
+type PasswordResetRepository struct {
+	conn *sql.DB
+}
+
+func NewPasswordResetRepository(conn *sql.DB) users.PasswordResetStorer {
+	return &PasswordResetRepository{conn}
+}
+
+func (p PasswordResetRepository) Insert(ctx context.Context, reset *users.PasswordReset) error {
+	query := `INSERT INTO password_resets (user_id, token_hash, created_at, expires_at) 
+				VALUES ($1, $2, $3, $4) 
+				RETURNING id`
+	reset.CreatedAt = time.Now()
+	return repo.Conn(ctx, p.conn).QueryRowContext(
+		ctx,
+		query,
+		reset.UserID,
+		reset.TokenHash,
+		reset.CreatedAt,
+		reset.ExpiresAt,
+	).Scan(&reset.ID)
+}
+
+// Consume marks every unused reset of the user as used, the condition on used_at is
+// checked again on the updated rows so concurrent calls cannot both consume a token
+func (p PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (int64, error) {
+	query := `UPDATE password_resets 
+				SET used_at=$2 
+				WHERE used_at IS NULL AND user_id=(
+					SELECT user_id FROM password_resets 
+					WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2
+				) 
+				RETURNING user_id`
+	var userID int64
+	err := repo.Conn(ctx, p.conn).QueryRowContext(ctx, query, tokenHash, time.Now()).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, apperrors.ErrConflict
+	}
+	return userID, err
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/users"
+)
+
+type PasswordResetRepositoryTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+	repo users.PasswordResetStorer
+}
+
+func (s *PasswordResetRepositoryTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.db = db
+	s.mock = mock
+	s.repo = NewPasswordResetRepository(db)
+}
+
+func TestPasswordResetRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(PasswordResetRepositoryTestSuite))
+}
+
+func (s *PasswordResetRepositoryTestSuite) TestConsume() {
+	t := s.T()
+
+	t.Run("when consume is successful", func(t *testing.T) {
+		s.mock.ExpectQuery("UPDATE password_resets").
+			WithArgs("hash-1", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(1))
+		userID, err := s.repo.Consume(context.Background(), "hash-1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), userID)
+	})
+
+	t.Run("when the token was used", func(t *testing.T) {
+		s.mock.ExpectQuery("UPDATE password_resets").
+			WithArgs("hash-1", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+		_, err := s.repo.Consume(context.Background(), "hash-1")
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
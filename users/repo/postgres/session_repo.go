@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/pkg/repo"
+	"github.com/sainak/bitsb/users"
+)
+
+type SessionRepository struct {
+	conn *sql.DB
+}
+
+func NewSessionRepository(conn *sql.DB) users.SessionStorer {
+	return &SessionRepository{conn}
+}
+
+const sessionColumns = `id, user_id, token_id, created_at, last_used_at, expires_at, revoked_at, user_agent, ip`
+
+// scanSession scans a row of the session columns from a *sql.Row or *sql.Rows
+func scanSession(row interface{ Scan(dest ...any) error }) (*users.Session, error) {
+	session := &users.Session{}
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.TokenID,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.UserAgent,
+		&session.IP,
+	)
+	return session, err
+}
+
+func (s SessionRepository) SelectByID(ctx context.Context, id string) (*users.Session, error) {
+	query := `SELECT ` + sessionColumns + ` 
+				FROM sessions 
+				WHERE id=$1`
+	session, err := scanSession(repo.Conn(ctx, s.conn).QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrSessionNotFound.Wrap(err)
+	}
+	return session, err
+}
+
+func (s SessionRepository) SelectActiveForUser(ctx context.Context, userID int64) ([]*users.Session, error) {
+	query := `SELECT ` + sessionColumns + ` 
+				FROM sessions 
+				WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > $2 
+				ORDER BY last_used_at DESC, id`
+	rows, err := repo.Conn(ctx, s.conn).QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		if err := rows.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}(rows)
+
+	sessions := make([]*users.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s SessionRepository) Insert(ctx context.Context, session *users.Session) error {
+	query := `INSERT INTO sessions (id, user_id, token_id, created_at, last_used_at, expires_at, user_agent, ip) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := repo.Conn(ctx, s.conn).ExecContext(
+		ctx,
+		query,
+		session.ID,
+		session.UserID,
+		session.TokenID,
+		session.CreatedAt,
+		session.LastUsedAt,
+		session.ExpiresAt,
+		session.UserAgent,
+		session.IP,
+	)
+	return err
+}
+
+// Rotate replaces the refresh token of the session, it fails with apperrors.ErrConflict when
+// tokenID is no longer the token of the session so a refresh token can only be rotated once
+func (s SessionRepository) Rotate(ctx context.Context, session *users.Session, tokenID string) error {
+	query := `UPDATE sessions 
+				SET token_id=$3, last_used_at=$4, expires_at=$5 
+				WHERE id=$1 AND token_id=$2 AND revoked_at IS NULL`
+	result, err := repo.Conn(ctx, s.conn).ExecContext(
+		ctx,
+		query,
+		session.ID,
+		tokenID,
+		session.TokenID,
+		session.LastUsedAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return apperrors.ErrConflict
+	}
+	return nil
+}
+
+// Revoke revokes the session, revoking a revoked session keeps the time it was first revoked
+func (s SessionRepository) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET revoked_at=COALESCE(revoked_at, $2) WHERE id=$1`
+	result, err := repo.Conn(ctx, s.conn).ExecContext(ctx, query, id, time.Now())
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != 1 {
+		return apperrors.ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s SessionRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := `UPDATE sessions SET revoked_at=$2 WHERE user_id=$1 AND revoked_at IS NULL`
+	_, err := repo.Conn(ctx, s.conn).ExecContext(ctx, query, userID, time.Now())
+	return err
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/users"
+)
+
+type SessionRepositoryTestSuite struct {
+	suite.Suite
+	db   *sql.DB
+	mock sqlmock.Sqlmock
+	repo users.SessionStorer
+}
+
+func (s *SessionRepositoryTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.db = db
+	s.mock = mock
+	s.repo = NewSessionRepository(db)
+}
+
+func TestSessionRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionRepositoryTestSuite))
+}
+
+func (s *SessionRepositoryTestSuite) TestSelectSession() {
+	t := s.T()
+
+	t.Run("when select is not successful", func(t *testing.T) {
+		s.mock.ExpectQuery("SELECT (.+) FROM sessions").
+			WithArgs("session-1").
+			WillReturnError(sql.ErrNoRows)
+		_, err := s.repo.SelectByID(context.Background(), "session-1")
+		assert.ErrorIs(t, err, apperrors.ErrSessionNotFound)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func (s *SessionRepositoryTestSuite) TestRotateSession() {
+	t := s.T()
+
+	session := &users.Session{
+		ID:         "session-1",
+		TokenID:    "token-2",
+		LastUsedAt: time.Date(2020, 11, 01, 00, 00, 00, 0, time.UTC),
+		ExpiresAt:  time.Date(2020, 11, 02, 00, 00, 00, 0, time.UTC),
+	}
+
+	t.Run("when rotate is successful", func(t *testing.T) {
+		s.mock.ExpectExec("UPDATE sessions").
+			WithArgs(session.ID, "token-1", session.TokenID, session.LastUsedAt, session.ExpiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		err := s.repo.Rotate(context.Background(), session, "token-1")
+		assert.Nil(t, err)
+	})
+
+	t.Run("when the token was already rotated", func(t *testing.T) {
+		s.mock.ExpectExec("UPDATE sessions").
+			WithArgs(session.ID, "token-1", session.TokenID, session.LastUsedAt, session.ExpiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		err := s.repo.Rotate(context.Background(), session, "token-1")
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+func (s *SessionRepositoryTestSuite) TestRevokeSession() {
+	t := s.T()
+
+	t.Run("when the session does not exist", func(t *testing.T) {
+		s.mock.ExpectExec("UPDATE sessions").
+			WithArgs("session-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		err := s.repo.Revoke(context.Background(), "session-1")
+		assert.ErrorIs(t, err, apperrors.ErrSessionNotFound)
+	})
+}
@@ -3,8 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/sainak/bitsb/apperrors"
+	"github.com/sainak/bitsb/pkg/repo"
 	"github.com/sainak/bitsb/users"
 )
 
@@ -18,7 +21,7 @@ func NewUserRepository(conn *sql.DB) users.UserStorer {
 
 func (u UserRepository) fetchUser(ctx context.Context, query string, args ...interface{}) (users.User, error) {
 	user := users.User{}
-	err := u.conn.QueryRowContext(ctx, query, args...).Scan(
+	err := repo.Conn(ctx, u.conn).QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.Email,
 		&user.FirstName,
@@ -26,36 +29,44 @@ func (u UserRepository) fetchUser(ctx context.Context, query string, args ...int
 		&user.Access,
 		&user.Password,
 		&user.LastLogin,
+		&user.VerifiedAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.HomeLocationID,
+		&user.WorkLocationID,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrUserNotFound.Wrap(err)
+	}
 	return user, err
 }
 
 func (u UserRepository) SelectByID(ctx context.Context, id int64) (users.User, error) {
-	query := `SELECT id, email, first_name, last_name, access_level, password, last_login, created_at, updated_at 
+	query := `SELECT id, email, first_name, last_name, access_level, password, last_login, verified_at, created_at, updated_at, 
+					home_location_id, work_location_id 
 				FROM users 
 				WHERE id=$1`
 	return u.fetchUser(ctx, query, id)
 }
 
 func (u UserRepository) SelectByEmail(ctx context.Context, email string) (users.User, error) {
-	query := `SELECT id, email, first_name, last_name, access_level, password, last_login, created_at, updated_at 
+	query := `SELECT id, email, first_name, last_name, access_level, password, last_login, verified_at, created_at, updated_at, 
+					home_location_id, work_location_id 
 				FROM users 
 				WHERE email=$1`
 	return u.fetchUser(ctx, query, email)
 }
 
 func (u UserRepository) Insert(ctx context.Context, user *users.User) error {
-	query := `INSERT INTO users (email, first_name, last_name, access_level, password, created_at, updated_at) 
-				VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	query := `INSERT INTO users (email, first_name, last_name, access_level, password, created_at, updated_at, home_location_id, work_location_id) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
 				RETURNING id`
 
 	currentTime := time.Now()
 	user.CreatedAt = currentTime
 	user.UpdatedAt = currentTime
 
-	err := u.conn.QueryRowContext(
+	err := repo.Conn(ctx, u.conn).QueryRowContext(
 		ctx,
 		query,
 		user.Email,
@@ -65,16 +76,19 @@ func (u UserRepository) Insert(ctx context.Context, user *users.User) error {
 		user.Password,
 		user.CreatedAt,
 		user.UpdatedAt,
+		user.HomeLocationID,
+		user.WorkLocationID,
 	).Scan(&user.ID)
 	return err
 }
 
 func (u UserRepository) Update(ctx context.Context, user *users.User) error {
 	query := `UPDATE users 
-				SET email=$2, first_name=$3, last_name=$4, password=$5, last_login=$6, updated_at=$7 
+				SET email=$2, first_name=$3, last_name=$4, password=$5, last_login=$6, updated_at=$7, 
+					home_location_id=$8, work_location_id=$9 
 				WHERE id=$1`
 	user.UpdatedAt = time.Now()
-	result, err := u.conn.ExecContext(
+	result, err := repo.Conn(ctx, u.conn).ExecContext(
 		ctx,
 		query,
 		user.ID,
@@ -84,6 +98,8 @@ func (u UserRepository) Update(ctx context.Context, user *users.User) error {
 		user.Password,
 		user.LastLogin,
 		user.UpdatedAt,
+		user.HomeLocationID,
+		user.WorkLocationID,
 	)
 	if err != nil {
 		return err
@@ -91,7 +107,19 @@ func (u UserRepository) Update(ctx context.Context, user *users.User) error {
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil || rowsAffected != 1 {
-		return sql.ErrNoRows
+		return apperrors.ErrUserNotFound.Wrap(sql.ErrNoRows)
+	}
+	return err
+}
+
+func (u UserRepository) MarkVerified(ctx context.Context, user *users.User) error {
+	query := `UPDATE users 
+				SET verified_at=COALESCE(verified_at, $2), updated_at=$2 
+				WHERE id=$1 
+				RETURNING verified_at, updated_at`
+	err := repo.Conn(ctx, u.conn).QueryRowContext(ctx, query, user.ID, time.Now()).Scan(&user.VerifiedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = apperrors.ErrUserNotFound.Wrap(err)
 	}
 	return err
 }
@@ -46,6 +46,9 @@ func (s *UserRepositoryTestSuite) TestSelectUser() {
 		Email:     "jhon.doe@example.com",
 		Password:  "test_password",
 		Access:    users.Admin,
+
+		HomeLocationID: null.IntFrom(2),
+		WorkLocationID: null.IntFrom(5),
 	}
 
 	t.Run("when select by user id is successful", func(t *testing.T) {
@@ -60,8 +63,11 @@ func (s *UserRepositoryTestSuite) TestSelectUser() {
 					"access_level",
 					"password",
 					"last_login",
+					"verified_at",
 					"created_at",
 					"updated_at",
+					"home_location_id",
+					"work_location_id",
 				}).
 				AddRow(
 					user.ID,
@@ -71,8 +77,11 @@ func (s *UserRepositoryTestSuite) TestSelectUser() {
 					user.Access,
 					user.Password,
 					user.LastLogin,
+					user.VerifiedAt,
 					user.CreatedAt,
 					user.UpdatedAt,
+					user.HomeLocationID,
+					user.WorkLocationID,
 				),
 			)
 		res, err := s.repo.SelectByID(context.Background(), user.ID)
@@ -92,8 +101,11 @@ func (s *UserRepositoryTestSuite) TestSelectUser() {
 					"access_level",
 					"password",
 					"last_login",
+					"verified_at",
 					"created_at",
 					"updated_at",
+					"home_location_id",
+					"work_location_id",
 				}).
 				AddRow(
 					user.ID,
@@ -103,8 +115,11 @@ func (s *UserRepositoryTestSuite) TestSelectUser() {
 					user.Access,
 					user.Password,
 					user.LastLogin,
+					user.VerifiedAt,
 					user.CreatedAt,
 					user.UpdatedAt,
+					user.HomeLocationID,
+					user.WorkLocationID,
 				),
 			)
 		res, err := s.repo.SelectByEmail(context.Background(), user.Email)
@@ -144,6 +159,8 @@ func (s *UserRepositoryTestSuite) TestInsertUser() {
 		Email:     "jhon.doe@example.com",
 		Password:  "test_password",
 		Access:    users.Admin,
+
+		HomeLocationID: null.IntFrom(2),
 	}
 
 	t.Run("when insert is successful", func(t *testing.T) {
@@ -156,6 +173,8 @@ func (s *UserRepositoryTestSuite) TestInsertUser() {
 				user.Password,
 				time.Now(),
 				time.Now(),
+				user.HomeLocationID,
+				user.WorkLocationID,
 			).
 			WillReturnRows(
 				sqlmock.NewRows([]string{"id"}).AddRow(user.ID),
@@ -176,6 +195,8 @@ func (s *UserRepositoryTestSuite) TestInsertUser() {
 				user.Password,
 				time.Now(),
 				time.Now(),
+				user.HomeLocationID,
+				user.WorkLocationID,
 			).
 			WillReturnError(sql.ErrNoRows)
 		err := s.repo.Insert(context.Background(), user)
@@ -209,6 +230,8 @@ func (s *UserRepositoryTestSuite) TestUpdateUser() {
 		LastLogin: null.Time{},
 		CreatedAt: time.Time{},
 		UpdatedAt: time.Time{},
+
+		WorkLocationID: null.IntFrom(5),
 	}
 
 	t.Run("when update is successful", func(t *testing.T) {
@@ -221,6 +244,8 @@ func (s *UserRepositoryTestSuite) TestUpdateUser() {
 				user.Password,
 				user.LastLogin,
 				time.Now(),
+				user.HomeLocationID,
+				user.WorkLocationID,
 			).
 			WillReturnResult(
 				sqlmock.NewResult(1, 1),
@@ -240,6 +265,8 @@ func (s *UserRepositoryTestSuite) TestUpdateUser() {
 				user.Password,
 				user.LastLogin,
 				time.Now(),
+				user.HomeLocationID,
+				user.WorkLocationID,
 			).
 			WillReturnResult(
 				sqlmock.NewResult(0, 0),
@@ -258,6 +285,8 @@ func (s *UserRepositoryTestSuite) TestUpdateUser() {
 				user.Password,
 				user.LastLogin,
 				time.Now(),
+				user.HomeLocationID,
+				user.WorkLocationID,
 			).
 			WillReturnError(sql.ErrNoRows)
 		err := s.repo.Update(context.Background(), user)
@@ -2,30 +2,62 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/guregu/null.v4"
 
 	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/pkg/jwt"
+	"github.com/sainak/bitsb/pkg/mail"
+	"github.com/sainak/bitsb/pkg/repo"
 	"github.com/sainak/bitsb/pkg/utils"
 	"github.com/sainak/bitsb/users"
 )
 
+// passwordResetLifespan is how long password reset tokens are valid
+const passwordResetLifespan = time.Hour
+
+// backgroundTimeout is how long work done off the request path may take
+const backgroundTimeout = 30 * time.Second
+
 type UserService struct {
-	repo users.UserStorer
-	jwt  *jwt.JWT
+	repo     users.UserStorer
+	sessions users.SessionStorer
+	resets   users.PasswordResetStorer
+	jwt      *jwt.JWT
+	mailer   mail.Mailer
+	uow      repo.UnitOfWork
+	// background tracks the work done off the request path, Close waits for it
+	background *sync.WaitGroup
 }
 
-func NewUserService(repo users.UserStorer, jwtInstance *jwt.JWT) users.UserServiceProvider {
+func NewUserService(
+	userRepo users.UserStorer,
+	sessions users.SessionStorer,
+	resets users.PasswordResetStorer,
+	jwtInstance *jwt.JWT,
+	mailer mail.Mailer,
+	uow repo.UnitOfWork,
+) users.UserServiceProvider {
 	return &UserService{
-		repo: repo,
-		jwt:  jwtInstance,
+		repo:       userRepo,
+		sessions:   sessions,
+		resets:     resets,
+		jwt:        jwtInstance,
+		mailer:     mailer,
+		uow:        uow,
+		background: &sync.WaitGroup{},
 	}
 }
 
-func (u UserService) Login(ctx context.Context, creds *users.UserLoginForm) (users.Token, error) {
+func (u UserService) Login(ctx context.Context, creds *users.UserLoginForm, device users.Device) (users.Token, error) {
 	token := users.Token{}
 
 	user, err := u.repo.SelectByEmail(ctx, creds.Email)
@@ -48,23 +80,126 @@ func (u UserService) Login(ctx context.Context, creds *users.UserLoginForm) (use
 		return token, err
 	}
 
-	token.AuthToken, err = u.jwt.CreateToken(user.ID)
+	return u.startSession(ctx, user.ID, device)
+}
+
+// RefreshToken rotates the refresh token of the session, a refresh token can only be used
+// once and using it again revokes its session, as the token or its successor was stolen
+func (u UserService) RefreshToken(ctx context.Context, refreshToken string) (users.Token, error) {
+	claims, err := u.jwt.ParseRefreshToken(refreshToken)
 	if err != nil {
-		return token, err
+		return users.Token{}, err
 	}
-	token.RefreshToken, err = u.jwt.CreateRefreshToken(user.ID)
+	session, err := u.activeSession(ctx, claims)
 	if err != nil {
-		return token, err
+		return users.Token{}, err
+	}
+	if session.TokenID != claims.TokenID {
+		return users.Token{}, u.revokeReused(ctx, session)
+	}
+
+	now := time.Now()
+	session.TokenID = jwt.NewID()
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(u.jwt.RefreshTokenLifespanHours)
+	err = u.sessions.Rotate(ctx, session, claims.TokenID)
+	if errors.Is(err, apperrors.ErrConflict) {
+		// the token was used by another request in the meantime
+		return users.Token{}, u.revokeReused(ctx, session)
+	}
+	if err != nil {
+		return users.Token{}, err
+	}
+	return u.issueTokens(session)
+}
+
+func (u UserService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := u.jwt.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	session, err := u.activeSession(ctx, claims)
+	if err != nil {
+		return err
+	}
+	return u.sessions.Revoke(ctx, session.ID)
+}
+
+func (u UserService) LogoutAll(ctx context.Context, userID int64) error {
+	return u.sessions.RevokeAllForUser(ctx, userID)
+}
+
+func (u UserService) ListSessions(ctx context.Context, userID int64) ([]*users.Session, error) {
+	return u.sessions.SelectActiveForUser(ctx, userID)
+}
+
+func (u UserService) RevokeSession(ctx context.Context, userID int64, id string) error {
+	session, err := u.sessions.SelectByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		// do not tell the sessions of other users apart from missing ones
+		return apperrors.ErrSessionNotFound
+	}
+	return u.sessions.Revoke(ctx, id)
+}
+
+// startSession starts a new session of the user and returns its tokens
+func (u UserService) startSession(ctx context.Context, userID int64, device users.Device) (users.Token, error) {
+	now := time.Now()
+	session := &users.Session{
+		ID:         jwt.NewID(),
+		UserID:     userID,
+		TokenID:    jwt.NewID(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(u.jwt.RefreshTokenLifespanHours),
+		Device:     device,
+	}
+	if err := u.sessions.Insert(ctx, session); err != nil {
+		return users.Token{}, err
+	}
+	return u.issueTokens(session)
+}
+
+func (u UserService) issueTokens(session *users.Session) (users.Token, error) {
+	token := users.Token{}
+	var err error
+	token.AuthToken, err = u.jwt.CreateToken(session.UserID, session.ID)
+	if err != nil {
+		return users.Token{}, err
+	}
+	token.RefreshToken, err = u.jwt.CreateRefreshToken(session.UserID, session.ID, session.TokenID)
+	if err != nil {
+		return users.Token{}, err
 	}
 	return token, nil
 }
 
-func (u UserService) RefreshToken(refreshToken string) (users.Token, error) {
-	token, err := u.jwt.RefreshToken(refreshToken)
+// activeSession returns the session of the refresh token, revoked
+// and expired sessions make the token invalid
+func (u UserService) activeSession(ctx context.Context, claims *jwt.RefreshClaims) (*users.Session, error) {
+	session, err := u.sessions.SelectByID(ctx, claims.SessionID)
+	if errors.Is(err, apperrors.ErrSessionNotFound) {
+		return nil, apperrors.ErrInvalidToken.Wrap(err)
+	}
 	if err != nil {
-		err = apperrors.ErrInvalidToken
+		return nil, err
+	}
+	if session.UserID != claims.UserID || !session.Active(time.Now()) {
+		return nil, apperrors.ErrInvalidToken
 	}
-	return users.Token{AuthToken: token}, err
+	return session, nil
+}
+
+// revokeReused revokes the session of a refresh token that was used again
+func (u UserService) revokeReused(ctx context.Context, session *users.Session) error {
+	logrus.Warnf("refresh token of session %s of user %d was reused, revoking the session", session.ID, session.UserID)
+	if err := u.sessions.Revoke(ctx, session.ID); err != nil {
+		return err
+	}
+	return apperrors.ErrTokenReused
 }
 
 func (u UserService) GetByID(ctx context.Context, id int64) (users.User, error) {
@@ -77,7 +212,168 @@ func (u UserService) Signup(ctx context.Context, user *users.User) error {
 		return err
 	}
 	user.Password = hashedPassword
-	return u.repo.Insert(ctx, user)
+	if err = u.repo.Insert(ctx, user); err != nil {
+		return err
+	}
+
+	// the user is created even when the mail fails, it can ask for the mail again
+	if err = u.sendVerification(ctx, user); err != nil {
+		logrus.Errorf("sending the verification mail of user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (u UserService) VerifyEmail(ctx context.Context, token string) (users.User, error) {
+	claims, err := u.jwt.ParseEmailToken(token)
+	if err != nil {
+		return users.User{}, err
+	}
+	user, err := u.repo.SelectByID(ctx, claims.UserID)
+	if errors.Is(err, apperrors.ErrUserNotFound) {
+		return users.User{}, apperrors.ErrInvalidToken.Wrap(err)
+	}
+	if err != nil {
+		return users.User{}, err
+	}
+	if user.Email != claims.Email {
+		// the email was changed after the token was sent
+		return users.User{}, apperrors.ErrInvalidToken
+	}
+	if err = u.repo.MarkVerified(ctx, &user); err != nil {
+		return users.User{}, err
+	}
+	return user, nil
+}
+
+func (u UserService) ResendVerification(ctx context.Context, userID int64) error {
+	user, err := u.repo.SelectByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.VerifiedAt.Valid {
+		return apperrors.ErrEmailAlreadyVerified
+	}
+	return u.sendVerification(ctx, &user)
+}
+
+// ForgotPassword mails a password reset token to the user of the email. The token is
+// issued and mailed off the request path, so the response takes as long whether the
+// email belongs to a user or not and does not tell who has an account.
+func (u UserService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := u.repo.SelectByEmail(ctx, email)
+	if errors.Is(err, apperrors.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	u.background.Add(1)
+	go func() {
+		defer u.background.Done()
+		// the request context is cancelled once the response is sent
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+		defer cancel()
+		if err := u.sendPasswordReset(ctx, user); err != nil {
+			logrus.Errorf("sending the password reset mail of user %d: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+// Close waits until the work done off the request path has finished or ctx is done,
+// it is called on shutdown once no more requests are served so no mail is lost
+func (u UserService) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		u.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendPasswordReset mails the user a new password reset token
+func (u UserService) sendPasswordReset(ctx context.Context, user users.User) error {
+	token := jwt.NewID()
+	reset := &users.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(passwordResetLifespan),
+	}
+	if err := u.resets.Insert(ctx, reset); err != nil {
+		return err
+	}
+
+	return u.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nreset your password by sending this token with your new password to /auth/password/reset:\n\n%s\n\n"+
+				"The token expires in %.0f minutes, ignore this mail if you did not ask to reset your password.\n",
+			user.FirstName,
+			token,
+			passwordResetLifespan.Minutes(),
+		),
+	})
+}
+
+func (u UserService) ResetPassword(ctx context.Context, token, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return u.uow.Do(ctx, func(ctx context.Context) error {
+		userID, err := u.resets.Consume(ctx, hashResetToken(token))
+		if errors.Is(err, apperrors.ErrConflict) {
+			return apperrors.ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+
+		user, err := u.repo.SelectByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user.Password = hashedPassword
+		if err = u.repo.Update(ctx, &user); err != nil {
+			return err
+		}
+		// whoever knew the old password is logged out
+		return u.sessions.RevokeAllForUser(ctx, user.ID)
+	})
+}
+
+// hashResetToken returns the hash password reset tokens are stored as,
+// the tokens are random so a fast hash is enough
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendVerification mails the user a token verifying its email
+func (u UserService) sendVerification(ctx context.Context, user *users.User) error {
+	token, err := u.jwt.CreateEmailToken(user.ID, user.Email)
+	if err != nil {
+		return err
+	}
+	return u.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nverify your email by sending this token to /auth/verify-email:\n\n%s\n\nThe token expires in %.0f hours.\n",
+			user.FirstName,
+			token,
+			jwt.EmailTokenLifespan.Hours(),
+		),
+	})
 }
 
 func (u UserService) Update(ctx context.Context, user *users.User) error {
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,19 +12,26 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/undefinedlabs/go-mpatch"
+	"golang.org/x/crypto/bcrypt"
 	"gopkg.in/guregu/null.v4"
 
+	"github.com/sainak/bitsb/apperrors"
 	"github.com/sainak/bitsb/mocks"
 	"github.com/sainak/bitsb/pkg/jwt"
+	"github.com/sainak/bitsb/pkg/mail"
 	"github.com/sainak/bitsb/pkg/utils"
 	"github.com/sainak/bitsb/users"
 )
 
 type UserServiceTestSuite struct {
 	suite.Suite
-	service users.UserServiceProvider
-	repo    *mocks.UserStorer
-	jwt     *jwt.JWT
+	service  users.UserServiceProvider
+	repo     *mocks.UserStorer
+	sessions *mocks.SessionStorer
+	resets   *mocks.PasswordResetStorer
+	jwt      *jwt.JWT
+	mailer   *mail.MemoryMailer
+	uow      *mocks.UnitOfWork
 }
 
 func TestUserServiceTestSuite(t *testing.T) {
@@ -31,8 +40,15 @@ func TestUserServiceTestSuite(t *testing.T) {
 
 func (s *UserServiceTestSuite) SetupTest() {
 	s.repo = mocks.NewUserStorer(s.T())
+	s.sessions = mocks.NewSessionStorer(s.T())
+	s.resets = mocks.NewPasswordResetStorer(s.T())
 	s.jwt = jwt.New("test_secret", "24", "5")
-	s.service = NewUserService(s.repo, s.jwt)
+	s.mailer = mail.NewMemoryMailer()
+	s.uow = mocks.NewUnitOfWork(s.T())
+	s.uow.On("Do", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) }).
+		Maybe()
+	s.service = NewUserService(s.repo, s.sessions, s.resets, s.jwt, s.mailer, s.uow)
 }
 
 func (s *UserServiceTestSuite) TestLogin() {
@@ -67,6 +83,8 @@ func (s *UserServiceTestSuite) TestLogin() {
 		UpdatedAt: time.Time{},
 	}
 
+	device := users.Device{UserAgent: "test-agent", IP: "203.0.113.7"}
+
 	t.Run("when login is successful", func(t *testing.T) {
 		s.repo.
 			On("SelectByEmail", mock.Anything, user.Email).
@@ -74,15 +92,25 @@ func (s *UserServiceTestSuite) TestLogin() {
 		s.repo.
 			On("Update", mock.Anything, &user).
 			Return(nil)
+		s.sessions.
+			On("Insert", mock.Anything, mock.MatchedBy(func(session *users.Session) bool {
+				return session.UserID == user.ID && session.ID != "" && session.TokenID != "" && session.Device == device &&
+					session.ExpiresAt.Equal(time.Now().Add(24*time.Hour))
+			})).
+			Return(nil).
+			Once()
 		creds := &users.UserLoginForm{
 			Email:    user.Email,
 			Password: password,
 		}
-		token, err := s.service.Login(context.Background(), creds)
+		token, err := s.service.Login(context.Background(), creds, device)
 		require.Nil(t, err)
 		parsedToken, err := s.jwt.ParseToken(token.AuthToken)
 		require.Nil(t, err)
 		require.True(t, parsedToken.Valid)
+		claims, err := s.jwt.ParseRefreshToken(token.RefreshToken)
+		require.Nil(t, err)
+		require.Equal(t, user.ID, claims.UserID)
 	})
 
 	t.Run("when password is incorrect", func(t *testing.T) {
@@ -91,7 +119,7 @@ func (s *UserServiceTestSuite) TestLogin() {
 			Email:    user.Email,
 			Password: "incorrect_password",
 		}
-		token, err := s.service.Login(context.Background(), creds)
+		token, err := s.service.Login(context.Background(), creds, device)
 		require.Error(t, err)
 		require.Zero(t, token)
 	})
@@ -104,8 +132,320 @@ func (s *UserServiceTestSuite) TestLogin() {
 			Email:    "nobody@example.com",
 			Password: password,
 		}
-		token, err := s.service.Login(context.Background(), creds)
+		token, err := s.service.Login(context.Background(), creds, device)
 		require.Error(t, err)
 		require.Zero(t, token)
 	})
 }
+
+func (s *UserServiceTestSuite) newSession(userID int64) (*users.Session, string) {
+	now := time.Now()
+	session := &users.Session{
+		ID:         jwt.NewID(),
+		UserID:     userID,
+		TokenID:    jwt.NewID(),
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	refreshToken, err := s.jwt.CreateRefreshToken(userID, session.ID, session.TokenID)
+	s.Require().Nil(err)
+	return session, refreshToken
+}
+
+func (s *UserServiceTestSuite) TestRefreshToken() {
+	t := s.T()
+
+	t.Run("when the token is rotated", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		tokenID := session.TokenID
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+		s.sessions.
+			On("Rotate", mock.Anything, mock.MatchedBy(func(rotated *users.Session) bool {
+				return rotated.ID == session.ID && rotated.TokenID != tokenID
+			}), tokenID).
+			Return(nil).
+			Once()
+
+		token, err := s.service.RefreshToken(context.Background(), refreshToken)
+		require.Nil(t, err)
+		require.NotEmpty(t, token.AuthToken)
+		claims, err := s.jwt.ParseRefreshToken(token.RefreshToken)
+		require.Nil(t, err)
+		require.Equal(t, session.ID, claims.SessionID)
+		require.Equal(t, session.TokenID, claims.TokenID)
+		require.NotEqual(t, tokenID, claims.TokenID)
+	})
+
+	t.Run("when a rotated token is used again", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		session.TokenID = jwt.NewID()
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+		s.sessions.On("Revoke", mock.Anything, session.ID).Return(nil).Once()
+
+		token, err := s.service.RefreshToken(context.Background(), refreshToken)
+		require.ErrorIs(t, err, apperrors.ErrTokenReused)
+		require.Zero(t, token)
+	})
+
+	t.Run("when the token is rotated by another request", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+		s.sessions.On("Rotate", mock.Anything, session, mock.Anything).Return(apperrors.ErrConflict).Once()
+		s.sessions.On("Revoke", mock.Anything, session.ID).Return(nil).Once()
+
+		_, err := s.service.RefreshToken(context.Background(), refreshToken)
+		require.ErrorIs(t, err, apperrors.ErrTokenReused)
+	})
+
+	t.Run("when the session is revoked", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		session.RevokedAt = null.TimeFrom(time.Now())
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+
+		_, err := s.service.RefreshToken(context.Background(), refreshToken)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+
+	t.Run("when the session does not exist", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		s.sessions.
+			On("SelectByID", mock.Anything, session.ID).
+			Return(&users.Session{}, apperrors.ErrSessionNotFound).
+			Once()
+
+		_, err := s.service.RefreshToken(context.Background(), refreshToken)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+
+	t.Run("when an auth token is used", func(t *testing.T) {
+		authToken, err := s.jwt.CreateToken(1, jwt.NewID())
+		require.Nil(t, err)
+
+		_, err = s.service.RefreshToken(context.Background(), authToken)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+}
+
+func (s *UserServiceTestSuite) TestLogout() {
+	t := s.T()
+
+	t.Run("when the session is revoked", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+		s.sessions.On("Revoke", mock.Anything, session.ID).Return(nil).Once()
+
+		require.Nil(t, s.service.Logout(context.Background(), refreshToken))
+	})
+
+	t.Run("when the session belongs to another user", func(t *testing.T) {
+		session, refreshToken := s.newSession(1)
+		session.UserID = 2
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+
+		require.ErrorIs(t, s.service.Logout(context.Background(), refreshToken), apperrors.ErrInvalidToken)
+	})
+
+	t.Run("when all sessions are revoked", func(t *testing.T) {
+		s.sessions.On("RevokeAllForUser", mock.Anything, int64(1)).Return(errors.New("db down")).Once()
+
+		require.Error(t, s.service.LogoutAll(context.Background(), 1))
+	})
+}
+
+func (s *UserServiceTestSuite) TestRevokeSession() {
+	t := s.T()
+
+	t.Run("when the session is revoked", func(t *testing.T) {
+		session, _ := s.newSession(1)
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+		s.sessions.On("Revoke", mock.Anything, session.ID).Return(nil).Once()
+
+		require.Nil(t, s.service.RevokeSession(context.Background(), 1, session.ID))
+	})
+
+	t.Run("when the session belongs to another user", func(t *testing.T) {
+		session, _ := s.newSession(2)
+		s.sessions.On("SelectByID", mock.Anything, session.ID).Return(session, nil).Once()
+
+		err := s.service.RevokeSession(context.Background(), 1, session.ID)
+		require.ErrorIs(t, err, apperrors.ErrSessionNotFound)
+	})
+}
+
+func (s *UserServiceTestSuite) TestSignup() {
+	t := s.T()
+
+	t.Run("when the verification mail is sent", func(t *testing.T) {
+		user := &users.User{FirstName: "Tester", Email: "testuser@email.com", Password: "test_pass"}
+		s.repo.
+			On("Insert", mock.Anything, user).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*users.User).ID = 1
+			}).
+			Return(nil).
+			Once()
+
+		require.Nil(t, s.service.Signup(context.Background(), user))
+		require.NotEqual(t, "test_pass", user.Password)
+
+		messages := s.mailer.Messages()
+		require.Len(t, messages, 1)
+		require.Equal(t, user.Email, messages[0].To)
+		// the token is the third paragraph of the mail
+		paragraphs := strings.Split(messages[0].Body, "\n\n")
+		require.Len(t, paragraphs, 4)
+		claims, err := s.jwt.ParseEmailToken(paragraphs[2])
+		require.Nil(t, err)
+		require.Equal(t, user.ID, claims.UserID)
+		require.Equal(t, user.Email, claims.Email)
+	})
+}
+
+func (s *UserServiceTestSuite) TestVerifyEmail() {
+	t := s.T()
+
+	user := users.User{ID: 1, FirstName: "Tester", Email: "testuser@email.com"}
+
+	t.Run("when the email is verified", func(t *testing.T) {
+		token, err := s.jwt.CreateEmailToken(user.ID, user.Email)
+		require.Nil(t, err)
+		s.repo.On("SelectByID", mock.Anything, user.ID).Return(user, nil).Once()
+		s.repo.
+			On("MarkVerified", mock.Anything, mock.AnythingOfType("*users.User")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*users.User).VerifiedAt = null.TimeFrom(time.Now())
+			}).
+			Return(nil).
+			Once()
+
+		verified, err := s.service.VerifyEmail(context.Background(), token)
+		require.Nil(t, err)
+		require.True(t, verified.VerifiedAt.Valid)
+	})
+
+	t.Run("when the email was changed", func(t *testing.T) {
+		token, err := s.jwt.CreateEmailToken(user.ID, "old@email.com")
+		require.Nil(t, err)
+		s.repo.On("SelectByID", mock.Anything, user.ID).Return(user, nil).Once()
+
+		_, err = s.service.VerifyEmail(context.Background(), token)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+
+	t.Run("when another token is used", func(t *testing.T) {
+		token, err := s.jwt.CreateToken(user.ID, jwt.NewID())
+		require.Nil(t, err)
+
+		_, err = s.service.VerifyEmail(context.Background(), token)
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+}
+
+func (s *UserServiceTestSuite) TestResendVerification() {
+	t := s.T()
+
+	t.Run("when the email is not verified", func(t *testing.T) {
+		user := users.User{ID: 1, Email: "testuser@email.com"}
+		s.repo.On("SelectByID", mock.Anything, user.ID).Return(user, nil).Once()
+
+		require.Nil(t, s.service.ResendVerification(context.Background(), user.ID))
+		require.Len(t, s.mailer.Messages(), 1)
+	})
+
+	t.Run("when the email is verified", func(t *testing.T) {
+		user := users.User{ID: 2, Email: "verified@email.com", VerifiedAt: null.TimeFrom(time.Now())}
+		s.repo.On("SelectByID", mock.Anything, user.ID).Return(user, nil).Once()
+
+		err := s.service.ResendVerification(context.Background(), user.ID)
+		require.ErrorIs(t, err, apperrors.ErrEmailAlreadyVerified)
+	})
+}
+
+func (s *UserServiceTestSuite) TestForgotPassword() {
+	t := s.T()
+
+	user := users.User{ID: 1, FirstName: "Tester", Email: "testuser@email.com"}
+
+	t.Run("when the email exists", func(t *testing.T) {
+		s.repo.On("SelectByEmail", mock.Anything, user.Email).Return(user, nil).Once()
+		var stored *users.PasswordReset
+		s.resets.
+			On("Insert", mock.Anything, mock.AnythingOfType("*users.PasswordReset")).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(*users.PasswordReset)
+			}).
+			Return(nil).
+			Once()
+
+		require.Nil(t, s.service.ForgotPassword(context.Background(), user.Email))
+		require.NoError(t, s.service.Close(context.Background()))
+		messages := s.mailer.Messages()
+		require.Len(t, messages, 1)
+		require.Equal(t, user.Email, messages[0].To)
+
+		// only the hash of the mailed token is stored
+		token := strings.Split(messages[0].Body, "\n\n")[2]
+		require.Equal(t, user.ID, stored.UserID)
+		require.Equal(t, hashResetToken(token), stored.TokenHash)
+		require.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+	})
+
+	t.Run("when the email does not exist", func(t *testing.T) {
+		s.repo.
+			On("SelectByEmail", mock.Anything, "nobody@example.com").
+			Return(users.User{}, apperrors.ErrUserNotFound).
+			Once()
+
+		require.Nil(t, s.service.ForgotPassword(context.Background(), "nobody@example.com"))
+		require.NoError(t, s.service.Close(context.Background()))
+		// only the mail of the previous case was sent
+		require.Len(t, s.mailer.Messages(), 1)
+	})
+
+	t.Run("when the service is closed while the mail is sent", func(t *testing.T) {
+		release := make(chan struct{})
+		s.repo.On("SelectByEmail", mock.Anything, user.Email).Return(user, nil).Once()
+		s.resets.
+			On("Insert", mock.Anything, mock.AnythingOfType("*users.PasswordReset")).
+			Run(func(args mock.Arguments) { <-release }).
+			Return(nil).
+			Once()
+
+		require.Nil(t, s.service.ForgotPassword(context.Background(), user.Email))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, s.service.Close(ctx), context.DeadlineExceeded)
+
+		close(release)
+		require.NoError(t, s.service.Close(context.Background()))
+		require.Len(t, s.mailer.Messages(), 2)
+	})
+}
+
+func (s *UserServiceTestSuite) TestResetPassword() {
+	t := s.T()
+
+	user := users.User{ID: 1, Email: "testuser@email.com", Password: "old_hash"}
+
+	t.Run("when the password is reset", func(t *testing.T) {
+		s.resets.On("Consume", mock.Anything, hashResetToken("token")).Return(user.ID, nil).Once()
+		s.repo.On("SelectByID", mock.Anything, user.ID).Return(user, nil).Once()
+		s.repo.
+			On("Update", mock.Anything, mock.MatchedBy(func(updated *users.User) bool {
+				return bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new_password")) == nil
+			})).
+			Return(nil).
+			Once()
+		s.sessions.On("RevokeAllForUser", mock.Anything, user.ID).Return(nil).Once()
+
+		require.Nil(t, s.service.ResetPassword(context.Background(), "token", "new_password"))
+	})
+
+	t.Run("when the token was used or expired", func(t *testing.T) {
+		s.resets.On("Consume", mock.Anything, hashResetToken("used")).Return(int64(0), apperrors.ErrConflict).Once()
+
+		err := s.service.ResetPassword(context.Background(), "used", "new_password")
+		require.ErrorIs(t, err, apperrors.ErrInvalidToken)
+	})
+}